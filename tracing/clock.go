@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Clock returns the current time.
+type Clock func() time.Time
+
+// DeterministicClock returns a Clock that starts at the given time, and for
+// every call advances by step. It is safe for concurrent use. It is useful for
+// deterministic, but still monotonic, timestamps in unit tests.
+// DO NOT use in production.
+func DeterministicClock(start time.Time, step time.Duration) Clock {
+	mu := &sync.Mutex{}
+	now := start
+	return func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+
+		t := now
+		now = now.Add(step)
+		return t
+	}
+}
+
+// clockProvider is a composite TracerProvider that registers timestamps from
+// the clock when spans start and end, and when events and errors are recorded.
+type clockProvider struct {
+	// embedding is important; this automatically exposes all inherited functionality from the
+	// underlying resource.
+	TracerProvider
+
+	clock Clock
+}
+
+func (tp *clockProvider) Tracer(instrumentationName string, opts ...trace.TracerOption) trace.Tracer {
+	return &clockTracer{tp.TracerProvider.Tracer(instrumentationName, opts...), tp}
+}
+
+type clockTracer struct {
+	// embedding is important; this automatically exposes all inherited functionality from the
+	// underlying resource.
+	trace.Tracer
+
+	provider *clockProvider
+}
+
+func (t *clockTracer) Start(ctx context.Context, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	// Prepend the timestamp, such that any user-given timestamp takes precedence
+	opts = append([]trace.SpanStartOption{trace.WithTimestamp(t.provider.clock())}, opts...)
+	ctx, span := t.Tracer.Start(ctx, spanName, opts...)
+	s := &clockSpan{span, t.provider}
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+type clockSpan struct {
+	// embedding is important; this automatically exposes all inherited functionality from the
+	// underlying resource.
+	trace.Span
+
+	provider *clockProvider
+}
+
+func (s *clockSpan) End(options ...trace.SpanEndOption) {
+	options = append([]trace.SpanEndOption{trace.WithTimestamp(s.provider.clock())}, options...)
+	s.Span.End(options...)
+}
+
+func (s *clockSpan) AddEvent(name string, options ...trace.EventOption) {
+	options = append([]trace.EventOption{trace.WithTimestamp(s.provider.clock())}, options...)
+	s.Span.AddEvent(name, options...)
+}
+
+func (s *clockSpan) RecordError(err error, options ...trace.EventOption) {
+	options = append([]trace.EventOption{trace.WithTimestamp(s.provider.clock())}, options...)
+	s.Span.RecordError(err, options...)
+}
+
+// AddLink implements LinkAdder.
+func (s *clockSpan) AddLink(link trace.Link) { AddLink(s.Span, link) }
+
+func (s *clockSpan) TracerProvider() trace.TracerProvider { return s.provider }
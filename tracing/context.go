@@ -2,6 +2,7 @@ package tracing
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-logr/logr"
 	"go.opentelemetry.io/otel/trace"
@@ -9,19 +10,74 @@ import (
 
 // SpanFromContext retrieves the currently-executing Span stored in the
 // context, if any, or a no-op Span.
-func SpanFromContext(ctx context.Context) Span { return trace.SpanFromContext(ctx) }
+//
+// Spans started using the TracerBuilder are returned as-is, that is, changes
+// registered with them are logged, as for the Span returned from TracerBuilder.Trace.
+// If the Span was started in some other way, for example directly using an
+// OpenTelemetry Tracer, and the Logger from LoggerFromContext is not logr.Discard(),
+// the Span is wrapped such that changes registered with it are logged using that
+// Logger as well.
+func SpanFromContext(ctx context.Context) Span {
+	span := trace.SpanFromContext(ctx)
+	if isLoggingSpan(span) || !span.SpanContext().IsValid() {
+		return span
+	}
+	log := LoggerFromContext(ctx)
+	if isDiscard(log) {
+		return span
+	}
+	if named, ok := span.(interface{ Name() string }); ok {
+		log = log.WithName(named.Name())
+	}
+	return &loggingSpan{
+		Span:     span,
+		provider: fromUpstream(span.TracerProvider()),
+		log:      log,
+	}
+}
+
+// isLoggingSpan returns true if span is, or wraps, a span started using the
+// TracerBuilder.
+func isLoggingSpan(span Span) bool {
+	switch s := span.(type) {
+	case *loggingSpan:
+		return true
+	case *timeoutSpan:
+		return isLoggingSpan(s.Span)
+	case *tracerProviderSpan:
+		return isLoggingSpan(s.Span)
+	default:
+		return false
+	}
+}
 
 // TracerProviderFromContext retrieves the TracerProvider from the
 // context. If the current Span's TracerProvider() is not the no-op
 // TracerProvider returned by trace.NewNoopTracerProvider(), it is
 // used, or otherwise the global from GetGlobalTracerProvider().
 func TracerProviderFromContext(ctx context.Context) TracerProvider {
-	if spanTp := fromUpstream(SpanFromContext(ctx).TracerProvider()); !spanTp.IsNoop() {
+	if spanTp := fromUpstream(trace.SpanFromContext(ctx).TracerProvider()); !spanTp.IsNoop() {
 		return spanTp
 	}
 	return GetGlobalTracerProvider()
 }
 
+// Detach returns a new context that carries all the values of ctx, for example
+// the Span, Logger, trace depth and TracerProvider, but that is never canceled
+// and has no deadline. This is useful for fire-and-forget goroutines that shall
+// keep tracing and logging in the context of the parent operation, without being
+// canceled when the parent, for example, a request context, is done.
+func Detach(ctx context.Context) context.Context { return detachedContext{ctx} }
+
+// detachedContext is a context.Context that delegates Value lookups to the
+// parent context, but never gets canceled.
+type detachedContext struct{ parent context.Context }
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
 // contextWithLogger injects the given Logger into a new context
 // descending from parent.
 func contextWithLogger(parent context.Context, log Logger) context.Context {
@@ -32,7 +88,7 @@ func contextWithLogger(parent context.Context, log Logger) context.Context {
 // descending from parent.
 func contextWithTracerProvider(parent context.Context, tp TracerProvider) context.Context {
 	return trace.ContextWithSpan(parent, &tracerProviderSpan{
-		Span: SpanFromContext(parent),
+		Span: trace.SpanFromContext(parent),
 		tp:   tp,
 	})
 }
@@ -47,6 +103,9 @@ type tracerProviderSpan struct {
 
 func (s *tracerProviderSpan) TracerProvider() trace.TracerProvider { return s.tp }
 
+// AddLink implements LinkAdder.
+func (s *tracerProviderSpan) AddLink(link trace.Link) { AddLink(s.Span, link) }
+
 // Context returns a new *ContextBuilder.
 func Context() *ContextBuilder { return &ContextBuilder{} }
 
@@ -57,6 +116,10 @@ type ContextBuilder struct {
 	tp   TracerProvider
 	log  Logger
 	lli  LogLevelIncreaser
+	kvs  []interface{}
+
+	logEvents     bool
+	spanDurations bool
 }
 
 // From sets the "base context" to start applying context.WithValue operations
@@ -84,6 +147,42 @@ func (b *ContextBuilder) WithLogLevelIncreaser(lli LogLevelIncreaser) *ContextBu
 	return b
 }
 
+// WithValues registers static key/value pairs with the context. For every span
+// started from the context (or a descendant of it), the pairs are added both to
+// the span's Logger using WithValues, and to the span as attributes. This is
+// useful for e.g. tagging all telemetry of a request with a request ID once.
+//
+// The keys must be strings, as for logr.Logger.WithValues. If the context
+// already carries static key/value pairs, the new pairs are appended.
+//
+// A call to this function appends to the list of previous values.
+func (b *ContextBuilder) WithValues(keysAndValues ...interface{}) *ContextBuilder {
+	b.kvs = append(b.kvs, keysAndValues...)
+	return b
+}
+
+// WithLogEvents makes the Logger returned from TracerBuilder.Trace register each
+// Info call also as a span event, named after the log message and with the
+// keysAndValues as event attributes, in addition to registering the keysAndValues
+// as span attributes. This makes individual log messages appear as timestamped
+// span events in e.g. Jaeger, instead of as attributes overwriting each other.
+//
+// By default log messages are not registered as span events.
+func (b *ContextBuilder) WithLogEvents() *ContextBuilder {
+	b.logEvents = true
+	return b
+}
+
+// WithSpanDurations makes spans log the duration of the span, as measured from
+// the start of the span, when the span ends. This gives useful latencies in
+// log-only deployments that have no trace backend.
+//
+// By default span durations are not logged.
+func (b *ContextBuilder) WithSpanDurations() *ContextBuilder {
+	b.spanDurations = true
+	return b
+}
+
 // Build builds the context.
 func (b *ContextBuilder) Build() context.Context {
 	ctx := b.from
@@ -93,11 +192,60 @@ func (b *ContextBuilder) Build() context.Context {
 	if b.tp != nil {
 		ctx = contextWithTracerProvider(ctx, b.tp)
 	}
-	if b.log != nil {
+	if b.log.GetSink() != nil {
 		ctx = contextWithLogger(ctx, b.log)
 	}
 	if b.lli != nil {
 		ctx = withLogLevelIncreaser(ctx, b.lli)
 	}
+	if len(b.kvs) != 0 {
+		ctx = withValues(ctx, b.kvs)
+	}
+	if b.logEvents {
+		ctx = context.WithValue(ctx, logEventsKey, true)
+	}
+	if b.spanDurations {
+		ctx = context.WithValue(ctx, spanDurationsKey, true)
+	}
 	return ctx
 }
+
+type logEventsKeyStruct struct{}
+
+var logEventsKey = logEventsKeyStruct{} //nolint:gochecknoglobals
+
+type spanDurationsKeyStruct struct{}
+
+var spanDurationsKey = spanDurationsKeyStruct{} //nolint:gochecknoglobals
+
+// spanDurationsFromContext returns whether WithSpanDurations was set for the context.
+func spanDurationsFromContext(ctx context.Context) bool {
+	spanDurations, _ := ctx.Value(spanDurationsKey).(bool)
+	return spanDurations
+}
+
+// logEventsFromContext returns whether WithLogEvents was set for the context.
+func logEventsFromContext(ctx context.Context) bool {
+	logEvents, _ := ctx.Value(logEventsKey).(bool)
+	return logEvents
+}
+
+type valuesKeyStruct struct{}
+
+var valuesKey = valuesKeyStruct{} //nolint:gochecknoglobals
+
+// withValues registers the given key/value pairs with a new context descending
+// from parent, in addition to the ones already registered in parent.
+func withValues(parent context.Context, keysAndValues []interface{}) context.Context {
+	parentKVs := valuesFromContext(parent)
+	kvs := make([]interface{}, 0, len(parentKVs)+len(keysAndValues))
+	kvs = append(kvs, parentKVs...)
+	kvs = append(kvs, keysAndValues...)
+	return context.WithValue(parent, valuesKey, kvs)
+}
+
+// valuesFromContext returns the key/value pairs registered using withValues.
+func valuesFromContext(ctx context.Context) []interface{} {
+	kvs, _ := ctx.Value(valuesKey).([]interface{})
+	return kvs
+}
@@ -66,14 +66,14 @@ up to a given maximum, configured by the user, similar to how MaxDepthEnabler wo
 
 Log output for the example above would looks something like:
 
-	{"level":"info(v=0)","logger":"A","msg":"starting span"}
-	{"level":"info(v=0)","logger":"B","msg":"starting span"}
-	{"level":"debug(v=1)","logger":"C","msg":"starting span"}
-	{"level":"debug(v=1)","logger":"C","msg":"ending span"}
-	{"level":"info(v=0)","logger":"B","msg":"ending span"}
-	{"level":"info(v=0)","logger":"D","msg":"starting span"}
-	{"level":"info(v=0)","logger":"D","msg":"ending span"}
-	{"level":"info(v=0)","logger":"A","msg":"ending span"}
+	{"level":"info","logger":"A","msg":"starting span","v":0}
+	{"level":"info","logger":"B","msg":"starting span","v":0}
+	{"level":"debug","logger":"C","msg":"starting span","v":1}
+	{"level":"debug","logger":"C","msg":"ending span","v":1}
+	{"level":"info","logger":"B","msg":"ending span","v":0}
+	{"level":"info","logger":"D","msg":"starting span","v":0}
+	{"level":"info","logger":"D","msg":"ending span","v":0}
+	{"level":"info","logger":"A","msg":"ending span","v":0}
 
 This is of course a bit dull example, because only the start/end span events are
 logged, but it shows the spirit. If span operations like
@@ -83,13 +83,17 @@ information will be logged automatically, without a need to call log.Info() sepa
 
 At the same time, all trace data is nicely visualized in Jaeger :). For convenience,
 a builder-pattern constructor for the zap logger, compliant with the Logger interface
-is provided through the ZapLogger() function and zaplog sub-directory.
+is provided through the ZapLogger() function and zaplog sub-directory. For services
+standardizing on log/slog, the sloglog sub-directory provides a similar builder
+(requires Go 1.21 or newer). Existing klog and logrus setups can be adapted using
+the kloglog and logruslog sub-directories.
 
 In package traceyaml there are utilities for unit testing the traces. In package
 filetest there are utilities for using "golden" testdata/ files for comparing actual
 output of loggers, tracers, and general writers against expected output. Both the
 TracerProviderBuilder and zaplog.Builder support deterministic output for unit tests
-and examples.
+and examples. In package sqltrace there is a database/sql driver wrapper that traces
+all database queries, statement executions and transactions.
 
 The philosophy behind this package is that instrumentable code (functions, structs,
 and so on), should use the TracerBuilder to start spans; and will from there get a
@@ -50,20 +50,20 @@ func Example_loggingAndYAMLTrace() {
 
 	// Output:
 	// Log representation:
-	// {"level":"info(v=0)","logger":"myInstrumentedFunc","msg":"starting span"}
-	// {"level":"info(v=0)","logger":"myInstrumentedFunc","msg":"normal verbosity!"}
-	// {"level":"debug(v=1)","logger":"myInstrumentedFunc","msg":"found a message","hello":"from the other side"}
-	// {"level":"debug(v=1)","logger":"child-0","msg":"starting span"}
-	// {"level":"debug(v=1)","logger":"child-0","msg":"span event","span-event":"DoSTH"}
-	// {"level":"debug(v=1)","logger":"child-0","msg":"span attribute change","span-attr-i":0}
-	// {"level":"debug(v=1)","logger":"child-0","msg":"ending span"}
-	// {"level":"debug(v=1)","logger":"child-1","msg":"starting span"}
-	// {"level":"debug(v=1)","logger":"child-1","msg":"span event","span-event":"DoSTH"}
-	// {"level":"debug(v=1)","logger":"child-1","msg":"span attribute change","span-attr-i":1}
-	// {"level":"debug(v=1)","logger":"child-1","msg":"ending span"}
+	// {"level":"info","logger":"myInstrumentedFunc","msg":"starting span","v":0}
+	// {"level":"info","logger":"myInstrumentedFunc","msg":"normal verbosity!","v":0}
+	// {"level":"debug","logger":"myInstrumentedFunc","msg":"found a message","v":1,"hello":"from the other side"}
+	// {"level":"debug","logger":"child-0","msg":"starting span","v":1}
+	// {"level":"debug","logger":"child-0","msg":"span event","v":1,"span-event":"DoSTH"}
+	// {"level":"debug","logger":"child-0","msg":"span attribute change","v":1,"span-attr-i":0}
+	// {"level":"debug","logger":"child-0","msg":"ending span","v":1}
+	// {"level":"debug","logger":"child-1","msg":"starting span","v":1}
+	// {"level":"debug","logger":"child-1","msg":"span event","v":1,"span-event":"DoSTH"}
+	// {"level":"debug","logger":"child-1","msg":"span attribute change","v":1,"span-attr-i":1}
+	// {"level":"debug","logger":"child-1","msg":"ending span","v":1}
 	// {"level":"error","logger":"myInstrumentedFunc","msg":"span error","error":"unexpected: sample error"}
-	// {"level":"info(v=0)","logger":"myInstrumentedFunc","msg":"ending span"}
-	// {"level":"info(v=0)","msg":"error is sampleErr","is-sampleErr":true}
+	// {"level":"info","logger":"myInstrumentedFunc","msg":"ending span","v":0}
+	// {"level":"info","msg":"error is sampleErr","v":0,"is-sampleErr":true}
 	//
 	// YAML trace representation:
 	// # myInstrumentedFunc
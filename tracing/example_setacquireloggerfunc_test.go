@@ -13,7 +13,7 @@ import (
 
 func myAcquire(ctx context.Context) tracing.Logger {
 	// If there is a logger in the context, use it
-	if log := logr.FromContext(ctx); log != nil {
+	if log, err := logr.FromContext(ctx); err == nil {
 		return log
 	}
 
@@ -44,13 +44,13 @@ func ExampleSetAcquireLoggerFunc() {
 
 	// Output:
 	// realLogger (zapr) is used with ctxWithLog:
-	// {"level":"info(v=0)","logger":"sampleInstrumentedFunc","msg":"starting span"}
-	// {"level":"debug(v=1)","logger":"sampleInstrumentedFunc","msg":"got context name","context-name":"ctxWithLog"}
-	// {"level":"info(v=0)","logger":"sampleInstrumentedFunc","msg":"ending span"}
+	// {"level":"info","logger":"sampleInstrumentedFunc","msg":"starting span","v":0}
+	// {"level":"debug","logger":"sampleInstrumentedFunc","msg":"got context name","v":1,"context-name":"ctxWithLog"}
+	// {"level":"info","logger":"sampleInstrumentedFunc","msg":"ending span","v":0}
 	// myAcquire defaults to stdr if there's no logger in the context:
-	// FooLogger: sampleInstrumentedFunc "level"=0 "msg"="starting span"
-	// FooLogger: sampleInstrumentedFunc "level"=1 "msg"="got context name"  "context-name"="context.Background"
-	// FooLogger: sampleInstrumentedFunc "level"=0 "msg"="ending span"
+	// FooLogger: sampleInstrumentedFunc: "level"=0 "msg"="starting span"
+	// FooLogger: sampleInstrumentedFunc: "level"=1 "msg"="got context name" "context-name"="context.Background"
+	// FooLogger: sampleInstrumentedFunc: "level"=0 "msg"="ending span"
 }
 
 func sampleInstrumentedFunc(ctx context.Context, contextName string) {
@@ -37,13 +37,13 @@ func ExampleSetGlobalLogger() {
 
 	// Output:
 	// realLogger (zapr) is used with ctxWithLog:
-	// {"level":"info(v=0)","logger":"sampleInstrumentedFunc2","msg":"starting span"}
-	// {"level":"debug(v=1)","logger":"sampleInstrumentedFunc2","msg":"got context name","context-name":"ctxWithLog"}
-	// {"level":"info(v=0)","logger":"sampleInstrumentedFunc2","msg":"ending span"}
+	// {"level":"info","logger":"sampleInstrumentedFunc2","msg":"starting span","v":0}
+	// {"level":"debug","logger":"sampleInstrumentedFunc2","msg":"got context name","v":1,"context-name":"ctxWithLog"}
+	// {"level":"info","logger":"sampleInstrumentedFunc2","msg":"ending span","v":0}
 	// Use the global stdr logger if there's no logger in the context:
-	// FooLogger: sampleInstrumentedFunc2 "level"=0 "msg"="starting span"
-	// FooLogger: sampleInstrumentedFunc2 "level"=1 "msg"="got context name"  "context-name"="context.Background"
-	// FooLogger: sampleInstrumentedFunc2 "level"=0 "msg"="ending span"
+	// FooLogger: sampleInstrumentedFunc2: "level"=0 "msg"="starting span"
+	// FooLogger: sampleInstrumentedFunc2: "level"=1 "msg"="got context name" "context-name"="context.Background"
+	// FooLogger: sampleInstrumentedFunc2: "level"=0 "msg"="ending span"
 }
 
 func sampleInstrumentedFunc2(ctx context.Context, contextName string) {
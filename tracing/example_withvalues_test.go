@@ -0,0 +1,72 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	golog "log"
+
+	"github.com/luxas/deklarative/tracing"
+)
+
+func ExampleContextBuilder_WithValues() {
+	// Make a TracerProvider writing YAML about what's happening to
+	// the yamlTrace buffer.
+	var yamlTrace bytes.Buffer
+	tp, err := tracing.Provider().TestYAMLTo(&yamlTrace).Build()
+	if err != nil {
+		golog.Fatal(err)
+	}
+
+	// Make an example logger logging to os.Stdout directly.
+	log := tracing.ZapLogger().Example().LogUpto(1).Build()
+
+	// Tag all telemetry originating from this context with the request ID.
+	ctx := tracing.Context().
+		WithLogger(log).
+		WithTracerProvider(tp).
+		WithValues("request-id", "a1b2c3").
+		Build()
+
+	handleRequest(ctx)
+
+	// Shutdown the TracerProvider, and output the YAML it yielded to os.Stdout.
+	if err := tp.Shutdown(ctx); err != nil {
+		golog.Fatal(err)
+	}
+	fmt.Printf("\n%s", yamlTrace.String())
+
+	// Output:
+	// {"level":"info","logger":"handleRequest","msg":"starting span","request-id":"a1b2c3","v":0}
+	// {"level":"debug","logger":"lookupUser","msg":"starting span","request-id":"a1b2c3","v":1}
+	// {"level":"debug","logger":"lookupUser","msg":"found user","request-id":"a1b2c3","v":1,"user":"luxas"}
+	// {"level":"debug","logger":"lookupUser","msg":"ending span","request-id":"a1b2c3","v":1}
+	// {"level":"info","logger":"handleRequest","msg":"ending span","request-id":"a1b2c3","v":0}
+	//
+	// # handleRequest
+	// - spanName: handleRequest
+	//   startConfig:
+	//     attributes:
+	//       request-id: a1b2c3
+	//   children:
+	//   - spanName: lookupUser
+	//     attributes:
+	//       log-attr-user: luxas
+	//     startConfig:
+	//       attributes:
+	//         request-id: a1b2c3
+}
+
+func handleRequest(ctx context.Context) {
+	ctx, span := tracing.Tracer().Start(ctx, "handleRequest")
+	defer span.End()
+
+	lookupUser(ctx)
+}
+
+func lookupUser(ctx context.Context) {
+	_, span, log := tracing.Tracer().Trace(ctx, "lookupUser")
+	defer span.End()
+
+	log.Info("found user", "user", "luxas")
+}
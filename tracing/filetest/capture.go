@@ -0,0 +1,61 @@
+package filetest
+
+import (
+	"io"
+	"os"
+	"sync"
+	"testing"
+)
+
+// CaptureStdout redirects os.Stdout and os.Stderr into target, for code that
+// writes to the process streams directly, e.g. using fmt.Println. Both streams
+// are written to the same target, in the order the writes happen.
+//
+// Only the os.Stdout and os.Stderr variables are replaced; file descriptors 1
+// and 2 are not redirected. Hence only writes by code that looks up
+// os.Stdout or os.Stderr at write time are captured, or by writers created
+// from them while capturing, e.g. a logger built after calling CaptureStdout.
+// Not captured are writes by writers that were created from os.Stdout or
+// os.Stderr earlier, e.g. the default logger of the log package and zap
+// loggers opened with the "stdout" or "stderr" sinks beforehand, nor output
+// of C code or subprocesses.
+//
+// The returned function restores os.Stdout and os.Stderr, and
+// waits for all captured content to be written to target; it must be called
+// before asserting, e.g.
+//
+//	g := filetest.New(t)
+//	defer g.Assert()
+//	restore := filetest.CaptureStdout(t, g.Add("stdout.txt"))
+//	codeUnderTest()
+//	restore()
+//
+// It is safe to call the returned function multiple times; it is also
+// called when the test finishes. As os.Stdout and os.Stderr are global, tests
+// using CaptureStdout must not be run in parallel.
+func CaptureStdout(t *testing.T, target *Target) func() { //nolint:thelper
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("could not create pipe for capturing stdout: %v", err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = io.Copy(target.Writer(), r)
+	}()
+
+	var once sync.Once
+	restore := func() {
+		once.Do(func() {
+			os.Stdout, os.Stderr = stdout, stderr
+			_ = w.Close()
+			<-done
+			_ = r.Close()
+		})
+	}
+	t.Cleanup(restore)
+	return restore
+}
@@ -0,0 +1,26 @@
+package filetest
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/sebdah/goldie/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaptureStdout(t *testing.T) {
+	stdout, stderr := os.Stdout, os.Stderr
+	g := New(t, goldie.WithFixtureDir(t.TempDir()))
+	target := g.Add("out.txt")
+
+	restore := CaptureStdout(t, target)
+	fmt.Println("to stdout")
+	fmt.Fprintln(os.Stderr, "to stderr")
+	restore()
+	restore()
+
+	assert.Equal(t, stdout, os.Stdout)
+	assert.Equal(t, stderr, os.Stderr)
+	assert.Equal(t, "to stdout\nto stderr\n", target.Buffer.String())
+}
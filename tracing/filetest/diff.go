@@ -0,0 +1,86 @@
+package filetest
+
+import (
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/sebdah/goldie/v2"
+)
+
+// DefaultDiffContext is the amount of unchanged lines shown around every
+// change in the diff shown by Assert, unless WithUnifiedDiff is given.
+const DefaultDiffContext = 3
+
+// ANSI escape codes used for colorizing diffs.
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+)
+
+// WithUnifiedDiff returns a goldie.Option that makes a failing Assert show a
+// unified diff between the expected and actual content, with context lines of
+// unchanged content around every change. If colorize is true, removed lines
+// are red, added lines green, and hunk headers cyan, using ANSI escape codes.
+//
+// New uses a unified diff with DefaultDiffContext context lines and no colors
+// by default.
+func WithUnifiedDiff(context int, colorize bool) goldie.Option {
+	return goldie.WithDiffFn(func(actual, expected string) string {
+		return UnifiedDiff(actual, expected, context, colorize)
+	})
+}
+
+// UnifiedDiff returns a unified diff from expected to actual, with context
+// lines of unchanged content around every change, as shown by WithUnifiedDiff.
+// If colorize is true, the diff is colorized using ANSI escape codes. An empty
+// string is returned if actual and expected are equal.
+func UnifiedDiff(actual, expected string, context int, colorize bool) string {
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(expected),
+		B:        splitLines(actual),
+		FromFile: "Expected",
+		ToFile:   "Actual",
+		Context:  context,
+	})
+	if !colorize {
+		return diff
+	}
+
+	lines := strings.SplitAfter(diff, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			lines[i] = colorLine(colorBold, line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = colorLine(colorCyan, line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = colorLine(colorRed, line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = colorLine(colorGreen, line)
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// splitLines splits s into lines, each ending in a newline, as required by
+// difflib. Unlike difflib.SplitLines, content ending in a newline doesn't get
+// an empty last line, which would show up as a phantom context line.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	last := len(lines) - 1
+	if lines[last] == "" {
+		return lines[:last]
+	}
+	lines[last] += "\n"
+	return lines
+}
+
+// colorLine wraps line in the given color, keeping the trailing newline
+// outside of the escape codes.
+func colorLine(color, line string) string {
+	content := strings.TrimSuffix(line, "\n")
+	return color + content + colorReset + line[len(content):]
+}
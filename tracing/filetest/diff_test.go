@@ -0,0 +1,39 @@
+package filetest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_UnifiedDiff(t *testing.T) {
+	expected := "a\nb\nc\nd\n"
+	actual := "a\nB\nc\nd\n"
+
+	assert.Equal(t, `--- Expected
++++ Actual
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`, UnifiedDiff(actual, expected, 1, false))
+
+	assert.Equal(t, "\x1b[1m--- Expected\x1b[0m\n"+
+		"\x1b[1m+++ Actual\x1b[0m\n"+
+		"\x1b[36m@@ -1,4 +1,4 @@\x1b[0m\n"+
+		" a\n"+
+		"\x1b[31m-b\x1b[0m\n"+
+		"\x1b[32m+B\x1b[0m\n"+
+		" c\n"+
+		" d\n", UnifiedDiff(actual, expected, 3, true))
+
+	assert.Empty(t, UnifiedDiff(expected, expected, 3, true))
+}
+
+func Test_splitLines(t *testing.T) {
+	assert.Equal(t, []string{"a\n", "b\n"}, splitLines("a\nb\n"))
+	// The last line is terminated, as difflib requires
+	assert.Equal(t, []string{"a\n", "b\n"}, splitLines("a\nb"))
+	assert.Empty(t, splitLines(""))
+}
@@ -0,0 +1,169 @@
+package filetest
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Dir is a write target for code that emits multiple output files. The code
+// under test writes files to the temporary directory Path, which is compared
+// recursively against the golden directory of the same name, conventionally
+// under testdata/. Before verifying that the content of each file is right,
+// the filters are applied in order.
+type Dir struct {
+	Path    string
+	Filters []FilterE
+
+	// t reports the errors of File.
+	t errorReporter
+}
+
+// errorReporter reports test errors, e.g. *testing.T.
+type errorReporter interface {
+	Errorf(format string, args ...interface{})
+}
+
+// AddDir adds a new directory target to the Dirs map. If name already exists
+// in the map, it is overwritten.
+//
+// When asserting, every file written under Dir.Path is compared against the
+// golden file with the same relative path under name. Golden files under name
+// that were not written are reported as missing; or removed when updating.
+func (g *Tester) AddDir(name string) *Dir {
+	d := &Dir{Path: g.T.TempDir(), t: g.T}
+	g.Dirs[name] = d
+	return d
+}
+
+// Filter adds a new filter to the Dir, applied to every file in it.
+func (d *Dir) Filter(filter Filter) *Dir {
+	return d.FilterE(filter.E())
+}
+
+// FilterE adds a new filter that can fail to the Dir, applied to every file
+// in it.
+func (d *Dir) FilterE(filter FilterE) *Dir {
+	d.Filters = append(d.Filters, filter)
+	return d
+}
+
+// File returns the path to the file with the given relative name in the
+// directory, for the code under test to write to. Parent directories are
+// created as needed; if that fails, the test fails. File is safe to call from
+// other goroutines than the test's.
+func (d *Dir) File(name string) string {
+	p := filepath.Join(d.Path, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		d.t.Errorf("could not create the parent directories of %s: %v", p, err)
+	}
+	return p
+}
+
+// files returns the relative, slash-separated paths of all regular files
+// under root in fsys, sorted. suffix is trimmed from the file names. If root
+// doesn't exist, no files are returned.
+func files(fsys fs.FS, root, suffix string) ([]string, error) {
+	var result []string
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel := p
+		if root != "." {
+			rel = strings.TrimPrefix(p, root+"/")
+		}
+		result = append(result, strings.TrimSuffix(rel, suffix))
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	sort.Strings(result)
+	return result, err
+}
+
+// goldenDir returns the golden directory for name, and the suffix goldie
+// appends to golden file names.
+func (g *Tester) goldenDir(t *testing.T, name string) (dir, suffix string) { //nolint:thelper
+	const probe = "probe"
+	p := g.G.GoldenFileName(t, filepath.Join(name, probe))
+	return filepath.Dir(p), strings.TrimPrefix(filepath.Base(p), probe)
+}
+
+// doDirs runs fn for every file in every directory target, and checkMissing
+// for every golden file that was not written.
+func (g *Tester) doDirs(fn func(*testing.T, string, []byte), checkMissing func(*testing.T, string)) {
+	for name, d := range g.Dirs {
+		written, err := files(os.DirFS(d.Path), ".", "")
+		if !assert.Nil(g.T, err) {
+			continue
+		}
+		wasWritten := make(map[string]bool, len(written))
+		for _, rel := range written {
+			wasWritten[rel] = true
+			content, err := os.ReadFile(filepath.Join(d.Path, filepath.FromSlash(rel)))
+			if !assert.Nil(g.T, err) {
+				continue
+			}
+			content, err = applyFilters(content, d.Filters)
+			content = substituteVars(content, g.vars)
+
+			fileName := filepath.Join(name, filepath.FromSlash(rel))
+			g.T.Run(fileName, func(t *testing.T) {
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				fn(t, fileName, content)
+			})
+		}
+
+		goldenDir, suffix := g.goldenDir(g.T, name)
+		golden, err := g.goldenFiles(goldenDir, suffix)
+		if !assert.Nil(g.T, err) {
+			continue
+		}
+		for _, rel := range golden {
+			if !wasWritten[rel] {
+				checkMissing(g.T, filepath.Join(goldenDir, filepath.FromSlash(rel)+suffix))
+			}
+		}
+	}
+}
+
+// goldenFiles lists the golden files under dir, either from the file system,
+// or from g.FS if set.
+func (g *Tester) goldenFiles(dir, suffix string) ([]string, error) {
+	if g.FS != nil {
+		return files(g.FS, filepath.ToSlash(dir), suffix)
+	}
+	return files(os.DirFS(dir), ".", suffix)
+}
+
+// assertMissing reports a golden file that was not written, or removes it if
+// the "-update" flag is passed.
+func assertMissing(t *testing.T, goldenFile string) { //nolint:thelper
+	if updating() {
+		removeMissing(t, goldenFile)
+		return
+	}
+	t.Errorf("golden file %s was not written", goldenFile)
+}
+
+func removeMissing(t *testing.T, goldenFile string) { //nolint:thelper
+	assert.Nil(t, os.Remove(goldenFile))
+}
+
+// updating returns true if the "-update" flag registered by goldie is set.
+func updating() bool {
+	f := flag.Lookup("update")
+	return f != nil && f.Value.String() == "true"
+}
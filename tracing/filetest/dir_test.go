@@ -0,0 +1,59 @@
+package filetest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sebdah/goldie/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddDir(t *testing.T) {
+	fixtureDir := t.TempDir()
+	stale := filepath.Join(fixtureDir, "out", "stale.txt.golden")
+	require.Nil(t, os.MkdirAll(filepath.Dir(stale), 0o755))
+	require.Nil(t, os.WriteFile(stale, []byte("stale"), 0o600))
+
+	g := New(t, goldie.WithFixtureDir(fixtureDir))
+	d := g.AddDir("out").Filter(replaceSpacing)
+
+	// The code under test emits multiple files to the directory
+	require.Nil(t, os.WriteFile(d.File("a.txt"), []byte("  a  "), 0o600))
+	require.Nil(t, os.WriteFile(d.File("sub/b.txt"), []byte("b"), 0o600))
+
+	g.Update()
+	g.Assert()
+
+	content, err := os.ReadFile(filepath.Join(fixtureDir, "out", "a.txt.golden"))
+	require.Nil(t, err)
+	assert.Equal(t, "a", string(content))
+	content, err = os.ReadFile(filepath.Join(fixtureDir, "out", "sub", "b.txt.golden"))
+	require.Nil(t, err)
+	assert.Equal(t, "b", string(content))
+	// Golden files that weren't written are removed when updating
+	assert.NoFileExists(t, stale)
+}
+
+// fakeErrorReporter records the errors reported to it.
+type fakeErrorReporter struct {
+	errs []string
+}
+
+func (r *fakeErrorReporter) Errorf(format string, args ...interface{}) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func TestDirFile_Error(t *testing.T) {
+	dir := t.TempDir()
+	// A file blocks creating the parent directory
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "blocker"), nil, 0o600))
+
+	r := &fakeErrorReporter{}
+	d := &Dir{Path: dir, t: r}
+	assert.Equal(t, filepath.Join(dir, "blocker", "a.txt"), d.File("blocker/a.txt"))
+	require.Len(t, r.errs, 1)
+	assert.Contains(t, r.errs[0], "could not create the parent directories of "+filepath.Join(dir, "blocker", "a.txt"))
+}
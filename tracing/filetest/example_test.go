@@ -10,15 +10,11 @@ import (
 
 func TestExample(t *testing.T) {
 	// The ideomatic way to use this is to define g somewhere in the beginning,
-	// and run g.Assert() as a deferred function, so that all writes registered
-	// in the test have time to be applied.
+	// and run g.AssertOrUpdate() as a deferred function, so that all writes
+	// registered in the test have time to be applied. The golden files are
+	// only updated when the user passes the "-update" flag.
 	g := New(t)
-	defer g.Assert()
-
-	// Update runs before Assert; hence this will always succeed as a sample test
-	// In real life, g.Update() wouldn't probably be called unconditionally, only
-	// when the user passes the "-update" flag.
-	defer g.Update()
+	defer g.AssertOrUpdate()
 
 	// Get a writer that will be comparing what was written to it with the
 	// contents of foo.txt, after the filter has been applied.
@@ -6,7 +6,13 @@ package filetest
 
 import (
 	"bytes"
+	"encoding/hex"
+	"fmt"
 	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sync"
 	"testing"
 
 	"github.com/sebdah/goldie/v2"
@@ -14,11 +20,17 @@ import (
 )
 
 // New is a wrapper for goldie.New, but returns a *Tester goldie helper.
+// Unless overridden by opts, a unified diff is shown when a file doesn't
+// match; see WithUnifiedDiff.
 func New(t *testing.T, opts ...goldie.Option) *Tester { //nolint:thelper
+	// Make sure to order the default options first, so opts can override them
+	opts = append([]goldie.Option{WithUnifiedDiff(DefaultDiffContext, false)}, opts...)
 	return &Tester{
 		G:     goldie.New(t, opts...),
 		T:     t,
 		Files: make(map[string]*Target),
+		Dirs:  make(map[string]*Dir),
+		opts:  opts,
 	}
 }
 
@@ -30,6 +42,17 @@ type Tester struct {
 	// Files map a file name (conventionally under testdata/) to a
 	// target buffer and set of filters.
 	Files map[string]*Target
+	// Dirs map a directory name (conventionally under testdata/) to a
+	// directory target and set of filters.
+	Dirs map[string]*Dir
+	// FS, if set, is where golden files are read from instead of from disk.
+	// See NewFS.
+	FS fs.FS
+
+	// opts are the options G was created with.
+	opts []goldie.Option
+	// vars are the variables of golden templates; see WithVars.
+	vars map[string]string
 }
 
 // Target is a write target for arbitrary content sources. Before verifying that
@@ -37,57 +60,210 @@ type Tester struct {
 // content.
 type Target struct {
 	Buffer  *bytes.Buffer
-	Filters []Filter
+	Filters []FilterE
+
+	// binary is set if the content shall be stored as a hexdump.
+	binary bool
+	// g is set if the target has its own goldie options.
+	g *goldie.Goldie
+	// mu guards Buffer and file when written to using Writer.
+	mu sync.Mutex
+	// file is set if the target streams its content to a file; see AddStream.
+	file *os.File
+	// errs are errors that occurred when writing to the target, reported
+	// when asserting.
+	errs []error
 }
 
 // Filter represents a byte filter; similar to an UNIX pipe.
 type Filter func([]byte) []byte
 
+// FilterE represents a byte filter that can fail, e.g. because the content
+// can't be parsed. If a FilterE returns an error, the test fails with it.
+type FilterE func([]byte) ([]byte, error)
+
+// E converts the Filter into a FilterE that never fails.
+func (f Filter) E() FilterE {
+	return func(in []byte) ([]byte, error) { return f(in), nil }
+}
+
+// applyFilters applies filters in order to content, and returns the first
+// error, if any.
+func applyFilters(content []byte, filters []FilterE) ([]byte, error) {
+	for i, filter := range filters {
+		var err error
+		content, err = filter(content)
+		if err != nil {
+			return nil, fmt.Errorf("filter %d failed: %w", i, err)
+		}
+	}
+	return content, nil
+}
+
 // Add adds a new file target to the Files map. If name already exists in the map,
 // it is overwritten.
-func (g *Tester) Add(name string) *Target {
+//
+// If opts are given, they are applied after the options given to New for this
+// target only, e.g. such that a target can use another fixture directory or
+// name suffix, like ".log" instead of ".golden".
+func (g *Tester) Add(name string, opts ...goldie.Option) *Target {
 	b := &Target{
 		Buffer: new(bytes.Buffer),
 	}
+	if len(opts) != 0 {
+		// Make sure to order the Tester options first, so opts can override them
+		b.g = goldie.New(g.T, append(append([]goldie.Option{}, g.opts...), opts...)...)
+	}
 	g.Files[name] = b
 	return b
 }
 
+// Case adds a new file target for a case of a table-driven test, namespaced
+// under the name of the test, like Add(path.Join(t.Name(), name)). For example,
+// in the test TestFoo, Case("case1.yaml") is verified against the golden file
+// testdata/TestFoo/case1.yaml.golden.
+//
+// This is the same directory layout as goldie.WithTestNameForDir creates, hence
+// it shall not be used together with that option.
+func (g *Tester) Case(name string, opts ...goldie.Option) *Target {
+	return g.Add(path.Join(g.T.Name(), name), opts...)
+}
+
 // Filter adds a new filter to the Target.
 func (b *Target) Filter(filter Filter) *Target {
+	return b.FilterE(filter.E())
+}
+
+// FilterE adds a new filter that can fail to the Target.
+func (b *Target) FilterE(filter FilterE) *Target {
 	b.Filters = append(b.Filters, filter)
 	return b
 }
 
+// Binary makes the Target store and compare the content as a canonical
+// hexdump, as returned by hex.Dump, such that non-text content like protobuf
+// messages or gzip frames can be reviewed in diffs. The hexdump is created
+// after all filters have been applied.
+func (b *Target) Binary() *Target {
+	b.binary = true
+	return b
+}
+
 // Writer returns the io.Writer which content sources can write to. The io.Writer
-// is/writes to the buffer.
-func (b *Target) Writer() io.Writer { return b.Buffer }
+// writes to the buffer, and is safe for concurrent use, e.g. by tracing providers
+// and loggers used from parallel goroutines. Each Write call is written to the
+// buffer atomically.
+func (b *Target) Writer() io.Writer { return (*targetWriter)(b) }
+
+// targetWriter writes to the buffer, or file, of a Target while holding its lock.
+type targetWriter Target
+
+func (w *targetWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file != nil {
+		return w.file.Write(p)
+	}
+	return w.Buffer.Write(p)
+}
+
+// bytes returns a copy of the buffered content.
+func (b *Target) bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.Buffer.Bytes()...)
+}
 
-func (g *Tester) do(fn func(*testing.T, string, []byte)) {
+// do runs fn for every file target, or streamFn with the path to the content
+// for streamed targets.
+func (g *Tester) do(fn func(*testing.T, string, []byte), streamFn func(*testing.T, string, string)) {
 	for name, a := range g.Files {
-		content := a.Buffer.Bytes()
-		for _, filter := range a.Filters {
-			content = filter(content)
+		if a.file != nil {
+			g.doStream(name, a, streamFn)
+			continue
+		}
+
+		content, err := applyFilters(a.bytes(), a.Filters)
+		content = substituteVars(content, g.vars)
+		if a.binary {
+			content = []byte(hex.Dump(content))
 		}
 
 		g.T.Run(name, func(t *testing.T) {
+			for _, writeErr := range a.errs {
+				t.Error(writeErr)
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
 			fn(t, name, content)
 		})
 	}
 }
 
+// goldieFor returns the goldie.Goldie to use for the golden file name; either
+// the one of the file target with its own options, or G.
+func (g *Tester) goldieFor(name string) *goldie.Goldie {
+	if b, ok := g.Files[name]; ok && b.g != nil {
+		return b.g
+	}
+	return g.G
+}
+
 // Assert verifies the all golden files are up-to-date.
 // All file verifications are run in separate sub-tests.
 //
 // If the "-update" flag is passed to "go test", for example as
 // "go test . -update", the files under testdata/ will be
-// automatically updated.
-func (g *Tester) Assert() { g.do(g.G.Assert) }
+// automatically updated, except for the golden files of streamed targets;
+// use AssertOrUpdate for updating those as well.
+func (g *Tester) Assert() {
+	if g.FS != nil {
+		g.do(g.assertFS, g.assertStream)
+		g.doDirs(g.assertFS, assertMissingFS)
+		return
+	}
+	g.do(func(t *testing.T, name string, content []byte) { //nolint:thelper
+		g.goldieFor(name).Assert(t, name, content)
+	}, g.assertStream)
+	g.doDirs(g.G.Assert, assertMissing)
+}
+
+// AssertOrUpdate updates all golden files if the "-update" flag is passed to
+// "go test", and verifies that all golden files are up-to-date otherwise.
+// This is the recommended way to finish a test, for example as
+//
+//	g := filetest.New(t)
+//	defer g.AssertOrUpdate()
+//
+// as it avoids accidentally leaving an unconditional call to Update in the test.
+//
+// For targets added using Add, Case and AddDir, it's equivalent to Assert, which
+// updates their golden files as well when "-update" is passed, and removes the
+// golden files of directory targets that weren't written. The difference is
+// that AssertOrUpdate also updates the golden files of streamed targets added
+// using AddStream, which Assert only compares against. When the golden files
+// are read from an fs.FS (see NewFS), AssertOrUpdate is equivalent to Assert.
+func (g *Tester) AssertOrUpdate() {
+	if updating() && g.FS == nil {
+		g.Update()
+		return
+	}
+	g.Assert()
+}
 
 // Update updates all file content to match the written bytes to the
 // returned io.Writer.
 func (g *Tester) Update() {
-	g.do(func(t *testing.T, name string, content []byte) { //nolint:thelper
-		assert.Nil(t, g.G.Update(t, name, content))
-	})
+	if g.FS != nil {
+		g.do(updateFS, func(t *testing.T, name, _ string) { updateFS(t, name, nil) }) //nolint:thelper
+		g.doDirs(updateFS, func(*testing.T, string) {})
+		return
+	}
+	update := func(t *testing.T, name string, content []byte) { //nolint:thelper
+		assert.Nil(t, g.goldieFor(name).Update(t, name, content))
+	}
+	g.do(update, g.updateStream)
+	g.doDirs(update, removeMissing)
 }
@@ -0,0 +1,141 @@
+package filetest
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sebdah/goldie/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetBinary(t *testing.T) {
+	fixtureDir := t.TempDir()
+	g := New(t, goldie.WithFixtureDir(fixtureDir))
+	_, err := g.Add("out.bin").Binary().Writer().Write([]byte{0x1f, 0x8b, 0x08, 0x00, 'h', 'i', '\n'})
+	require.Nil(t, err)
+
+	g.Update()
+	g.Assert()
+
+	content, err := os.ReadFile(filepath.Join(fixtureDir, "out.bin.golden"))
+	require.Nil(t, err)
+	assert.Equal(t, "00000000  1f 8b 08 00 68 69 0a                              |....hi.|\n", string(content))
+}
+
+func TestNewFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"testdata/foo.txt.golden":       {Data: []byte("foo")},
+		"testdata/out/a.txt.golden":     {Data: []byte("a")},
+		"testdata/out/sub/b.txt.golden": {Data: []byte("b")},
+	}
+	g := NewFS(t, fsys)
+	defer g.Assert()
+
+	_, err := g.Add("foo.txt").Writer().Write([]byte("foo"))
+	require.Nil(t, err)
+	d := g.AddDir("out")
+	require.Nil(t, os.WriteFile(d.File("a.txt"), []byte("a"), 0o600))
+	require.Nil(t, os.WriteFile(d.File("sub/b.txt"), []byte("b"), 0o600))
+}
+
+func TestAddOptions(t *testing.T) {
+	fixtureDir, logDir := t.TempDir(), t.TempDir()
+	g := New(t, goldie.WithFixtureDir(fixtureDir))
+	_, err := g.Add("out.yaml").Writer().Write([]byte("foo: bar\n"))
+	require.Nil(t, err)
+	_, err = g.Add("out", goldie.WithFixtureDir(logDir), goldie.WithNameSuffix(".log")).Writer().Write([]byte("hello\n"))
+	require.Nil(t, err)
+
+	g.Update()
+	g.Assert()
+
+	content, err := os.ReadFile(filepath.Join(fixtureDir, "out.yaml.golden"))
+	require.Nil(t, err)
+	assert.Equal(t, "foo: bar\n", string(content))
+	content, err = os.ReadFile(filepath.Join(logDir, "out.log"))
+	require.Nil(t, err)
+	assert.Equal(t, "hello\n", string(content))
+}
+
+func TestTargetWriterConcurrent(t *testing.T) {
+	g := New(t, goldie.WithFixtureDir(t.TempDir()))
+	w := g.Add("out.txt").Filter(func(in []byte) []byte {
+		// Sort the lines, as the order of the writes is nondeterministic
+		lines := strings.SplitAfter(string(in), "\n")
+		sort.Strings(lines)
+		return []byte(strings.Join(lines, ""))
+	}).Writer()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, err := fmt.Fprintf(w, "line %d\n", i)
+				assert.Nil(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	g.Update()
+	g.Assert()
+	assert.Equal(t, 1000, strings.Count(g.Files["out.txt"].Buffer.String(), "\n"))
+}
+
+func TestCase(t *testing.T) {
+	fixtureDir := t.TempDir()
+	g := New(t, goldie.WithFixtureDir(fixtureDir))
+	for _, tc := range []string{"case1", "case2"} {
+		_, err := g.Case(tc + ".yaml").Writer().Write([]byte(tc + "\n"))
+		require.Nil(t, err)
+	}
+
+	g.Update()
+	g.Assert()
+
+	content, err := os.ReadFile(filepath.Join(fixtureDir, "TestCase", "case2.yaml.golden"))
+	require.Nil(t, err)
+	assert.Equal(t, "case2\n", string(content))
+}
+
+func TestAssertOrUpdate_Update(t *testing.T) {
+	require.Nil(t, flag.Set("update", "true"))
+	defer func() { require.Nil(t, flag.Set("update", "false")) }()
+
+	fixtureDir := t.TempDir()
+	// The golden file of a directory target file that isn't written anymore
+	removed := filepath.Join(fixtureDir, "dir", "removed.golden")
+	require.Nil(t, os.MkdirAll(filepath.Dir(removed), 0o755))
+	require.Nil(t, os.WriteFile(removed, []byte("old"), 0o600))
+
+	g := New(t, goldie.WithFixtureDir(fixtureDir))
+	_, _ = g.Add("buffered").Writer().Write([]byte("buffered content"))
+	_, _ = g.AddStream("streamed").Writer().Write([]byte("streamed content"))
+	require.Nil(t, os.WriteFile(g.AddDir("dir").File("written"), []byte("dir content"), 0o600))
+	g.AssertOrUpdate()
+
+	for name, want := range map[string]string{
+		"buffered.golden":    "buffered content",
+		"streamed.golden":    "streamed content",
+		"dir/written.golden": "dir content",
+	} {
+		got, err := os.ReadFile(filepath.Join(fixtureDir, filepath.FromSlash(name)))
+		require.Nil(t, err)
+		assert.Equal(t, want, string(got), name)
+	}
+	assert.NoFileExists(t, removed)
+
+	// The updated golden files are up-to-date
+	require.Nil(t, flag.Set("update", "false"))
+	g.Assert()
+}
@@ -0,0 +1,178 @@
+package filetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// NormalizeJSON returns a Filter that parses the content as a stream of JSON
+// values, and re-emits each of them indented by two spaces, with object keys
+// sorted and followed by a newline. Object fields with any of the given names
+// are dropped at any level, e.g. for volatile fields like timestamps. This
+// allows JSON written by nondeterministic encoders to be compared.
+//
+// If the content is not valid JSON, it is returned as-is, such that the
+// golden file comparison shows the actual content. Use NormalizeJSONE to
+// fail the test instead.
+func NormalizeJSON(dropFields ...string) Filter {
+	return orInput(NormalizeJSONE(dropFields...))
+}
+
+// NormalizeJSONE is like NormalizeJSON, but fails if the content is not
+// valid JSON.
+func NormalizeJSONE(dropFields ...string) FilterE {
+	drop := make(map[string]bool, len(dropFields))
+	for _, field := range dropFields {
+		drop[field] = true
+	}
+	return func(in []byte) ([]byte, error) {
+		var out bytes.Buffer
+		dec := json.NewDecoder(bytes.NewReader(in))
+		// Don't lose precision of numbers
+		dec.UseNumber()
+		for {
+			var obj interface{}
+			err := dec.Decode(&obj)
+			if errors.Is(err, io.EOF) {
+				return out.Bytes(), nil
+			} else if err != nil {
+				return nil, fmt.Errorf("content is not valid JSON: %w", err)
+			}
+
+			b, err := json.MarshalIndent(dropJSONFields(obj, drop), "", "  ")
+			if err != nil {
+				return nil, err
+			}
+			out.Write(b)
+			out.WriteByte('\n')
+		}
+	}
+}
+
+// orInput converts f into a Filter that returns the input as-is if f fails.
+func orInput(f FilterE) Filter {
+	return func(in []byte) []byte {
+		out, err := f(in)
+		if err != nil {
+			return in
+		}
+		return out
+	}
+}
+
+// dropJSONFields recursively removes the object fields in drop from obj.
+func dropJSONFields(obj interface{}, drop map[string]bool) interface{} {
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if drop[key] {
+				delete(v, key)
+				continue
+			}
+			v[key] = dropJSONFields(val, drop)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = dropJSONFields(v[i], drop)
+		}
+	}
+	return obj
+}
+
+// NormalizeYAML returns a Filter that parses the content as a stream of YAML
+// documents, and re-emits them with a stable indentation and sorted map keys,
+// separated by "---". This allows YAML produced by third-party tools to be
+// compared.
+//
+// The documents are parsed and re-emitted using gopkg.in/yaml.v2, as the
+// deklarative YAML encoder isn't available yet. The output hence follows the
+// yaml.v2 conventions: two-space indentation, sequences not indented relative
+// to their parent mapping, and comments, anchors and the original quoting
+// style dropped. Golden files might need to be updated once the deklarative
+// YAML encoder is used instead.
+//
+// If the content is not valid YAML, it is returned as-is, such that the
+// golden file comparison shows the actual content. Use NormalizeYAMLE to
+// fail the test instead.
+func NormalizeYAML() Filter {
+	return orInput(NormalizeYAMLE())
+}
+
+// NormalizeYAMLE is like NormalizeYAML, but fails if the content is not
+// valid YAML.
+func NormalizeYAMLE() FilterE {
+	return func(in []byte) ([]byte, error) {
+		var docs [][]byte
+		dec := yaml.NewDecoder(bytes.NewReader(in))
+		for {
+			var obj interface{}
+			err := dec.Decode(&obj)
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("content is not valid YAML: %w", err)
+			}
+
+			b, err := yaml.Marshal(obj)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, b)
+		}
+		return bytes.Join(docs, []byte("---\n")), nil
+	}
+}
+
+// ReplaceRegexp returns a Filter that replaces all matches of the regular
+// expression pattern with replacement, e.g. to scrub temporary paths or
+// durations from the content. Inside replacement, $ signs are interpreted as
+// in regexp.Regexp.Expand, e.g. $1 is the text of the first submatch.
+//
+// ReplaceRegexp panics if pattern can't be compiled, like regexp.MustCompile.
+func ReplaceRegexp(pattern, replacement string) Filter {
+	re := regexp.MustCompile(pattern)
+	repl := []byte(replacement)
+	return func(in []byte) []byte {
+		return re.ReplaceAll(in, repl)
+	}
+}
+
+// TimestampToken is what ScrubTimestamps replaces timestamps with.
+const TimestampToken = "<timestamp>"
+
+const (
+	// rfc3339Pattern matches RFC 3339 and ISO 8601 timestamps, with optional
+	// fractional seconds, e.g. "2021-01-01T00:00:00.123Z" or
+	// "2021-01-01T00:00:00+0100".
+	rfc3339Pattern = `\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})`
+	// unixPattern matches Unix timestamps between 2001 and 2033 in seconds,
+	// with optional fractional seconds, or in milli-, micro- or nanoseconds,
+	// that are the value of a key named "ts", or containing "time". The key,
+	// separator and any opening quote are captured in the first submatch.
+	unixPattern = `(?i)(\b(?:ts|[\w.-]*time[\w.-]*)"?\s*[:=]\s*"?)1\d{9}(?:\.\d+|\d{3}|\d{6}|\d{9})?\b`
+)
+
+// ScrubTimestamps returns a Filter that replaces RFC 3339 and Unix timestamps
+// with TimestampToken, such that output from real clocks, e.g. logs and
+// traces, can be compared. Unix timestamps are recognized in seconds (with
+// optional fractional seconds), milliseconds, microseconds and nanoseconds,
+// for dates between 2001 and 2033.
+//
+// As Unix timestamps can't be told apart from other large numbers, e.g. IDs
+// or byte counts, they are only replaced when they're the value of a key named
+// "ts", or a key containing "time" (case-insensitively), e.g. "ts":1609459200,
+// time=1609459200123 or "startTimeUnixNano":"1609459200000000000". Use
+// ReplaceRegexp for scrubbing Unix timestamps in other contexts.
+func ScrubTimestamps() Filter {
+	rfc3339 := ReplaceRegexp(rfc3339Pattern, TimestampToken)
+	unix := ReplaceRegexp(unixPattern, "${1}"+TimestampToken)
+	return func(in []byte) []byte {
+		return unix(rfc3339(in))
+	}
+}
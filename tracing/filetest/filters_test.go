@@ -0,0 +1,163 @@
+package filetest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		dropFields []string
+		in         string
+		want       string
+	}{
+		{
+			name: "stream",
+			in:   `{"b": 1, "a": {"d": [1.50, 2], "c": null}}` + "\n" + `{"ts":123456789012345678901}`,
+			want: `{
+  "a": {
+    "c": null,
+    "d": [
+      1.50,
+      2
+    ]
+  },
+  "b": 1
+}
+{
+  "ts": 123456789012345678901
+}
+`,
+		},
+		{
+			name:       "drop fields",
+			dropFields: []string{"ts"},
+			in:         `{"msg": "foo", "ts": 1, "nested": [{"ts": 2, "x": true}]}`,
+			want: `{
+  "msg": "foo",
+  "nested": [
+    {
+      "x": true
+    }
+  ]
+}
+`,
+		},
+		{
+			name: "invalid",
+			in:   `{"foo": `,
+			want: `{"foo": `,
+		},
+	}
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			assert.Equal(t, rt.want, string(NormalizeJSON(rt.dropFields...)([]byte(rt.in))))
+		})
+	}
+}
+
+func TestNormalizeYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "sorted and indented",
+			in: `b: 1
+a:
+    d:
+        - foo
+        - {q: 2, p: 1}
+    c: "bar"
+`,
+			want: `a:
+  c: bar
+  d:
+  - foo
+  - p: 1
+    q: 2
+b: 1
+`,
+		},
+		{
+			name: "stream",
+			in:   "b: 1\na: 2\n---\n- foo\n",
+			want: "a: 2\nb: 1\n---\n- foo\n",
+		},
+		{
+			name: "invalid",
+			in:   "foo: [",
+			want: "foo: [",
+		},
+	}
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			assert.Equal(t, rt.want, string(NormalizeYAML()([]byte(rt.in))))
+		})
+	}
+}
+
+func TestNormalizeE(t *testing.T) {
+	out, err := NormalizeJSONE()([]byte(`{"b":1,"a":2}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "{\n  \"a\": 2,\n  \"b\": 1\n}\n", string(out))
+	_, err = NormalizeJSONE()([]byte(`{"a":`))
+	assert.EqualError(t, err, "content is not valid JSON: unexpected EOF")
+
+	out, err = NormalizeYAMLE()([]byte("b: 1\na: 2\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "a: 2\nb: 1\n", string(out))
+	_, err = NormalizeYAMLE()([]byte("foo: ["))
+	assert.Error(t, err)
+}
+
+func Test_applyFilters(t *testing.T) {
+	upper := Filter(bytes.ToUpper).E()
+	fail := func([]byte) ([]byte, error) { return nil, errors.New("boom") }
+
+	out, err := applyFilters([]byte("foo"), []FilterE{upper})
+	assert.Nil(t, err)
+	assert.Equal(t, "FOO", string(out))
+
+	_, err = applyFilters([]byte("foo"), []FilterE{upper, fail})
+	assert.EqualError(t, err, "filter 1 failed: boom")
+}
+
+func TestReplaceRegexp(t *testing.T) {
+	filter := ReplaceRegexp(`took [0-9.]+m?s`, "took <duration>")
+	assert.Equal(t, "took <duration>, then took <duration>",
+		string(filter([]byte("took 1.5ms, then took 3s"))))
+
+	filter = ReplaceRegexp(`/tmp/[^/]+/(\w+)`, "<tmp>/$1")
+	assert.Equal(t, "wrote <tmp>/foo", string(filter([]byte("wrote /tmp/TestFoo123/foo"))))
+
+	assert.Panics(t, func() { ReplaceRegexp(`(`, "") })
+}
+
+func TestScrubTimestamps(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: `{"ts":"2021-01-01T00:00:00Z"}`, want: `{"ts":"<timestamp>"}`},
+		{in: "2021-06-30T12:34:56.789+02:00\tINFO", want: "<timestamp>\tINFO"},
+		{in: "2021-06-30T12:34:56.789+0200 INFO", want: "<timestamp> INFO"},
+		{in: `{"ts":1609459200.123456}`, want: `{"ts":<timestamp>}`},
+		{in: `{"ts":1609459200123}`, want: `{"ts":<timestamp>}`},
+		{in: `{"ts":1609459200000000000}`, want: `{"ts":<timestamp>}`},
+		{in: `time=1609459200 level=info`, want: `time=<timestamp> level=info`},
+		{in: `{"startTimeUnixNano":"1609459200000000000"}`, want: `{"startTimeUnixNano":"<timestamp>"}`},
+		{in: `{"count":12345, "id":916094592001}`, want: `{"count":12345, "id":916094592001}`},
+		// Large integers that aren't the value of a timestamp key are kept
+		{in: `{"id":1609459200123, "bytes":1234567890}`, want: `{"id":1609459200123, "bytes":1234567890}`},
+		{in: "order 1609459200 shipped", want: "order 1609459200 shipped"},
+	}
+	for _, rt := range tests {
+		assert.Equal(t, rt.want, string(ScrubTimestamps()([]byte(rt.in))))
+	}
+}
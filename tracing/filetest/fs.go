@@ -0,0 +1,48 @@
+package filetest
+
+import (
+	"bytes"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/sebdah/goldie/v2"
+)
+
+// NewFS is like New, but reads the golden files from fsys instead of from
+// disk, e.g. from an embed.FS, such that golden files can be embedded in the
+// test binary and asserted in environments where testdata/ is not writable.
+// The golden file names are resolved like with New, e.g. including the
+// fixture directory "testdata", hence fsys should conventionally contain it.
+//
+// Golden files read from fsys are read-only; Update reports an error, and
+// the "-update" flag is ignored. Mismatches are shown as a unified diff.
+func NewFS(t *testing.T, fsys fs.FS, opts ...goldie.Option) *Tester { //nolint:thelper
+	g := New(t, opts...)
+	g.FS = fsys
+	return g
+}
+
+// assertFS verifies that actual matches the golden file for name in g.FS.
+func (g *Tester) assertFS(t *testing.T, name string, actual []byte) { //nolint:thelper
+	goldenFile := filepath.ToSlash(g.goldieFor(name).GoldenFileName(t, name))
+	expected, err := fs.ReadFile(g.FS, goldenFile)
+	if err != nil {
+		t.Errorf("Golden fixture %s could not be read: %v", goldenFile, err)
+		return
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("Result did not match the golden fixture. Diff is below:\n\n%s",
+			UnifiedDiff(string(actual), string(expected), DefaultDiffContext, false))
+	}
+}
+
+// assertMissingFS reports a golden file in g.FS that was not written.
+func assertMissingFS(t *testing.T, goldenFile string) { //nolint:thelper
+	t.Errorf("golden file %s was not written", filepath.ToSlash(goldenFile))
+}
+
+// updateFS reports that golden files in g.FS can't be updated.
+func updateFS(t *testing.T, name string, _ []byte) { //nolint:thelper
+	t.Errorf("golden file for %s is read-only, as it's read from an fs.FS", name)
+}
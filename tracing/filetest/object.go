@@ -0,0 +1,61 @@
+package filetest
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ContentType specifies the format Go values are marshalled in by
+// Target.Object.
+type ContentType string
+
+const (
+	// ContentTypeJSON marshals values as JSON, indented by two spaces.
+	ContentTypeJSON ContentType = "application/json"
+	// ContentTypeYAML marshals values as YAML using gopkg.in/yaml.v2, as the
+	// deklarative YAML encoder isn't available yet. Hence the yaml struct tags
+	// and the yaml.v2 output conventions apply, see NormalizeYAML.
+	ContentTypeYAML ContentType = "application/yaml"
+)
+
+// Object marshals obj in the given format and writes it to the Target, such
+// that the golden file is a snapshot of the Go value. This is useful for
+// verifying structs without marshalling them manually. The content ends with
+// a newline, and Object can be called multiple times to write a stream of
+// values; JSON values are then separated by newlines, and YAML documents by
+// "---".
+//
+// If obj can't be marshalled, or format is unknown, the error is reported
+// when asserting.
+func (b *Target) Object(obj interface{}, format ContentType) *Target {
+	content, err := marshal(obj, format)
+	if err != nil {
+		b.mu.Lock()
+		b.errs = append(b.errs, err)
+		b.mu.Unlock()
+		return b
+	}
+	// Separate YAML documents
+	if format == ContentTypeYAML && len(b.bytes()) != 0 {
+		content = append([]byte("---\n"), content...)
+	}
+	_, _ = b.Writer().Write(content)
+	return b
+}
+
+func marshal(obj interface{}, format ContentType) ([]byte, error) {
+	switch format {
+	case ContentTypeJSON:
+		b, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	case ContentTypeYAML:
+		return yaml.Marshal(obj)
+	default:
+		return nil, fmt.Errorf("unknown content type %q", format)
+	}
+}
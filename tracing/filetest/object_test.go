@@ -0,0 +1,25 @@
+package filetest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTargetObject(t *testing.T) {
+	type obj struct {
+		Name  string `json:"name" yaml:"name"`
+		Count int    `json:"count,omitempty" yaml:"count,omitempty"`
+	}
+
+	g := New(t)
+	b := g.Add("json").Object(obj{Name: "foo", Count: 1}, ContentTypeJSON).Object(obj{Name: "bar"}, ContentTypeJSON)
+	assert.Equal(t, "{\n  \"name\": \"foo\",\n  \"count\": 1\n}\n{\n  \"name\": \"bar\"\n}\n", b.Buffer.String())
+
+	b = g.Add("yaml").Object(obj{Name: "foo", Count: 1}, ContentTypeYAML).Object([]string{"bar"}, ContentTypeYAML)
+	assert.Equal(t, "name: foo\ncount: 1\n---\n- bar\n", b.Buffer.String())
+
+	b = g.Add("unknown").Object(obj{}, "text/plain").Object(make(chan int), ContentTypeJSON)
+	assert.Empty(t, b.Buffer.String())
+	assert.Len(t, b.errs, 2)
+}
@@ -0,0 +1,82 @@
+package filetest
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// AssertComplete is like Assert, but additionally fails if the golden
+// directory of the test contains golden files that were not written during
+// the test, e.g. dead fixtures left behind after a refactor. The golden
+// directory of the test is where Case puts golden files, and where
+// goldie.WithTestNameForDir does, e.g. testdata/TestFoo/. Golden files
+// directly under testdata/ can't be attributed to a single test, and are
+// hence not checked.
+//
+// If the "-update" flag is passed, the stale golden files are removed.
+func (g *Tester) AssertComplete() {
+	g.Assert()
+
+	checkMissing := assertMissing
+	if g.FS != nil {
+		checkMissing = assertMissingFS
+	}
+	stale, err := g.staleGoldenFiles()
+	if !assert.Nil(g.T, err) {
+		return
+	}
+	for _, goldenFile := range stale {
+		checkMissing(g.T, goldenFile)
+	}
+}
+
+// staleGoldenFiles returns the golden files in the golden directory of the
+// test that don't belong to any target.
+func (g *Tester) staleGoldenFiles() ([]string, error) {
+	written := make(map[string]bool, len(g.Files))
+	for name := range g.Files {
+		written[g.goldieFor(name).GoldenFileName(g.T, name)] = true
+	}
+	// Directory targets check for their own missing files
+	dirs := make([]string, 0, len(g.Dirs))
+	for name := range g.Dirs {
+		dir, _ := g.goldenDir(g.T, name)
+		dirs = append(dirs, dir+string(filepath.Separator))
+	}
+
+	testDir := g.testGoldenDir()
+	golden, err := g.goldenFiles(testDir, "")
+	if err != nil {
+		return nil, err
+	}
+	var stale []string
+	for _, rel := range golden {
+		goldenFile := filepath.Join(testDir, filepath.FromSlash(rel))
+		if !written[goldenFile] && !hasAnyPrefix(goldenFile, dirs) {
+			stale = append(stale, goldenFile)
+		}
+	}
+	return stale, nil
+}
+
+// testGoldenDir returns the golden directory of the test.
+func (g *Tester) testGoldenDir() string {
+	testName := filepath.FromSlash(g.T.Name())
+	dir := filepath.Dir(g.G.GoldenFileName(g.T, "probe"))
+	// goldie.WithTestNameForDir already includes the test name
+	if strings.HasSuffix(dir, string(filepath.Separator)+testName) {
+		return dir
+	}
+	return filepath.Join(dir, testName)
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package filetest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/sebdah/goldie/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssertComplete(t *testing.T) {
+	fixtureDir := t.TempDir()
+	for _, f := range []string{
+		"TestAssertComplete/case1.golden",
+		"TestAssertComplete/stale.golden",
+		"TestAssertComplete/out/a.golden",
+		"TestAssertComplete/out/stale.golden",
+		"other.golden",
+	} {
+		p := filepath.Join(fixtureDir, filepath.FromSlash(f))
+		require.Nil(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.Nil(t, os.WriteFile(p, []byte("a"), 0o600))
+	}
+
+	g := New(t, goldie.WithFixtureDir(fixtureDir))
+	g.Case("case1")
+	g.AddDir("TestAssertComplete/out")
+
+	stale, err := g.staleGoldenFiles()
+	require.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(fixtureDir, "TestAssertComplete", "stale.golden")}, stale)
+
+	g = New(t, goldie.WithFixtureDir(fixtureDir), goldie.WithTestNameForDir(true))
+	g.Add("case1")
+	stale, err = g.staleGoldenFiles()
+	require.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(fixtureDir, "TestAssertComplete", "out", "a.golden"),
+		filepath.Join(fixtureDir, "TestAssertComplete", "out", "stale.golden"),
+		filepath.Join(fixtureDir, "TestAssertComplete", "stale.golden"),
+	}, stale)
+}
+
+// assertCompleteFixtureDirEnv is set to the fixture directory when the test
+// binary is run by TestAssertComplete_Public, which makes
+// TestAssertComplete_Helper call AssertComplete. A failing test can't be
+// run as a subtest, as that would fail the parent test as well.
+const assertCompleteFixtureDirEnv = "FILETEST_ASSERT_COMPLETE_FIXTURE_DIR"
+
+func TestAssertComplete_Helper(t *testing.T) {
+	fixtureDir := os.Getenv(assertCompleteFixtureDirEnv)
+	if fixtureDir == "" {
+		t.Skip("only run by TestAssertComplete_Public")
+	}
+	g := New(t, goldie.WithFixtureDir(fixtureDir))
+	_, _ = g.Case("case1").Writer().Write([]byte("a"))
+	g.AssertComplete()
+}
+
+func TestAssertComplete_Public(t *testing.T) {
+	fixtureDir := t.TempDir()
+	goldenFile := func(name string) string {
+		return filepath.Join(fixtureDir, "TestAssertComplete_Helper", name)
+	}
+	for _, p := range []string{
+		goldenFile("case1.golden"),
+		goldenFile("stale.golden"),
+		filepath.Join(fixtureDir, "other.golden"),
+	} {
+		require.Nil(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.Nil(t, os.WriteFile(p, []byte("a"), 0o600))
+	}
+
+	run := func(args ...string) (string, error) {
+		args = append([]string{"-test.run=^TestAssertComplete_Helper$", "-test.v"}, args...)
+		cmd := exec.Command(os.Args[0], args...) //nolint:gosec
+		cmd.Env = append(os.Environ(), assertCompleteFixtureDirEnv+"="+fixtureDir)
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+
+	// The stale golden file fails the test, and is reported by its path
+	out, err := run()
+	assert.Error(t, err)
+	assert.Contains(t, out, "golden file "+goldenFile("stale.golden")+" was not written")
+	assert.NotContains(t, out, "case1.golden was not written")
+	assert.NotContains(t, out, "other.golden")
+	assert.FileExists(t, goldenFile("stale.golden"))
+
+	// -update removes the stale golden file, but keeps the others
+	out, err = run("-update")
+	assert.Nil(t, err, out)
+	assert.NoFileExists(t, goldenFile("stale.golden"))
+	assert.FileExists(t, goldenFile("case1.golden"))
+	assert.FileExists(t, filepath.Join(fixtureDir, "other.golden"))
+
+	// After which the golden directory is complete
+	out, err = run()
+	assert.Nil(t, err, out)
+}
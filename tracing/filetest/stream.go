@@ -0,0 +1,184 @@
+package filetest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sebdah/goldie/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// streamChunkSize is the size of the chunks streamed content is compared in.
+const streamChunkSize = 64 * 1024
+
+// AddStream adds a new file target to the Files map, like Add, but the content
+// written to the Target is streamed to a temporary file instead of being
+// buffered in memory. This allows verifying very large outputs, e.g.
+// multi-hundred-MB exporter dumps. The Buffer of the Target is not used.
+//
+// When asserting, the content is compared chunk by chunk to the golden file.
+// Instead of a diff, a mismatch is reported with the offset of the first
+// differing byte, and the sizes and SHA-256 sums of both files. Filters and
+// Binary are not supported for streamed targets, as they need the whole
+// content in memory.
+func (g *Tester) AddStream(name string, opts ...goldie.Option) *Target {
+	b := g.Add(name, opts...)
+	// The file is closed before the temporary directory is removed, as
+	// cleanup functions are called in last added, first called order.
+	f, err := os.Create(filepath.Join(g.T.TempDir(), "stream"))
+	if err != nil {
+		g.T.Fatalf("could not create stream file for %s: %v", name, err)
+	}
+	g.T.Cleanup(func() { _ = f.Close() })
+	b.file = f
+	return b
+}
+
+func (g *Tester) doStream(name string, a *Target, fn func(*testing.T, string, string)) {
+	g.T.Run(name, func(t *testing.T) {
+		if len(a.Filters) != 0 || a.binary {
+			t.Errorf("filters and hexdumps are not supported for the streamed target %s", name)
+			return
+		}
+		fn(t, name, a.file.Name())
+	})
+}
+
+// assertStream verifies that the content of the file at path matches the
+// golden file for name, either on disk or in g.FS.
+func (g *Tester) assertStream(t *testing.T, name, path string) { //nolint:thelper
+	goldenFile := g.goldieFor(name).GoldenFileName(t, name)
+	var expected io.ReadCloser
+	var err error
+	if g.FS != nil {
+		goldenFile = filepath.ToSlash(goldenFile)
+		expected, err = g.FS.Open(goldenFile)
+	} else {
+		expected, err = os.Open(goldenFile)
+	}
+	if err != nil {
+		t.Errorf("Golden fixture %s could not be read: %v", goldenFile, err)
+		return
+	}
+	defer expected.Close()
+
+	actual, err := os.Open(path)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer actual.Close()
+
+	diff, err := compareStreams(actual, expected)
+	if !assert.Nil(t, err) {
+		return
+	}
+	if diff != nil {
+		t.Errorf("Result did not match the golden fixture %s. First difference at byte offset %d.\n"+
+			"Actual:   %d bytes, sha256 %x\nExpected: %d bytes, sha256 %x",
+			goldenFile, diff.offset, diff.actual.size, diff.actual.sum, diff.expected.size, diff.expected.sum)
+	}
+}
+
+// updateStream copies the file at path to the golden file for name.
+func (g *Tester) updateStream(t *testing.T, name, path string) { //nolint:thelper
+	goldenFile := g.goldieFor(name).GoldenFileName(t, name)
+	if !assert.Nil(t, os.MkdirAll(filepath.Dir(goldenFile), 0o755)) {
+		return
+	}
+	src, err := os.Open(path)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer src.Close()
+	dst, err := os.Create(goldenFile)
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, err = io.Copy(dst, src)
+	assert.Nil(t, err)
+	assert.Nil(t, dst.Close())
+}
+
+// streamDiff describes how two streams differ.
+type streamDiff struct {
+	// offset is the offset of the first differing byte.
+	offset   int64
+	actual   streamSum
+	expected streamSum
+}
+
+type streamSum struct {
+	size int64
+	sum  []byte
+}
+
+// compareStreams compares actual and expected chunk by chunk. If they differ,
+// the rest of both streams is read to compute their sizes and checksums.
+func compareStreams(actual, expected io.Reader) (*streamDiff, error) {
+	actualHash, expectedHash := sha256.New(), sha256.New()
+	actual, expected = io.TeeReader(actual, actualHash), io.TeeReader(expected, expectedHash)
+
+	a, e := make([]byte, streamChunkSize), make([]byte, streamChunkSize)
+	var offset int64
+	for {
+		na, errA := readChunk(actual, a)
+		ne, errE := readChunk(expected, e)
+		if errA != nil {
+			return nil, errA
+		} else if errE != nil {
+			return nil, errE
+		}
+
+		if i := firstDiff(a[:na], e[:ne]); i != -1 {
+			diff := &streamDiff{offset: offset + int64(i)}
+			var err error
+			diff.actual, err = drain(actual, actualHash, offset+int64(na))
+			if err != nil {
+				return nil, err
+			}
+			diff.expected, err = drain(expected, expectedHash, offset+int64(ne))
+			return diff, err
+		}
+		// Both streams are at EOF
+		if na == 0 {
+			return nil, nil
+		}
+		offset += int64(na)
+	}
+}
+
+// readChunk fills buf from r, unless r reaches EOF first.
+func readChunk(r io.Reader, buf []byte) (int, error) {
+	n, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return n, nil
+	}
+	return n, err
+}
+
+// firstDiff returns the index of the first differing byte of a and b, or -1
+// if they are equal.
+func firstDiff(a, b []byte) int {
+	if bytes.Equal(a, b) {
+		return -1
+	}
+	for i := range a {
+		if i >= len(b) || a[i] != b[i] {
+			return i
+		}
+	}
+	return len(a)
+}
+
+// drain reads the rest of r, which is teed to h, and returns the total size
+// and checksum of the stream. read is the number of bytes already read.
+func drain(r io.Reader, h hash.Hash, read int64) (streamSum, error) {
+	n, err := io.Copy(io.Discard, r)
+	return streamSum{size: read + n, sum: h.Sum(nil)}, err
+}
@@ -0,0 +1,96 @@
+package filetest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sebdah/goldie/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddStream(t *testing.T) {
+	fixtureDir := t.TempDir()
+	g := New(t, goldie.WithFixtureDir(fixtureDir))
+	w := g.AddStream("out.txt").Writer()
+	for i := 0; i < 10000; i++ {
+		_, err := w.Write([]byte("0123456789abcdef\n"))
+		require.Nil(t, err)
+	}
+	assert.Zero(t, g.Files["out.txt"].Buffer.Len())
+
+	g.Update()
+	g.Assert()
+
+	info, err := os.Stat(filepath.Join(fixtureDir, "out.txt.golden"))
+	require.Nil(t, err)
+	assert.Equal(t, int64(170000), info.Size())
+}
+
+func Test_compareStreams(t *testing.T) {
+	chunks := bytes.Repeat([]byte{'a'}, 2*streamChunkSize+10)
+	changed := append([]byte(nil), chunks...)
+	changed[streamChunkSize+5] = 'b'
+	sum := func(b []byte) []byte {
+		s := sha256.Sum256(b)
+		return s[:]
+	}
+
+	tests := []struct {
+		name     string
+		actual   []byte
+		expected []byte
+		want     *streamDiff
+	}{
+		{
+			name:     "equal",
+			actual:   chunks,
+			expected: chunks,
+		},
+		{
+			name:     "empty",
+			actual:   nil,
+			expected: nil,
+		},
+		{
+			name:     "changed byte",
+			actual:   changed,
+			expected: chunks,
+			want: &streamDiff{
+				offset:   streamChunkSize + 5,
+				actual:   streamSum{size: int64(len(changed)), sum: sum(changed)},
+				expected: streamSum{size: int64(len(chunks)), sum: sum(chunks)},
+			},
+		},
+		{
+			name:     "truncated",
+			actual:   chunks[:streamChunkSize],
+			expected: chunks,
+			want: &streamDiff{
+				offset:   streamChunkSize,
+				actual:   streamSum{size: streamChunkSize, sum: sum(chunks[:streamChunkSize])},
+				expected: streamSum{size: int64(len(chunks)), sum: sum(chunks)},
+			},
+		},
+		{
+			name:     "appended",
+			actual:   chunks,
+			expected: chunks[:10],
+			want: &streamDiff{
+				offset:   10,
+				actual:   streamSum{size: int64(len(chunks)), sum: sum(chunks)},
+				expected: streamSum{size: 10, sum: sum(chunks[:10])},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := compareStreams(bytes.NewReader(tt.actual), bytes.NewReader(tt.expected))
+			require.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package filetest
+
+import (
+	"sort"
+	"strings"
+)
+
+// WithVars registers variables for golden templates. Golden files may contain
+// ${NAME} placeholders for values that legitimately vary, e.g. version strings
+// or module paths, instead of filtering them out. Before comparing, every
+// occurrence of a variable's value in the filtered content is replaced with
+// its placeholder; hence updating writes the placeholders to the golden files.
+// Longer values take precedence, and placeholders are never substituted
+// again, even if they contain another variable's value. Variables with empty
+// values are ignored.
+//
+// Variables are not substituted for streamed targets; see AddStream.
+// A call to this function appends to the map of previous values.
+func (g *Tester) WithVars(vars map[string]string) *Tester {
+	if g.vars == nil {
+		g.vars = make(map[string]string, len(vars))
+	}
+	for name, val := range vars {
+		g.vars[name] = val
+	}
+	return g
+}
+
+// substituteVars replaces the values of vars in content with their
+// placeholders.
+func substituteVars(content []byte, vars map[string]string) []byte {
+	names := make([]string, 0, len(vars))
+	for name, val := range vars {
+		if len(val) != 0 {
+			names = append(names, name)
+		}
+	}
+	// Prefer longer values, such that values containing other values are
+	// replaced as a whole. Sort by name as well to be deterministic.
+	sort.Slice(names, func(i, j int) bool {
+		vi, vj := vars[names[i]], vars[names[j]]
+		if len(vi) != len(vj) {
+			return len(vi) > len(vj)
+		}
+		return names[i] < names[j]
+	})
+	// Replace all values in a single pass, such that the inserted placeholders
+	// aren't matched by other values. The Replacer tries the old strings in
+	// argument order at each position, hence the longest value wins.
+	oldnew := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		oldnew = append(oldnew, vars[name], "${"+name+"}")
+	}
+	return []byte(strings.NewReplacer(oldnew...).Replace(string(content)))
+}
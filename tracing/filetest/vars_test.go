@@ -0,0 +1,60 @@
+package filetest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sebdah/goldie/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithVars(t *testing.T) {
+	fixtureDir := t.TempDir()
+	g := New(t, goldie.WithFixtureDir(fixtureDir)).
+		WithVars(map[string]string{"MODULE": "example.com/foo"}).
+		WithVars(map[string]string{"PKG": "example.com/foo/bar", "EMPTY": ""})
+	_, err := g.Add("out.txt").Writer().Write([]byte("example.com/foo/bar in example.com/foo\n"))
+	require.Nil(t, err)
+
+	g.Update()
+	g.Assert()
+
+	content, err := os.ReadFile(filepath.Join(fixtureDir, "out.txt.golden"))
+	require.Nil(t, err)
+	assert.Equal(t, "${PKG} in ${MODULE}\n", string(content))
+}
+
+func Test_substituteVars(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		vars    map[string]string
+		want    string
+	}{
+		{
+			name:    "longest first",
+			content: "github.com/x/y in github.com/x",
+			vars:    map[string]string{"MOD": "github.com/x", "PKG": "github.com/x/y"},
+			want:    "${PKG} in ${MOD}",
+		},
+		{
+			name:    "placeholders are not substituted again",
+			content: "github.com/x and MOD",
+			vars:    map[string]string{"MOD": "github.com/x", "X": "MOD"},
+			want:    "${MOD} and ${X}",
+		},
+		{
+			name:    "empty values are ignored",
+			content: "foo",
+			vars:    map[string]string{"EMPTY": ""},
+			want:    "foo",
+		},
+	}
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			assert.Equal(t, rt.want, string(substituteVars([]byte(rt.content), rt.vars)))
+		})
+	}
+}
@@ -53,7 +53,7 @@ type AcquireLoggerFunc func(context.Context) Logger
 // It tries to resolve a logger from the given context using logr.FromContext,
 // but if no Logger is registered, it defaults to GetGlobalLogger().
 func DefaultAcquireLoggerFunc(ctx context.Context) Logger {
-	if log := logr.FromContext(ctx); log != nil {
+	if log, err := logr.FromContext(ctx); err == nil {
 		return log
 	}
 	return GetGlobalLogger()
@@ -69,7 +69,11 @@ func LoggerFromContext(ctx context.Context) Logger {
 	acquireLoggerFuncMu.Lock()
 	defer acquireLoggerFuncMu.Unlock()
 
-	return acquireLoggerFunc(ctx)
+	// Never return the zero value Logger, as it is not usable
+	if log := acquireLoggerFunc(ctx); log.GetSink() != nil {
+		return log
+	}
+	return logr.Discard()
 }
 
 // SetAcquireLoggerFunc sets the globally-registered AcquireLoggerFunc
@@ -1,21 +1,21 @@
 module github.com/luxas/deklarative/tracing
 
+// The sloglog package requires Go 1.21, and is gated by the go1.21 build tag,
+// such that the rest of the module still supports Go 1.16.
 go 1.16
 
-replace (
-	// TODO: Fix this log level propagation issue upstream.
-	github.com/go-logr/zapr => github.com/luxas/zapr v0.4.1
-	// TODO: Remove this once https://github.com/open-telemetry/opentelemetry-go/pull/2196
-	// is merged.
-	go.opentelemetry.io/otel/exporters/stdout/stdouttrace => github.com/luxas/opentelemetry-go/exporters/stdout/stdouttrace v1.0.0-RC2-fix-timestamps
-)
+// TODO: Remove this once https://github.com/open-telemetry/opentelemetry-go/pull/2196
+// is merged.
+replace go.opentelemetry.io/otel/exporters/stdout/stdouttrace => github.com/luxas/opentelemetry-go/exporters/stdout/stdouttrace v1.0.0-RC2-fix-timestamps
 
 require (
-	github.com/go-logr/logr v0.4.0
-	github.com/go-logr/stdr v0.4.0
-	github.com/go-logr/zapr v0.4.0
+	github.com/go-logr/logr v1.2.0
+	github.com/go-logr/stdr v1.2.0
+	github.com/go-logr/zapr v1.2.0
 	github.com/maxbrunsfeld/counterfeiter/v6 v6.4.1
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/sebdah/goldie/v2 v2.5.3
+	github.com/sirupsen/logrus v1.8.1
 	github.com/stretchr/testify v1.7.0
 	go.opentelemetry.io/otel v1.0.0-RC2
 	go.opentelemetry.io/otel/exporters/jaeger v1.0.0-RC2
@@ -23,7 +23,12 @@ require (
 	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.0.0-RC2
 	go.opentelemetry.io/otel/sdk v1.0.0-RC2
 	go.opentelemetry.io/otel/trace v1.0.0-RC2
+	go.opentelemetry.io/proto/otlp v0.9.0
 	go.uber.org/multierr v1.7.0
-	go.uber.org/zap v1.19.0
+	go.uber.org/zap v1.21.0
+	golang.org/x/sys v0.0.0-20210510120138-977fb7262007
+	google.golang.org/grpc v1.39.0
+	gopkg.in/natefinch/lumberjack.v2 v2.0.0
 	gopkg.in/yaml.v2 v2.4.0
+	k8s.io/klog/v2 v2.30.0
 )
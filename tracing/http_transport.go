@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WrapTransport returns a http.RoundTripper that starts a child span using the
+// TracerBuilder for each outbound request, using the context of the request. If
+// rt is nil, http.DefaultTransport is used.
+//
+// The request method and URL, and the response status code are registered as
+// attributes. The span status is set according to the response status code, and
+// errors returned from rt are registered with the span. As the span is started
+// using the TracerBuilder, the Logger and LogLevelIncreaser of the request context
+// are used as for any other span.
+//
+// The span ends when rt returns, that is, when the response headers are received.
+func WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &tracingTransport{rt}
+}
+
+type tracingTransport struct {
+	rt http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (_ *http.Response, retErr error) {
+	ctx, span := Tracer().
+		WithActor(t).
+		WithAttributes(
+			semconv.HTTPMethodKey.String(req.Method),
+			semconv.HTTPURLKey.String(req.URL.String()),
+		).
+		Capture(&retErr).
+		Start(req.Context(), req.Method, trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	resp, err := t.rt.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(resp.StatusCode)...)
+	if code, desc := semconv.SpanStatusFromHTTPStatusCode(resp.StatusCode); code != codes.Unset {
+		span.SetStatus(code, desc)
+	}
+	return resp, nil
+}
+
+// TracerName implements TracerNamed.
+func (t *tracingTransport) TracerName() string { return "http.RoundTripper" }
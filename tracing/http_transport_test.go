@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	var yamlTrace bytes.Buffer
+	tp, err := Provider().TestYAMLTo(&yamlTrace).Build()
+	require.Nil(t, err)
+	ctx := Context().WithTracerProvider(tp).Build()
+
+	client := &http.Client{Transport: WrapTransport(nil)}
+	for _, path := range []string{"/", "/missing"} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path, nil)
+		require.Nil(t, err)
+		resp, err := client.Do(req)
+		require.Nil(t, err)
+		assert.Nil(t, resp.Body.Close())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://invalid.localhost:0", nil)
+	require.Nil(t, err)
+	_, err = client.Do(req) //nolint:bodyclose
+	assert.NotNil(t, err)
+
+	out := yamlTrace.String()
+	assert.True(t, strings.HasPrefix(out, `# http.RoundTripper.GET
+- spanName: http.RoundTripper.GET
+  attributes:
+    http.status_code: 200
+  startConfig:
+    attributes:
+      http.method: GET
+      http.url: `+srv.URL+`/
+    spanKind: 3
+
+# http.RoundTripper.GET
+- spanName: http.RoundTripper.GET
+  attributes:
+    http.status_code: 404
+  startConfig:
+    attributes:
+      http.method: GET
+      http.url: `+srv.URL+`/missing
+    spanKind: 3
+  statusChanges:
+  - code: Error
+
+# http.RoundTripper.GET
+- spanName: http.RoundTripper.GET
+  errors:
+`), out)
+}
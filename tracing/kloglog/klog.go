@@ -0,0 +1,113 @@
+// Package kloglog provides a builder-pattern constructor for creating a
+// logr.Logger implementation backed by k8s.io/klog/v2, for e.g. Kubernetes
+// controllers that already use klog and want to adopt the tracing package
+// without switching log libraries.
+//
+// Note that klog is configured through global state. Hence, building a
+// Logger using this package reconfigures the output and verbosity of all
+// klog usage in the binary.
+package kloglog
+
+import (
+	"flag"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	"github.com/luxas/deklarative/tracing/filetest"
+	"k8s.io/klog/v2"
+	"k8s.io/klog/v2/klogr"
+)
+
+// NewKlog returns a new *Builder using the default configuration.
+func NewKlog() *Builder {
+	return &Builder{outW: os.Stderr}
+}
+
+// Builder is a builder-pattern struct for building a logr.Logger
+// using k8s.io/klog/v2.
+//
+// The default configuration writes klog-formatted text including headers
+// to os.Stderr, and logs upto log level 0.
+type Builder struct {
+	outW      io.Writer
+	level     int8
+	noHeaders bool
+}
+
+// LogTo specifies where to write logs. All severities are written once
+// to w.
+//
+// Defaults to os.Stderr.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) LogTo(w io.Writer) *Builder {
+	b.outW = w
+	return b
+}
+
+// LogUpto specifies the logr level that shall be used. All log messages from
+// a logr.Logger with a log level _less than or equal to_ logrLevel will be output.
+// This corresponds to the -v flag of klog.
+//
+// According to logr.Logger, "it's illegal to pass a log
+// level less than zero.", hence, negative logrLevel values are disallowed.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) LogUpto(logrLevel int8) *Builder {
+	if logrLevel >= 0 {
+		b.level = logrLevel
+	}
+	return b
+}
+
+// NoHeaders omits the klog headers, that is, the severity, timestamp, PID
+// and caller, from the logs. It's useful for deterministic output in examples
+// and tests. This corresponds to the -skip_headers flag of klog.
+//
+// By default headers are included in the log output.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) NoHeaders() *Builder {
+	b.noHeaders = true
+	return b
+}
+
+// Example is a shorthand for NoHeaders().
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) Example() *Builder {
+	return b.NoHeaders()
+}
+
+// Test is a shorthand for verifying log output in a test with the help of the
+// filetest package. Given a filetest.Tester, this will make the logger log to
+// a file under testdata/ with the name of the test + the ".log" suffix.
+func (b *Builder) Test(g *filetest.Tester) *Builder {
+	return b.LogTo(g.Add(g.T.Name() + ".log").Writer())
+}
+
+// Build configures klog globally with the given options, and returns a
+// logr.Logger backed by klog.
+//
+// By default the logger name is an empty string, and the log level is 0.
+func (b *Builder) Build() logr.Logger {
+	fs := flag.NewFlagSet("klog", flag.PanicOnError)
+	klog.InitFlags(fs)
+	for name, value := range map[string]string{
+		// Write everything to outW, once
+		"logtostderr":     "false",
+		"alsologtostderr": "false",
+		"stderrthreshold": "FATAL",
+		"one_output":      "true",
+		"v":               strconv.Itoa(int(b.level)),
+		"skip_headers":    strconv.FormatBool(b.noHeaders),
+	} {
+		// The flags are known to exist, and the values are valid
+		_ = fs.Set(name, value)
+	}
+	klog.SetOutput(b.outW)
+
+	return klogr.NewWithOptions(klogr.WithFormat(klogr.FormatKlog))
+}
@@ -0,0 +1,54 @@
+package kloglog
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/luxas/deklarative/tracing/filetest"
+)
+
+func ExampleBuilder() {
+	// Build an example logger called bar that logs levels <= 1.
+	log := NewKlog().Example().LogTo(os.Stdout).LogUpto(1).Build().WithName("bar")
+
+	// Sample info usage
+	log.Info("some message", "foo", true)
+	log.WithValues("bar", 1).V(1).Info("hello")
+
+	// Sample error usage
+	err := errors.New("unexpected error") //nolint:goerr113
+	log.Error(err, "I don't know what happened here", "duration", time.Minute)
+
+	// Verify that v=2 is disabled (i.e. discarded), but v=1 is enabled
+	log.V(1).Info("am I enabled?", "enabled", log.V(1).Enabled())
+	log.V(2).Info("am I enabled?", "enabled", log.V(2).Enabled())
+
+	// Output:
+	// "bar: some message" foo=true
+	// "bar: hello" bar=1
+	// "bar: I don't know what happened here" err="unexpected error" duration="1m0s"
+	// "bar: am I enabled?" enabled=true
+}
+
+func TestTestdata(t *testing.T) {
+	g := filetest.New(t)
+	defer g.Assert()
+
+	// Build an example logger called bar that logs levels <= 1.
+	log := NewKlog().
+		Example().
+		Test(g).
+		LogUpto(1).
+		Build().
+		WithName("bar")
+
+	log.Info("some message", "foo", true)
+	log.WithName("baz").WithValues("bar", 1).V(1).Info("hello")
+	log.Info("odd number of arguments are ignored", "hello")
+
+	err := errors.New("unexpected error") //nolint:goerr113
+	log.Error(err, "I don't know what happened here", "duration", time.Minute)
+	log.V(2).Info("too verbose, ignored")
+}
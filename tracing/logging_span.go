@@ -1,7 +1,9 @@
 package tracing
 
 import (
-	"github.com/go-logr/logr"
+	"sync"
+	"time"
+
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
@@ -18,6 +20,48 @@ type loggingSpan struct {
 	log      Logger
 	err      *error
 	errFn    ErrRegisterFunc
+	// start is set if the span duration shall be logged when the span ends.
+	start time.Time
+
+	// pooledLogger is set if the span and this logger shall be released
+	// back to their pools when the span ends.
+	pooledLogger *spanLogger
+}
+
+//nolint:gochecknoglobals
+var (
+	loggingSpanPool = &sync.Pool{New: func() interface{} { return &loggingSpan{} }}
+	spanLoggerPool  = &sync.Pool{New: func() interface{} { return &spanLogger{} }}
+)
+
+// newLoggingSpan returns an empty *loggingSpan, possibly from the pool.
+func newLoggingSpan(pooled bool) *loggingSpan {
+	if !pooled {
+		return &loggingSpan{}
+	}
+	s, _ := loggingSpanPool.Get().(*loggingSpan)
+	return s
+}
+
+// newSpanLogger returns an empty *spanLogger, possibly from the pool.
+func newSpanLogger(pooled bool) *spanLogger {
+	if !pooled {
+		return &spanLogger{}
+	}
+	l, _ := spanLoggerPool.Get().(*spanLogger)
+	return l
+}
+
+// release puts the span and its logger back to their pools, if pooled. After
+// this, the span and logger must not be used; see TracerBuilder.Pooled.
+func (s *loggingSpan) release() {
+	if s.pooledLogger == nil {
+		return
+	}
+	*s.pooledLogger = spanLogger{}
+	spanLoggerPool.Put(s.pooledLogger)
+	*s = loggingSpan{}
+	loggingSpanPool.Put(s)
 }
 
 const (
@@ -25,6 +69,8 @@ const (
 	spanEventKey             = "span-event"
 	spanStatusCodeKey        = "span-status-code"
 	spanStatusDescriptionKey = "span-status-description"
+	spanDurationKey          = "span-duration"
+	spanLinkKey              = "span-link"
 	// SpanAttributePrefix is the prefix used when logging an attribute registered
 	// with a Span.
 	SpanAttributePrefix = "span-attr-"
@@ -35,33 +81,49 @@ const (
 
 func (s *loggingSpan) TracerProvider() trace.TracerProvider { return s.provider }
 
-func (s *loggingSpan) End(options ...trace.SpanEndOption) {
+func (s *loggingSpan) End(options ...trace.SpanEndOption) { s.end(1, options) }
+
+// end implements End. callDepth is the number of stack frames between the
+// caller of interest and this function.
+func (s *loggingSpan) end(callDepth int, options []trace.SpanEndOption) {
 	// Register the error, if any
-	log := logr.WithCallDepth(s.log, 1)
+	log := s.log.WithCallDepth(callDepth + 1)
 	if s.err != nil {
 		s2 := *s
-		s2.log = logr.WithCallDepth(log, 1)
+		s2.log = log.WithCallDepth(1)
 		s.errFn(*s.err, &s2, log)
 	}
 
-	log.Info("ending span")
+	if !s.start.IsZero() {
+		log.Info("ending span", spanDurationKey, time.Since(s.start))
+	} else {
+		log.Info("ending span")
+	}
 	s.Span.End(options...)
+	s.release()
 }
 
 func (s *loggingSpan) AddEvent(name string, options ...trace.EventOption) {
-	log := logr.WithCallDepth(s.log, 1)
+	log := s.log.WithCallDepth(1)
 	log.Info("span event", spanEventKey, name)
 	s.Span.AddEvent(name, options...)
 }
 
+// AddLink implements LinkAdder.
+func (s *loggingSpan) AddLink(link trace.Link) {
+	log := s.log.WithCallDepth(1)
+	log.Info("span link", spanLinkKey, link.SpanContext.SpanID().String())
+	AddLink(s.Span, link)
+}
+
 func (s *loggingSpan) RecordError(err error, options ...trace.EventOption) {
-	log := logr.WithCallDepth(s.log, 1)
+	log := s.log.WithCallDepth(1)
 	log.Error(err, "span error")
 	s.Span.RecordError(err, options...)
 }
 
 func (s *loggingSpan) SetStatus(code codes.Code, description string) {
-	log := logr.WithCallDepth(s.log, 1)
+	log := s.log.WithCallDepth(1)
 	// The description is only included when there's an error, as per the
 	// spec of Span.SetStatus.
 	args := []interface{}{spanStatusCodeKey, code.String()}
@@ -74,13 +136,13 @@ func (s *loggingSpan) SetStatus(code codes.Code, description string) {
 }
 
 func (s *loggingSpan) SetName(name string) {
-	log := logr.WithCallDepth(s.log, 1)
+	log := s.log.WithCallDepth(1)
 	log.Info("span name change", spanNameKey, name)
 	s.Span.SetName(name)
 }
 
 func (s *loggingSpan) SetAttributes(kv ...attribute.KeyValue) {
-	log := logr.WithCallDepth(s.log, 1)
+	log := s.log.WithCallDepth(1)
 	log.Info("span attribute change", kvListToLogAttrs(kv)...)
 	s.Span.SetAttributes(kv...)
 }
@@ -0,0 +1,256 @@
+// Package logruslog provides a builder-pattern constructor for creating a
+// logr.Logger implementation backed by github.com/sirupsen/logrus, for
+// services that already use logrus and want to adopt the tracing package
+// without switching log libraries.
+package logruslog
+
+import (
+	"io"
+	"os"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	"github.com/luxas/deklarative/tracing/filetest"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// LogrLevelKey is the key of the field that holds the logr level (verbosity)
+	// of Info log entries.
+	LogrLevelKey = "v"
+	// NameKey is the key of the field that holds the logger name, as
+	// set using logr.Logger.WithName.
+	NameKey = "logger"
+)
+
+// JSONFormatter returns a logrus.JSONFormatter with default settings.
+func JSONFormatter() logrus.Formatter { return &logrus.JSONFormatter{} }
+
+// TextFormatter returns a logrus.TextFormatter with default settings.
+func TextFormatter() logrus.Formatter { return &logrus.TextFormatter{} }
+
+// NewLogrus returns a new *Builder using the default configuration.
+func NewLogrus() *Builder {
+	return &Builder{
+		outW:      os.Stderr,
+		formatter: TextFormatter(),
+	}
+}
+
+// Builder is a builder-pattern struct for building a logr.Logger
+// using github.com/sirupsen/logrus.
+//
+// The default configuration writes text to os.Stderr (like logrus does by
+// default), and includes the logr level of Info log entries in the
+// LogrLevelKey field.
+type Builder struct {
+	outW         io.Writer
+	formatter    logrus.Formatter
+	level        int
+	noTimestamps bool
+}
+
+// LogTo specifies where to write logs.
+//
+// Defaults to os.Stderr.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) LogTo(w io.Writer) *Builder {
+	b.outW = w
+	return b
+}
+
+// WithFormatter uses a specific logrus.Formatter to format log entries.
+//
+// Defaults to TextFormatter().
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) WithFormatter(formatter logrus.Formatter) *Builder {
+	b.formatter = formatter
+	return b
+}
+
+// LogUpto specifies the logr level that shall be used. All log messages from
+// a logr.Logger with a log level _less than or equal to_ logrLevel will be output.
+//
+// Logr levels are mapped to logrus levels as follows:
+//
+//	Logr	Logrus
+//	0	Info
+//	1	Debug
+//	2+	Trace
+//
+// According to logr.Logger, "it's illegal to pass a log
+// level less than zero.", hence, negative logrLevel values are disallowed.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) LogUpto(logrLevel int8) *Builder {
+	if logrLevel >= 0 {
+		b.level = int(logrLevel)
+	}
+	return b
+}
+
+// NoTimestamps omits timestamps in the logs. It's useful for deterministic
+// output in examples and tests. It only applies to the formatters of the
+// logrus package, and sets their DisableTimestamp field at Build time.
+//
+// By default timestamps are included in the log output.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) NoTimestamps() *Builder {
+	b.noTimestamps = true
+	return b
+}
+
+// Example is a shorthand for
+//
+//	WithFormatter(&logrus.TextFormatter{DisableColors: true}).
+//	NoTimestamps()
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) Example() *Builder {
+	return b.WithFormatter(&logrus.TextFormatter{DisableColors: true}).
+		NoTimestamps()
+}
+
+// Test is a shorthand for verifying log output in a test with the help of the
+// filetest package. Given a filetest.Tester, this will make the logger log to
+// a file under testdata/ with the name of the test + the ".log" suffix.
+func (b *Builder) Test(g *filetest.Tester) *Builder {
+	return b.LogTo(g.Add(g.T.Name() + ".log").Writer())
+}
+
+// Build builds the logger with the configured options.
+//
+// By default the logger name is an empty string, and the log level is 0.
+func (b *Builder) Build() logr.Logger {
+	formatter := b.formatter
+	if b.noTimestamps {
+		// Modify a copy, such that formatters shared between loggers aren't
+		// affected.
+		switch f := formatter.(type) {
+		case *logrus.TextFormatter:
+			c := &logrus.TextFormatter{}
+			copyExportedFields(c, f)
+			c.DisableTimestamp = true
+			formatter = c
+		case *logrus.JSONFormatter:
+			c := &logrus.JSONFormatter{}
+			copyExportedFields(c, f)
+			c.DisableTimestamp = true
+			formatter = c
+		}
+	}
+
+	l := logrus.New()
+	l.SetOutput(b.outW)
+	l.SetFormatter(formatter)
+	l.SetLevel(toLogrusLevel(b.level))
+	return logr.New(&logrusSink{entry: logrus.NewEntry(l), maxLevel: b.level})
+}
+
+// toLogrusLevel converts a logr level to a logrus level.
+func toLogrusLevel(level int) logrus.Level {
+	switch level {
+	case 0:
+		return logrus.InfoLevel
+	case 1:
+		return logrus.DebugLevel
+	default:
+		return logrus.TraceLevel
+	}
+}
+
+// logrusSink is a logr.LogSink that is backed by a logrus.Entry.
+type logrusSink struct {
+	entry    *logrus.Entry
+	name     string
+	maxLevel int
+}
+
+// Init is a no-op, as logrus doesn't support skipping stack frames when
+// reporting the caller.
+func (s *logrusSink) Init(logr.RuntimeInfo) {}
+
+func (s *logrusSink) Enabled(level int) bool { return level <= s.maxLevel }
+
+func (s *logrusSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.withFields(keysAndValues).
+		WithField(LogrLevelKey, level).
+		Log(toLogrusLevel(level), msg)
+}
+
+func (s *logrusSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.withFields(keysAndValues).WithError(err).Error(msg)
+}
+
+// Warn logs a warning at logrus.WarnLevel. It makes the LogSink implement
+// the Warner interface of the tracing package.
+func (s *logrusSink) Warn(err error, msg string, keysAndValues ...interface{}) {
+	e := s.withFields(keysAndValues)
+	if err != nil {
+		e = e.WithError(err)
+	}
+	e.Warn(msg)
+}
+
+func (s *logrusSink) withFields(keysAndValues []interface{}) *logrus.Entry {
+	e := s.entry
+	if len(s.name) != 0 {
+		e = e.WithField(NameKey, s.name)
+	}
+	if len(keysAndValues) != 0 {
+		e = e.WithFields(keysAndValuesToFields(keysAndValues))
+	}
+	return e
+}
+
+func (s *logrusSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	newSink := *s
+	newSink.entry = s.entry.WithFields(keysAndValuesToFields(keysAndValues))
+	return &newSink
+}
+
+func (s *logrusSink) WithName(name string) logr.LogSink {
+	newSink := *s
+	if len(s.name) != 0 {
+		name = s.name + "." + name
+	}
+	newSink.name = name
+	return &newSink
+}
+
+// GetUnderlying returns the underlying logrus.Entry.
+func (s *logrusSink) GetUnderlying() *logrus.Entry { return s.entry }
+
+// copyExportedFields copies the exported fields of the struct src points to,
+// to the struct dst points to. The unexported fields, e.g. the sync.Once of
+// the logrus.TextFormatter, can't be copied.
+func copyExportedFields(dst, src interface{}) {
+	d, s := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem()
+	for i := 0; i < d.NumField(); i++ {
+		if f := d.Field(i); f.CanSet() {
+			f.Set(s.Field(i))
+		}
+	}
+}
+
+// keysAndValuesToFields converts keysAndValues to logrus fields. If the amount of
+// arguments is odd, the last one is ignored. All arguments after a non-string key
+// are ignored.
+func keysAndValuesToFields(keysAndValues []interface{}) logrus.Fields {
+	fields := make(logrus.Fields, len(keysAndValues)/2)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			break
+		}
+		val := keysAndValues[i+1]
+		if marshaler, ok := val.(logr.Marshaler); ok {
+			val = marshaler.MarshalLog()
+		}
+		fields[key] = val
+	}
+	return fields
+}
@@ -0,0 +1,74 @@
+package logruslog
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/luxas/deklarative/tracing"
+	"github.com/luxas/deklarative/tracing/filetest"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleBuilder() {
+	// Build an example logger called bar that logs levels <= 1.
+	log := NewLogrus().Example().LogTo(os.Stdout).LogUpto(1).Build().WithName("bar")
+
+	// Sample info usage
+	log.Info("some message", "foo", true)
+	log.WithValues("bar", 1).V(1).Info("hello")
+
+	// Sample error usage
+	err := errors.New("unexpected error") //nolint:goerr113
+	log.Error(err, "I don't know what happened here", "duration", time.Minute)
+
+	// Verify that v=2 is disabled (i.e. discarded), but v=1 is enabled
+	log.V(1).Info("am I enabled?", "enabled", log.V(1).Enabled())
+	log.V(2).Info("am I enabled?", "enabled", log.V(2).Enabled())
+
+	// Output:
+	// level=info msg="some message" foo=true logger=bar v=0
+	// level=debug msg=hello bar=1 logger=bar v=1
+	// level=error msg="I don't know what happened here" duration=1m0s error="unexpected error" logger=bar
+	// level=debug msg="am I enabled?" enabled=true logger=bar v=1
+}
+
+func TestTestdata(t *testing.T) {
+	g := filetest.New(t)
+	defer g.Assert()
+
+	// Build an example logger called bar that logs levels <= 1.
+	log := NewLogrus().
+		Example().
+		Test(g).
+		WithFormatter(JSONFormatter()).
+		NoTimestamps().
+		LogUpto(1).
+		Build().
+		WithName("bar")
+
+	log.Info("some message", "foo", true)
+	log.WithName("baz").WithValues("bar", 1).V(1).Info("hello")
+	log.Info("odd number of arguments are ignored", "hello")
+	log.Info("non-string key invocations ignored", "hello", true, 123, false)
+
+	err := errors.New("unexpected error") //nolint:goerr113
+	log.Error(err, "I don't know what happened here", "duration", time.Minute)
+	log.V(2).Info("too verbose, ignored")
+
+	tracing.Warn(log, err, "a warning", "hello", 1)
+}
+
+func TestNoTimestampsSharedFormatter(t *testing.T) {
+	formatter := &logrus.JSONFormatter{DisableHTMLEscape: true}
+	var withTS, withoutTS bytes.Buffer
+	NewLogrus().WithFormatter(formatter).NoTimestamps().LogTo(&withoutTS).Build().Info("<foo>")
+	NewLogrus().WithFormatter(formatter).LogTo(&withTS).Build().Info("<foo>")
+
+	assert.False(t, formatter.DisableTimestamp)
+	assert.Equal(t, `{"level":"info","msg":"<foo>","v":0}`+"\n", withoutTS.String())
+	assert.Contains(t, withTS.String(), `"time":`)
+}
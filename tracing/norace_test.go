@@ -0,0 +1,8 @@
+//go:build !race
+// +build !race
+
+package tracing
+
+// raceEnabled is true if the race detector is enabled. The race detector
+// adds allocations, and makes sync.Pool drop items at random.
+const raceEnabled = false
@@ -0,0 +1,183 @@
+package tracing
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"runtime/debug"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.uber.org/multierr"
+)
+
+// Environment variables conventionally populated by the Kubernetes Downward API,
+// read by KubernetesDownwardAPIDetector.
+const (
+	PodNameEnvVar      = "POD_NAME"
+	PodNamespaceEnvVar = "POD_NAMESPACE"
+	NodeNameEnvVar     = "NODE_NAME"
+)
+
+type detectorFunc func(ctx context.Context) (*resource.Resource, error)
+
+func (f detectorFunc) Detect(ctx context.Context) (*resource.Resource, error) {
+	return f(ctx)
+}
+
+// ProcessDetector is a resource.Detector that registers information about the
+// running process, e.g. PID, executable name and path, command arguments,
+// owner and Go runtime information. If some of the information can't be
+// detected, e.g. the owner on some platforms, the rest is returned together
+// with an error wrapping resource.ErrPartialResource.
+func ProcessDetector() resource.Detector {
+	return detectorFunc(func(ctx context.Context) (*resource.Resource, error) {
+		return partialResource(resource.New(ctx, resource.WithProcess(), resource.WithSchemaURL(semconv.SchemaURL)))
+	})
+}
+
+// HostDetector is a resource.Detector that registers the host name.
+func HostDetector() resource.Detector {
+	return detectorFunc(func(ctx context.Context) (*resource.Resource, error) {
+		return partialResource(resource.New(ctx, resource.WithHost(), resource.WithSchemaURL(semconv.SchemaURL)))
+	})
+}
+
+// partialResource wraps err in resource.ErrPartialResource if res contains
+// what could be detected. This is needed as resource.New doesn't wrap the
+// errors of the detectors it runs.
+func partialResource(res *resource.Resource, err error) (*resource.Resource, error) {
+	if err != nil && res != nil && res.Len() != 0 && !errors.Is(err, resource.ErrPartialResource) {
+		err = fmt.Errorf("%w: %v", resource.ErrPartialResource, err)
+	}
+	return res, err
+}
+
+// detectResources runs the detectors, and returns the attributes of the
+// detected resources. Errors wrapping resource.ErrPartialResource are not
+// fatal; the partial resource is kept, and the error is reported as a warning
+// to the global OpenTelemetry error handler, see otel.Handle.
+func detectResources(ctx context.Context, detectors []resource.Detector) ([]attribute.KeyValue, error) {
+	var attrs []attribute.KeyValue
+	var errs []error
+	for _, detector := range detectors {
+		if detector == nil {
+			continue
+		}
+		res, err := detector.Detect(ctx)
+		if errors.Is(err, resource.ErrPartialResource) {
+			otel.Handle(err)
+		} else if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if res != nil {
+			attrs = append(attrs, res.Attributes()...)
+		}
+	}
+	return attrs, multierr.Combine(errs...)
+}
+
+// BuildInfoDetector is a resource.Detector that registers the version of the
+// main module of the binary as "service.version", if the binary was built
+// with module support and the version is known.
+func BuildInfoDetector() resource.Detector {
+	return detectorFunc(func(context.Context) (*resource.Resource, error) {
+		bi, ok := debug.ReadBuildInfo()
+		if !ok || len(bi.Main.Version) == 0 || bi.Main.Version == "(devel)" {
+			return resource.Empty(), nil
+		}
+		return resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceVersionKey.String(bi.Main.Version),
+		), nil
+	})
+}
+
+// containerIDRegexp matches the 64 hexadecimal characters long container ID
+// in /proc/self/cgroup, as used by e.g. Docker, containerd and CRI-O.
+var containerIDRegexp = regexp.MustCompile(`[0-9a-f]{64}`) //nolint:gochecknoglobals
+
+// ContainerDetector is a resource.Detector that registers the ID of the
+// container the process is running in, if any, as "container.id". The ID
+// is read from /proc/self/cgroup; on other platforms than Linux, or when
+// not running in a container, no attributes are registered.
+func ContainerDetector() resource.Detector {
+	return detectorFunc(func(context.Context) (*resource.Resource, error) {
+		content, err := os.ReadFile("/proc/self/cgroup")
+		if errors.Is(err, fs.ErrNotExist) {
+			return resource.Empty(), nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		id := containerIDFromCgroup(content)
+		if len(id) == 0 {
+			return resource.Empty(), nil
+		}
+		return resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ContainerIDKey.String(id),
+		), nil
+	})
+}
+
+func containerIDFromCgroup(content []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(content))
+	for s.Scan() {
+		if id := containerIDRegexp.Find(s.Bytes()); id != nil {
+			return string(id)
+		}
+	}
+	return ""
+}
+
+// KubernetesDownwardAPIDetector is a resource.Detector that registers the
+// Kubernetes Pod name, namespace and Node name, given that the PodNameEnvVar,
+// PodNamespaceEnvVar and NodeNameEnvVar environment variables are populated
+// using the Kubernetes Downward API, as follows:
+//
+//	env:
+//	- name: POD_NAME
+//	  valueFrom:
+//	    fieldRef:
+//	      fieldPath: metadata.name
+//	- name: POD_NAMESPACE
+//	  valueFrom:
+//	    fieldRef:
+//	      fieldPath: metadata.namespace
+//	- name: NODE_NAME
+//	  valueFrom:
+//	    fieldRef:
+//	      fieldPath: spec.nodeName
+//
+// Unset environment variables are ignored.
+func KubernetesDownwardAPIDetector() resource.Detector {
+	return detectorFunc(func(context.Context) (*resource.Resource, error) {
+		return kubernetesResource(os.Getenv), nil
+	})
+}
+
+func kubernetesResource(getenv func(string) string) *resource.Resource {
+	envKeys := []struct {
+		env string
+		key attribute.Key
+	}{
+		{PodNameEnvVar, semconv.K8SPodNameKey},
+		{PodNamespaceEnvVar, semconv.K8SNamespaceNameKey},
+		{NodeNameEnvVar, semconv.K8SNodeNameKey},
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(envKeys))
+	for _, ek := range envKeys {
+		if val := getenv(ek.env); len(val) != 0 {
+			attrs = append(attrs, ek.key.String(val))
+		}
+	}
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+}
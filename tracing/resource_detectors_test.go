@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func Test_containerIDFromCgroup(t *testing.T) {
+	const id = "8d3b5c1f4b2a9e7d6c5b4a3f2e1d0c9b8a7f6e5d4c3b2a1f0e9d8c7b6a5f4e3d"
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"docker", "12:pids:/docker/" + id + "\n0::/docker/" + id + "\n", id},
+		{"containerd", "0::/kubepods.slice/cri-containerd-" + id + ".scope\n", id},
+		{"no container", "0::/user.slice/user-1000.slice/session-2.scope\n", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, containerIDFromCgroup([]byte(tt.content)))
+		})
+	}
+}
+
+func Test_kubernetesResource(t *testing.T) {
+	env := map[string]string{
+		PodNameEnvVar:  "foo-7d4b9c",
+		NodeNameEnvVar: "node-1",
+	}
+	res := kubernetesResource(func(key string) string { return env[key] })
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("k8s.node.name", "node-1"),
+		attribute.String("k8s.pod.name", "foo-7d4b9c"),
+	}, res.Attributes())
+}
+
+func TestWithResourceDetectors(t *testing.T) {
+	_, err := Provider().
+		WithResourceDetectors(context.Background(),
+			ProcessDetector(),
+			HostDetector(),
+			BuildInfoDetector(),
+			ContainerDetector(),
+			KubernetesDownwardAPIDetector(),
+		).
+		Build()
+	require.Nil(t, err)
+}
+
+var errDetect = errors.New("detect failed")
+
+func TestWithResourceDetectors_partial(t *testing.T) {
+	var handled []error
+	otel.SetErrorHandler(errorHandlerFunc(func(err error) { handled = append(handled, err) }))
+
+	partial := detectorFunc(func(context.Context) (*resource.Resource, error) {
+		return partialResource(resource.NewWithAttributes("", attribute.String("foo", "bar")), errDetect)
+	})
+	b := Provider().WithResourceDetectors(context.Background(), partial)
+	assert.Equal(t, []attribute.KeyValue{attribute.String("foo", "bar")}, b.detected)
+	_, err := b.Build()
+	require.Nil(t, err)
+	require.Len(t, handled, 1)
+	assert.ErrorIs(t, handled[0], resource.ErrPartialResource)
+	assert.Contains(t, handled[0].Error(), errDetect.Error())
+
+	failing := detectorFunc(func(context.Context) (*resource.Resource, error) {
+		return nil, errDetect
+	})
+	_, err = Provider().WithResourceDetectors(context.Background(), partial, failing).Build()
+	assert.ErrorIs(t, err, errDetect)
+}
+
+type errorHandlerFunc func(error)
+
+func (f errorHandlerFunc) Handle(err error) { f(err) }
@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/go-logr/zapr"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/multierr"
+)
+
+// ShutdownOnSignal blocks until one of the given signals is received, or ctx is
+// done. After that, tp is force-flushed and shut down, such that all batched spans
+// are exported before the application exits. If no signals are given, SIGTERM and
+// SIGINT are waited for.
+//
+// If the Logger from LoggerFromContext(ctx) is backed by zap (for example built
+// using the zaplog package), its sinks are also synced. This flushes e.g. buffered
+// log output.
+//
+// The flush and shutdown operations are bounded by tracesdk.DefaultExportTimeout,
+// as ctx might already be done at that point. A common usage pattern is:
+//
+//	go func() { _ = tracing.ShutdownOnSignal(ctx, tp) }()
+func ShutdownOnSignal(ctx context.Context, tp TracerProvider, signals ...os.Signal) error {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGTERM, syscall.SIGINT}
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, signals...)
+	defer signal.Stop(sigCh)
+
+	select {
+	case <-sigCh:
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), tracesdk.DefaultExportTimeout)
+	defer cancel()
+
+	err := multierr.Combine(
+		tp.ForceFlush(shutdownCtx),
+		tp.Shutdown(shutdownCtx),
+	)
+
+	if underlier, ok := LoggerFromContext(ctx).GetSink().(zapr.Underlier); ok {
+		// Syncing e.g. os.Stdout fails on some platforms, hence the
+		// error is deliberately ignored.
+		_ = underlier.GetUnderlying().Sync()
+	}
+	return err
+}
@@ -0,0 +1,10 @@
+// Package sloglog provides a builder-pattern constructor for creating a
+// logr.Logger implementation using log/slog with some commonly-good defaults.
+// It is a sibling of the zaplog package, for services that standardize on
+// log/slog.
+//
+// This package requires Go 1.21 or newer, as log/slog was added in Go 1.21.
+// The tracing module itself still supports Go 1.16, hence all other files in
+// this package are gated by the go1.21 build tag; with older Go versions, the
+// package only contains this documentation, and exports nothing.
+package sloglog
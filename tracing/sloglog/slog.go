@@ -0,0 +1,313 @@
+//go:build go1.21
+// +build go1.21
+
+package sloglog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/luxas/deklarative/tracing/filetest"
+)
+
+type (
+	// Handler is a symbolic link to slog.Handler.
+	Handler = slog.Handler
+	// HandlerOptions is a symbolic link to slog.HandlerOptions.
+	HandlerOptions = slog.HandlerOptions
+
+	// HandlerCreator represents a Handler constructor given the writer to log
+	// to and populated HandlerOptions.
+	HandlerCreator func(io.Writer, *HandlerOptions) Handler
+	// LevelEncoder encodes a slog.Level into its string representation.
+	LevelEncoder func(slog.Level) string
+)
+
+// JSONHandlerCreator returns a HandlerCreator for slog.NewJSONHandler.
+func JSONHandlerCreator() HandlerCreator {
+	return func(w io.Writer, opts *HandlerOptions) Handler { return slog.NewJSONHandler(w, opts) }
+}
+
+// TextHandlerCreator returns a HandlerCreator for slog.NewTextHandler.
+func TextHandlerCreator() HandlerCreator {
+	return func(w io.Writer, opts *HandlerOptions) Handler { return slog.NewTextHandler(w, opts) }
+}
+
+const (
+	// LogrLevelKey is the key of the attribute that holds the logr level (verbosity)
+	// of Info log entries.
+	LogrLevelKey = "v"
+	// NameKey is the key of the attribute that holds the logger name, as
+	// set using logr.Logger.WithName.
+	NameKey = "logger"
+	// ErrorKey is the key of the attribute that holds the error of Error log entries.
+	ErrorKey = "error"
+)
+
+// LowercaseLevelEncoder is the default LevelEncoder; it encodes the levels as
+// "debug", "info", "warn" and "error". All levels more verbose than slog.LevelInfo,
+// i.e. logr levels 1 and higher, are encoded as "debug". The logr level is
+// registered with the LogrLevelKey attribute instead.
+func LowercaseLevelEncoder() LevelEncoder {
+	return func(l slog.Level) string { return strings.ToLower(capitalLevel(l)) }
+}
+
+// CapitalLevelEncoder works like LowercaseLevelEncoder, but encodes the levels
+// in upper case, e.g. "DEBUG" and "INFO".
+func CapitalLevelEncoder() LevelEncoder {
+	return capitalLevel
+}
+
+func capitalLevel(l slog.Level) string {
+	switch {
+	case l < slog.LevelInfo:
+		return "DEBUG"
+	case l < slog.LevelWarn:
+		return "INFO"
+	case l < slog.LevelError:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// NewSlog returns a new *Builder using the default configuration.
+func NewSlog() *Builder {
+	return &Builder{
+		outW:           os.Stdout,
+		handlerCreator: JSONHandlerCreator(),
+		levelEnc:       LowercaseLevelEncoder(),
+	}
+}
+
+// Builder is a builder-pattern struct for building a logr.Logger
+// using log/slog.
+//
+// The default configuration writes JSON, includes the logr level of Info log
+// entries in the LogrLevelKey attribute, and logs to os.Stdout.
+type Builder struct {
+	outW           io.Writer
+	handlerCreator HandlerCreator
+	levelEnc       LevelEncoder
+	level          slog.Level
+	noTimestamps   bool
+}
+
+// LogTo specifies where to write logs. If you want to write to multiple
+// destinations, use io.MultiWriter.
+//
+// Defaults to os.Stdout.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) LogTo(w io.Writer) *Builder {
+	b.outW = w
+	return b
+}
+
+// WithHandlerCreator uses a specific HandlerCreator to create the handler.
+//
+// Defaults to JSONHandlerCreator().
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) WithHandlerCreator(handlerCreator HandlerCreator) *Builder {
+	b.handlerCreator = handlerCreator
+	return b
+}
+
+// LogUpto specifies the logr level that shall be used. All log messages from
+// a logr.Logger with a log level _less than or equal to_ logrLevel will be output.
+//
+// A logr level of N corresponds to the slog level -N, i.e. logr level 0 is
+// slog.LevelInfo, and logr level 4 is slog.LevelDebug.
+//
+// According to logr.Logger, "it's illegal to pass a log
+// level less than zero.", hence, negative logrLevel values are disallowed.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) LogUpto(logrLevel int8) *Builder {
+	if logrLevel >= 0 {
+		b.level = toSlogLevel(int(logrLevel))
+	}
+	return b
+}
+
+// WithLevelEncoder customizes how the log level is encoded.
+//
+// The default is LowercaseLevelEncoder.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) WithLevelEncoder(levelEnc LevelEncoder) *Builder {
+	b.levelEnc = levelEnc
+	return b
+}
+
+// NoTimestamps omits timestamps in the logs. It's useful for deterministic
+// output in examples and tests.
+//
+// By default timestamps are included in the log output.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) NoTimestamps() *Builder {
+	b.noTimestamps = true
+	return b
+}
+
+// Text is a shorthand for:
+//
+//	WithHandlerCreator(TextHandlerCreator()).
+//	WithLevelEncoder(CapitalLevelEncoder())
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) Text() *Builder {
+	return b.WithHandlerCreator(TextHandlerCreator()).
+		WithLevelEncoder(CapitalLevelEncoder())
+}
+
+// Example is a shorthand for NoTimestamps().
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) Example() *Builder {
+	return b.NoTimestamps()
+}
+
+// Test is a shorthand for verifying log output in a test with the help of the
+// filetest package. Given a filetest.Tester, this will make the logger log to
+// a file under testdata/ with the name of the test + the ".log" suffix.
+func (b *Builder) Test(g *filetest.Tester) *Builder {
+	return b.LogTo(g.Add(g.T.Name() + ".log").Writer())
+}
+
+// Build builds the logger with the configured options.
+//
+// By default the logger name is an empty string, and the log level is 0.
+func (b *Builder) Build() logr.Logger {
+	levelEnc := b.levelEnc
+	noTimestamps := b.noTimestamps
+	opts := &HandlerOptions{
+		Level: b.level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			// Only modify the built-in top-level attributes
+			if len(groups) != 0 {
+				return a
+			}
+			switch a.Key {
+			case slog.TimeKey:
+				if noTimestamps {
+					return slog.Attr{}
+				}
+			case slog.LevelKey:
+				if l, ok := a.Value.Any().(slog.Level); ok && levelEnc != nil {
+					a.Value = slog.StringValue(levelEnc(l))
+				}
+			}
+			return a
+		},
+	}
+	return logr.New(&slogSink{handler: b.handlerCreator(b.outW, opts)})
+}
+
+// toSlogLevel converts a logr level to a slog level.
+func toSlogLevel(level int) slog.Level { return slog.Level(-level) }
+
+// slogSink is a logr.LogSink that is backed by a slog.Handler.
+type slogSink struct {
+	handler   slog.Handler
+	name      string
+	callDepth int
+}
+
+// Assert that slogSink supports call depths.
+var _ logr.CallDepthLogSink = &slogSink{}
+
+func (s *slogSink) Init(info logr.RuntimeInfo) { s.callDepth += info.CallDepth }
+
+func (s *slogSink) Enabled(level int) bool {
+	return s.handler.Enabled(context.Background(), toSlogLevel(level))
+}
+
+func (s *slogSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.log(toSlogLevel(level), msg, keysAndValues, nil)
+}
+
+func (s *slogSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.log(slog.LevelError, msg, keysAndValues, err)
+}
+
+// Warn logs a warning at slog.LevelWarn. It makes the LogSink implement
+// the Warner interface of the tracing package.
+func (s *slogSink) Warn(err error, msg string, keysAndValues ...interface{}) {
+	s.log(slog.LevelWarn, msg, keysAndValues, err)
+}
+
+func (s *slogSink) log(level slog.Level, msg string, keysAndValues []interface{}, err error) {
+	ctx := context.Background()
+	if !s.handler.Enabled(ctx, level) {
+		return
+	}
+
+	// Skip runtime.Callers, this function, and the calling LogSink method
+	var pcs [1]uintptr
+	runtime.Callers(s.callDepth+3, pcs[:])
+
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	if len(s.name) != 0 {
+		r.AddAttrs(slog.String(NameKey, s.name))
+	}
+	if level <= slog.LevelInfo {
+		r.AddAttrs(slog.Int(LogrLevelKey, -int(level)))
+	}
+	r.AddAttrs(keysAndValuesToAttrs(keysAndValues)...)
+	if err != nil {
+		r.AddAttrs(slog.Any(ErrorKey, err))
+	}
+	_ = s.handler.Handle(ctx, r)
+}
+
+func (s *slogSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	newSink := *s
+	newSink.handler = s.handler.WithAttrs(keysAndValuesToAttrs(keysAndValues))
+	return &newSink
+}
+
+func (s *slogSink) WithName(name string) logr.LogSink {
+	newSink := *s
+	if len(s.name) != 0 {
+		name = s.name + "." + name
+	}
+	newSink.name = name
+	return &newSink
+}
+
+func (s *slogSink) WithCallDepth(depth int) logr.LogSink {
+	newSink := *s
+	newSink.callDepth += depth
+	return &newSink
+}
+
+// GetUnderlying returns the underlying slog.Handler.
+func (s *slogSink) GetUnderlying() slog.Handler { return s.handler }
+
+// keysAndValuesToAttrs converts keysAndValues to slog attributes. If the amount of
+// arguments is odd, the last one is ignored. All arguments after a non-string key
+// are ignored.
+func keysAndValuesToAttrs(keysAndValues []interface{}) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(keysAndValues)/2)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			break
+		}
+		val := keysAndValues[i+1]
+		if marshaler, ok := val.(logr.Marshaler); ok {
+			val = marshaler.MarshalLog()
+		}
+		attrs = append(attrs, slog.Any(key, val))
+	}
+	return attrs
+}
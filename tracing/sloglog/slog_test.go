@@ -0,0 +1,160 @@
+//go:build go1.21
+// +build go1.21
+
+package sloglog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luxas/deklarative/tracing"
+	"github.com/luxas/deklarative/tracing/filetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ExampleBuilder_json() {
+	// Build an example logger called bar that logs levels <= 1.
+	log := NewSlog().Example().LogUpto(1).Build().WithName("bar")
+
+	// Sample info usage
+	log.Info("some message", "foo", true)
+	log.WithValues("bar", 1).V(1).Info("hello")
+
+	// Sample error usage
+	err := errors.New("unexpected error") //nolint:goerr113
+	log.Error(err, "I don't know what happened here", "duration", time.Minute)
+
+	// Verify that v=2 is disabled (i.e. discarded), but v=1 is enabled
+	log.V(1).Info("am I enabled?", "enabled", log.V(1).Enabled())
+	log.V(2).Info("am I enabled?", "enabled", log.V(2).Enabled())
+
+	// Output:
+	// {"level":"info","msg":"some message","logger":"bar","v":0,"foo":true}
+	// {"level":"debug","msg":"hello","bar":1,"logger":"bar","v":1}
+	// {"level":"error","msg":"I don't know what happened here","logger":"bar","duration":60000000000,"error":"unexpected error"}
+	// {"level":"debug","msg":"am I enabled?","logger":"bar","v":1,"enabled":true}
+}
+
+func ExampleBuilder_text() {
+	// Build an example logger called bar that logs levels <= 1.
+	log := NewSlog().Example().Text().LogUpto(1).Build().WithName("bar")
+
+	// Sample info usage
+	log.Info("some message", "foo", true)
+	log.WithValues("bar", 1).V(1).Info("hello")
+
+	// Sample error usage
+	err := errors.New("unexpected error") //nolint:goerr113
+	log.Error(err, "I don't know what happened here", "duration", time.Minute)
+
+	// Verify that v=2 is disabled (i.e. discarded), but v=1 is enabled
+	log.V(1).Info("am I enabled?", "enabled", log.V(1).Enabled())
+	log.V(2).Info("am I enabled?", "enabled", log.V(2).Enabled())
+
+	// Output:
+	// level=INFO msg="some message" logger=bar v=0 foo=true
+	// level=DEBUG msg=hello bar=1 logger=bar v=1
+	// level=ERROR msg="I don't know what happened here" logger=bar duration=1m0s error="unexpected error"
+	// level=DEBUG msg="am I enabled?" logger=bar v=1 enabled=true
+}
+
+func TestTestdata(t *testing.T) {
+	g := filetest.New(t)
+	defer g.Assert()
+
+	// Build an example logger called bar that logs levels <= 1.
+	log := NewSlog().
+		NoTimestamps().
+		Test(g).
+		Text().
+		LogUpto(1).
+		Build().
+		WithName("bar")
+
+	log.Info("some message", "foo", true)
+	log.WithName("baz").WithValues("bar", 1).V(1).Info("hello")
+	log.Info("odd number of arguments are ignored", "hello")
+	log.Info("non-string key invocations ignored", "hello", true, 123, false)
+
+	err := errors.New("unexpected error") //nolint:goerr113
+	log.Error(err, "I don't know what happened here", "duration", time.Minute)
+	log.V(2).Info("too verbose, ignored")
+}
+
+func TestCallDepth(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewSlog().
+		Example().
+		LogTo(&buf).
+		WithHandlerCreator(func(w io.Writer, opts *HandlerOptions) Handler {
+			opts.AddSource = true
+			return slog.NewJSONHandler(w, opts)
+		}).
+		Build()
+
+	log.Info("info")
+	log.WithValues("foo", "bar").WithName("foo").Error(nil, "error")
+	helper := func() { log.WithCallDepth(1).Info("from helper") }
+	helper()
+	tracing.Warn(log, nil, "warning")
+
+	ctx := tracing.Context().WithLogger(log).Build()
+	_, span, spanLog := tracing.Tracer().Trace(ctx, "span")
+	spanLog.Info("span info")
+	tracing.Warn(spanLog, nil, "span warning")
+	span.End()
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	require.Len(t, lines, 8)
+	for _, line := range lines {
+		entry := struct {
+			Level  string `json:"level"`
+			Source struct {
+				File string `json:"file"`
+			} `json:"source"`
+		}{}
+		require.Nil(t, json.Unmarshal(line, &entry))
+		assert.True(t, strings.HasSuffix(entry.Source.File, "sloglog/slog_test.go"), string(line))
+		if bytes.Contains(line, []byte("warning")) {
+			assert.Equal(t, "warn", entry.Level)
+		}
+	}
+}
+
+func TestLevelEncoders(t *testing.T) {
+	tests := []struct {
+		enc   LevelEncoder
+		level slog.Level
+		want  string
+	}{
+		// Capital case
+		{CapitalLevelEncoder(), slog.LevelError, "ERROR"},
+		{CapitalLevelEncoder(), slog.LevelError + 4, "ERROR"},
+		{CapitalLevelEncoder(), slog.LevelWarn, "WARN"},
+		{CapitalLevelEncoder(), slog.LevelInfo, "INFO"},
+		{CapitalLevelEncoder(), -1, "DEBUG"},
+		{CapitalLevelEncoder(), slog.LevelDebug, "DEBUG"},
+		{CapitalLevelEncoder(), -44, "DEBUG"},
+		// Lowercase
+		{LowercaseLevelEncoder(), slog.LevelError, "error"},
+		{LowercaseLevelEncoder(), slog.LevelError + 4, "error"},
+		{LowercaseLevelEncoder(), slog.LevelWarn, "warn"},
+		{LowercaseLevelEncoder(), slog.LevelInfo, "info"},
+		{LowercaseLevelEncoder(), -1, "debug"},
+		{LowercaseLevelEncoder(), slog.LevelDebug, "debug"},
+		{LowercaseLevelEncoder(), -44, "debug"},
+	}
+	for i, tt := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.enc(tt.level))
+		})
+	}
+}
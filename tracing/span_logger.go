@@ -2,88 +2,151 @@ package tracing
 
 import (
 	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"github.com/luxas/deklarative/tracing/zaplog"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// spanLogger is a composite logr.Logger implementation that registers
+// spanLogger is a composite logr.LogSink implementation that registers
 // keysAndValues arguments of Logger.Info and Logger.Error calls with
 // the span.
 type spanLogger struct {
 	// embedding is important; this automatically exposes all inherited functionality from the
 	// underlying resource.
-	Logger
+	logr.LogSink
 
 	span          Span
 	keysAndValues []interface{}
+	// logEvents specifies whether Info calls also shall be registered
+	// as span events.
+	logEvents bool
 }
 
-func (l *spanLogger) Enabled() bool { return l.Logger.Enabled() }
-func (l *spanLogger) Info(msg string, keysAndValues ...interface{}) {
-	if !l.Enabled() {
-		return
+// logRecorder is implemented by spans that capture log lines, e.g. the
+// traceyaml test span.
+type logRecorder interface {
+	RecordLog(level int, msg string, err error, fields ...attribute.KeyValue)
+}
+
+// Assert that spanLogger supports call depths, as needed by the loggingSpan.
+var _ logr.CallDepthLogSink = &spanLogger{}
+
+// withSpanLogger returns log with its LogSink replaced by l. The LogSink of log
+// is told to skip one more stack frame, as spanLogger adds one frame between
+// the logr.Logger and the underlying LogSink.
+func withSpanLogger(log Logger, l *spanLogger) Logger {
+	l.LogSink = withCallDepth(log.GetSink(), 1)
+	return log.WithSink(l)
+}
+
+// withSpanRef attaches a zaplog.SpanRef for the span with the given name and
+// span context to log, if log is created by the zaplog package. This correlates
+// e.g. the log records exported using zaplog.Builder.ExportOTLP with the span.
+// Other loggers are returned as-is, as they'd write the SpanRef as a field.
+func withSpanRef(log Logger, name string, sc trace.SpanContext) Logger {
+	if !sc.IsValid() || !isZapSink(log.GetSink()) {
+		return log
+	}
+	return log.WithValues(zaplog.SpanRefKey, zaplog.SpanRef{Name: name, SpanContext: sc})
+}
+
+// isZapSink returns true if sink, or the sink wrapped by a spanLogger, is
+// created by the zaplog package.
+func isZapSink(sink logr.LogSink) bool {
+	for {
+		switch s := sink.(type) {
+		case *spanLogger:
+			sink = s.LogSink
+		case zapr.Underlier:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// withCallDepth returns sink with the given call depth, if sink supports it.
+func withCallDepth(sink logr.LogSink, depth int) logr.LogSink {
+	if depthSink, ok := sink.(logr.CallDepthLogSink); ok {
+		return depthSink.WithCallDepth(depth)
 	}
+	return sink
+}
+
+// Init is a no-op, as the underlying LogSink has already been initialized.
+func (l *spanLogger) Init(logr.RuntimeInfo) {}
 
-	attrs := keysAndValuesToAttrs(append(l.keysAndValues, keysAndValues...))
+func (l *spanLogger) Info(level int, msg string, keysAndValues ...interface{}) {
+	attrs := keysAndValuesToAttrs(LogAttributePrefix, append(l.keysAndValues, keysAndValues...))
 	if len(attrs) != 0 {
 		l.span.SetAttributes(attrs...)
 	}
+	if l.logEvents {
+		l.span.AddEvent(msg, trace.WithAttributes(attrs...))
+	}
+	l.recordLog(level, msg, nil, keysAndValues)
 
-	l.Logger.Info(msg, keysAndValues...)
+	l.LogSink.Info(level, msg, keysAndValues...)
 }
 
 func (l *spanLogger) Error(err error, msg string, keysAndValues ...interface{}) {
-	if !l.Enabled() {
-		return
-	}
-
-	attrs := keysAndValuesToAttrs(append(l.keysAndValues, keysAndValues...))
+	attrs := keysAndValuesToAttrs(LogAttributePrefix, append(l.keysAndValues, keysAndValues...))
 	if len(attrs) != 0 {
 		l.span.SetAttributes(attrs...)
 	}
 	l.span.RecordError(err)
+	l.recordLog(0, msg, err, keysAndValues)
 
-	l.Logger.Error(err, msg, keysAndValues...)
+	l.LogSink.Error(err, msg, keysAndValues...)
 }
 
-func (l *spanLogger) V(level int) Logger {
-	return &spanLogger{
-		Logger:        l.Logger.V(level),
-		span:          l.span,
-		keysAndValues: l.keysAndValues,
+// recordLog registers the log line with the span, if the span captures log
+// lines.
+func (l *spanLogger) recordLog(level int, msg string, err error, keysAndValues []interface{}) {
+	if recorder, ok := l.span.(logRecorder); ok {
+		recorder.RecordLog(level, msg, err, keysAndValuesToAttrs("", append(l.keysAndValues, keysAndValues...))...)
 	}
 }
 
-func (l *spanLogger) WithValues(keysAndValues ...interface{}) Logger {
+func (l *spanLogger) WithValues(keysAndValues ...interface{}) logr.LogSink {
 	return &spanLogger{
-		Logger:        l.Logger.WithValues(keysAndValues...),
+		LogSink:       l.LogSink.WithValues(keysAndValues...),
 		span:          l.span,
 		keysAndValues: append(l.keysAndValues, keysAndValues...),
+		logEvents:     l.logEvents,
 	}
 }
 
-func (l *spanLogger) WithName(name string) Logger {
+func (l *spanLogger) WithName(name string) logr.LogSink {
 	return &spanLogger{
-		Logger:        l.Logger.WithName(name),
+		LogSink:       l.LogSink.WithName(name),
 		span:          l.span,
 		keysAndValues: l.keysAndValues,
+		logEvents:     l.logEvents,
 	}
 }
 
-func (l *spanLogger) WithCallDepth(depth int) Logger {
-	if depthLog, ok := l.Logger.(logr.CallDepthLogger); ok {
-		return depthLog.WithCallDepth(depth)
+func (l *spanLogger) WithCallDepth(depth int) logr.LogSink {
+	return &spanLogger{
+		LogSink:       withCallDepth(l.LogSink, depth),
+		span:          l.span,
+		keysAndValues: l.keysAndValues,
+		logEvents:     l.logEvents,
 	}
-	return l.Logger
 }
 
-func keysAndValuesToAttrs(keysAndValues []interface{}) []attribute.KeyValue {
+// keysAndValuesToAttrs converts keysAndValues to attributes, with the keys
+// prefixed by prefix. zaplog.SpanRef values are skipped, as they're attached
+// by withSpanRef for the logger only.
+func keysAndValuesToAttrs(prefix string, keysAndValues []interface{}) []attribute.KeyValue {
 	keyValLen := len(keysAndValues)
 	if keyValLen%2 != 0 {
 		// match zap behavior of "odd number of arguments passed as key-value pairs for logging"
 		return nil
 	}
 	attrLen := keyValLen / 2
-	attrs := make([]attribute.KeyValue, attrLen)
+	attrs := make([]attribute.KeyValue, 0, attrLen)
 	for i := 0; i < attrLen; i++ {
 		k := keysAndValues[i*2]
 		v := keysAndValues[i*2+1]
@@ -93,7 +156,10 @@ func keysAndValuesToAttrs(keysAndValues []interface{}) []attribute.KeyValue {
 			// match zap behavior of "non-string key argument passed to logging, ignoring all later arguments"
 			return nil
 		}
-		attrs[i] = attribute.Any(LogAttributePrefix+key, v)
+		if _, ok := v.(zaplog.SpanRef); ok {
+			continue
+		}
+		attrs = append(attrs, attribute.Any(prefix+key, v))
 	}
 	return attrs
 }
@@ -1,29 +1,40 @@
 package tracing
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"io"
+	"sync"
 	"testing"
 
 	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
 	"github.com/luxas/deklarative/tracing/filetest"
 	"github.com/luxas/deklarative/tracing/tracingfakes"
+	"github.com/luxas/deklarative/tracing/zaplog"
 	"github.com/sebdah/goldie/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
 )
 
 // TODO: Make sure keysAndValues aren't modified when passed to Info/Error.
 
 func Test_spanLogger_WithValues(t *testing.T) {
-	log := (&spanLogger{Logger: logr.Discard()}).
+	log := withSpanLogger(logr.Discard(), &spanLogger{}).
 		WithValues("foo", "bar")
-	assert.Equal(t, log.(*spanLogger).keysAndValues, []interface{}{"foo", "bar"})
+	assert.Equal(t, log.GetSink().(*spanLogger).keysAndValues, []interface{}{"foo", "bar"})
 
 	newlog := log.WithValues("private", true)
 	// newlog shouldn't modify the earlier assertion, verify it again
-	assert.Equal(t, log.(*spanLogger).keysAndValues, []interface{}{"foo", "bar"})
+	assert.Equal(t, log.GetSink().(*spanLogger).keysAndValues, []interface{}{"foo", "bar"})
 	// newlog should now have more keys and values
-	assert.Equal(t, newlog.(*spanLogger).keysAndValues, []interface{}{
+	assert.Equal(t, newlog.GetSink().(*spanLogger).keysAndValues, []interface{}{
 		"foo", "bar", "private", true,
 	})
 }
@@ -40,7 +51,7 @@ func Test_spanLogger_args(t *testing.T) {
 	zapLogger = zapLogger.WithName("foo")
 	s := &tracingfakes.FakeSpan{}
 
-	log := &spanLogger{Logger: zapLogger, span: s}
+	log := withSpanLogger(zapLogger, &spanLogger{span: s})
 	log.Info("good, no args")
 	log.Info("good", "hello-1", 123)
 	log.Info("odd number of arguments are ignored", "hello-2")
@@ -52,10 +63,10 @@ func Test_spanLogger_args(t *testing.T) {
 	log.Error(errSample, "good, no args")
 	log.Error(errSample, "good", "hello-5", false, "sample-float", 1.2)
 	log.Error(errSample, "odd number of arguments are ignored", "hello-6")
-	log.V(1).Error(errSample, "too verbose, ignored", "hello-7", 123)
+	log.V(1).Error(errSample, "errors are logged regardless of verbosity", "hello-7", 123)
 	log.Error(errSample, "non-string key invocations ignored", "hello-8", true, 123, false)
 
-	assert.Equal(t, 3, s.SetAttributesCallCount())
+	assert.Equal(t, 4, s.SetAttributesCallCount())
 	assert.Equal(t,
 		[]attribute.KeyValue{attribute.Int64("log-attr-hello-1", 123)},
 		s.SetAttributesArgsForCall(0))
@@ -70,4 +81,142 @@ func Test_spanLogger_args(t *testing.T) {
 			attribute.Float64("log-attr-sample-float", 1.2),
 		},
 		s.SetAttributesArgsForCall(2))
+	assert.Equal(t,
+		[]attribute.KeyValue{attribute.Int64("log-attr-hello-7", 123)},
+		s.SetAttributesArgsForCall(3))
+}
+
+func Test_spanLogger_logEvents(t *testing.T) {
+	s := &tracingfakes.FakeSpan{}
+	log := withSpanLogger(ZapLogger().LogTo(io.Discard).Build(), &spanLogger{span: s, logEvents: true}).
+		WithName("foo").
+		WithValues("foo", "bar")
+	log.Info("hello", "hello-1", 123)
+
+	assert.Equal(t, 1, s.AddEventCallCount())
+	name, opts := s.AddEventArgsForCall(0)
+	assert.Equal(t, "hello", name)
+	assert.Equal(t,
+		[]attribute.KeyValue{
+			attribute.String("log-attr-foo", "bar"),
+			attribute.Int64("log-attr-hello-1", 123),
+		},
+		trace.NewEventConfig(opts...).Attributes())
+}
+
+func Test_spanLogger_Warn(t *testing.T) {
+	g := filetest.New(t, goldie.WithNameSuffix(""))
+	defer g.Assert()
+
+	zapLogger := ZapLogger().Console().Example().Test(g).Build()
+	s := &tracingfakes.FakeSpan{}
+
+	log := withSpanLogger(zapLogger, &spanLogger{span: s}).WithName("foo").V(1)
+	Warn(log, errSample, "something might be wrong", "hello", 1)
+	Warn(log, nil, "no error")
+	Warn(zapLogger.WithName("bar"), errSample, "not a span Logger")
+	Warn(logr.Discard(), errSample, "discarded")
+
+	assert.Equal(t, 2, s.AddEventCallCount())
+	name, opts := s.AddEventArgsForCall(0)
+	assert.Equal(t, WarningEvent, name)
+	assert.Equal(t,
+		[]attribute.KeyValue{
+			attribute.Int64("log-attr-hello", 1),
+			attribute.String(WarningMessageKey, "something might be wrong"),
+			attribute.String(WarningErrorKey, errSample.Error()),
+		},
+		trace.NewEventConfig(opts...).Attributes())
+}
+
+type fakeLogsServiceClient struct {
+	mu      sync.Mutex
+	records []*logspb.LogRecord
+}
+
+func (c *fakeLogsServiceClient) Export(_ context.Context, in *collogspb.ExportLogsServiceRequest, _ ...grpc.CallOption) (*collogspb.ExportLogsServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rl := range in.ResourceLogs {
+		for _, ill := range rl.InstrumentationLibraryLogs {
+			c.records = append(c.records, ill.Logs...)
+		}
+	}
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func Test_spanLogger_ExportOTLP(t *testing.T) {
+	tp, err := Provider().Build()
+	require.Nil(t, err)
+	client := &fakeLogsServiceClient{}
+	var buf bytes.Buffer
+	// Child spans are logged at a higher verbosity by default
+	log := ZapLogger().Example().LogTo(&buf).LogUpto(1).ExportOTLP(client, nil).Build()
+	ctx := Context().WithTracerProvider(tp).WithLogger(log).Build()
+
+	ctx, parent, parentLog := Tracer().Trace(ctx, "parent")
+	parentLog.Info("in parent")
+	// Child spans of loggers carrying the parent span are correlated with the child
+	_, child, childLog := Tracer().Trace(logr.NewContext(ctx, parentLog), "child")
+	childLog.Info("in child", "foo", true)
+	child.End()
+	parent.End()
+
+	require.Nil(t, log.GetSink().(zapr.Underlier).GetUnderlying().Sync())
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	records := make(map[string]*logspb.LogRecord, len(client.records))
+	for _, record := range client.records {
+		var logger string
+		for _, attr := range record.Attributes {
+			if attr.Key == "logger" {
+				logger = attr.Value.GetStringValue()
+			}
+		}
+		records[logger+": "+record.Body.GetStringValue()] = record
+	}
+
+	for key, span := range map[string]trace.Span{
+		"parent: in parent":           parent,
+		"parent: ending span":         parent,
+		"parent.child: in child":      child,
+		"parent.child: ending span":   child,
+		"parent.child: starting span": parent, // The child span doesn't exist yet
+	} {
+		require.Contains(t, records, key)
+		traceID, spanID := span.SpanContext().TraceID(), span.SpanContext().SpanID()
+		assert.Equal(t, traceID[:], records[key].TraceId, key)
+		assert.Equal(t, spanID[:], records[key].SpanId, key)
+	}
+	assert.Empty(t, records["parent: starting span"].TraceId)
+
+	// The SpanRef isn't written to the destination
+	assert.NotContains(t, buf.String(), zaplog.SpanRefKey)
+}
+
+func Test_keysAndValuesToAttrs_SpanRef(t *testing.T) {
+	// SpanRefs aren't registered as span attributes
+	attrs := keysAndValuesToAttrs("log-", []interface{}{"foo", 1, zaplog.SpanRefKey, zaplog.SpanRef{Name: "bar"}})
+	assert.Equal(t, []attribute.KeyValue{attribute.Int("log-foo", 1)}, attrs)
+}
+
+func Test_spanLogger_WithErrorReporter(t *testing.T) {
+	tp, err := Provider().Build()
+	require.Nil(t, err)
+	var reports []zaplog.ErrorReport
+	reporter := zaplog.ErrorReporterFunc(func(report zaplog.ErrorReport) {
+		reports = append(reports, report)
+	})
+	log := ZapLogger().LogTo(io.Discard).WithErrorReporter(reporter).Build()
+	ctx := Context().WithTracerProvider(tp).WithLogger(log).Build()
+
+	_, span, spanLog := Tracer().Trace(ctx, "foo")
+	spanLog.Error(errSample, "unexpected")
+	span.End()
+
+	require.Len(t, reports, 1)
+	assert.Equal(t, errSample, reports[0].Err)
+	assert.Equal(t, "foo", reports[0].SpanName)
+	assert.Equal(t, span.SpanContext().TraceID(), reports[0].SpanContext.TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), reports[0].SpanContext.SpanID())
 }
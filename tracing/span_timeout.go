@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// DeadlineExceededEvent is the name of the event registered with a Span started
+	// using TracerBuilder.StartWithTimeout when its deadline is exceeded.
+	DeadlineExceededEvent = "deadline exceeded"
+)
+
+// StartWithTimeout works like Start, but the returned context is derived using
+// context.WithTimeout(ctx, d). If the deadline is exceeded before the span ends,
+// the DeadlineExceededEvent event is added to the span, and its status is set to
+// codes.Error. This is common for e.g. outbound calls.
+//
+// Ending the returned Span releases the resources associated with the timeout,
+// i.e. there is no need to cancel the returned context separately.
+func (b *TracerBuilder) StartWithTimeout(ctx context.Context, fnName string, d time.Duration, opts ...trace.SpanStartOption) (context.Context, Span) {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	ctx, span, _ := b.trace(ctx, fnName, 1, opts)
+
+	s := &timeoutSpan{
+		Span:     span,
+		cancel:   cancel,
+		endOnce:  &sync.Once{},
+		ended:    make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	go s.watch(ctx, d)
+
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+// timeoutSpan is a composite Span that cancels the timeout context when
+// it ends.
+type timeoutSpan struct {
+	// embedding is important; this automatically exposes all inherited functionality from the
+	// underlying resource.
+	Span
+
+	cancel  context.CancelFunc
+	endOnce *sync.Once
+	// ended is closed when End is called.
+	ended chan struct{}
+	// finished is closed when the watch goroutine is done.
+	finished chan struct{}
+}
+
+func (s *timeoutSpan) watch(ctx context.Context, d time.Duration) {
+	defer close(s.finished)
+
+	select {
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			s.Span.AddEvent(DeadlineExceededEvent)
+			s.Span.SetStatus(codes.Error, "deadline of "+d.String()+" exceeded")
+		}
+	case <-s.ended:
+	}
+}
+
+// AddLink implements LinkAdder.
+func (s *timeoutSpan) AddLink(link trace.Link) { AddLink(s.Span, link) }
+
+func (s *timeoutSpan) End(options ...trace.SpanEndOption) {
+	// Wait for the watch goroutine, such that the status is always
+	// registered before the span ends.
+	s.endOnce.Do(func() {
+		close(s.ended)
+		<-s.finished
+		s.cancel()
+	})
+
+	// Attribute the log entries of a loggingSpan to the caller
+	if ls, ok := s.Span.(*loggingSpan); ok {
+		ls.end(1, options)
+		return
+	}
+	s.Span.End(options...)
+}
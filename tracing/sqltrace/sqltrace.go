@@ -0,0 +1,525 @@
+// Package sqltrace provides a database/sql/driver.Driver wrapper that instruments
+// all queries, statement executions and transactions using the tracing package.
+//
+// A span is started for each query, exec and transaction, with the query, the
+// number of arguments and the amount of rows affected or returned registered as
+// attributes. This means that database latency shows up in the same log and trace
+// stream as the rest of the application.
+//
+// Usage with a driver that is registered in database/sql:
+//
+//	sql.Register("traced-postgres", sqltrace.Wrap(&pq.Driver{}))
+//	db, err := sql.Open("traced-postgres", dsn)
+//
+// Or, with a driver.Connector:
+//
+//	db := sql.OpenDB(sqltrace.WrapConnector(connector))
+//
+// The context given to e.g. db.QueryContext decides what TracerProvider and
+// Logger are used, in the same way as for tracing.TracerBuilder.
+package sqltrace
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+
+	"github.com/luxas/deklarative/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+const (
+	// TracerName is the actor name of all spans created by this package.
+	TracerName = "sql"
+
+	// ArgsCountKey is the attribute key for the number of arguments given to
+	// a query or statement execution.
+	ArgsCountKey = attribute.Key("db.args_count")
+	// RowsKey is the attribute key for the number of rows affected by a
+	// statement execution, or returned by a query.
+	RowsKey = attribute.Key("db.rows")
+)
+
+// Wrap returns a driver.Driver that instruments all connections opened by d.
+// If d implements driver.DriverContext, so does the returned driver.
+func Wrap(d driver.Driver) driver.Driver {
+	if dc, ok := d.(driver.DriverContext); ok {
+		return &tracedDriverContext{tracedDriver{d}, dc}
+	}
+	return &tracedDriver{d}
+}
+
+// WrapConnector returns a driver.Connector that instruments all connections
+// created by c. It is meant to be used with sql.OpenDB.
+func WrapConnector(c driver.Connector) driver.Connector {
+	return &tracedConnector{c}
+}
+
+type tracedDriver struct{ driver.Driver }
+
+func (d *tracedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return newTracedConn(c), nil
+}
+
+type tracedDriverContext struct {
+	tracedDriver
+	dc driver.DriverContext
+}
+
+func (d *tracedDriverContext) OpenConnector(name string) (driver.Connector, error) {
+	c, err := d.dc.OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConnector{c}, nil
+}
+
+type tracedConnector struct{ driver.Connector }
+
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newTracedConn(conn), nil
+}
+
+func (c *tracedConnector) Driver() driver.Driver { return Wrap(c.Connector.Driver()) }
+
+// errRegisterFunc registers all errors except the ones used for signaling
+// between database/sql and the driver.
+func errRegisterFunc(err error, span tracing.Span, log tracing.Logger) {
+	if errors.Is(err, driver.ErrSkip) || errors.Is(err, io.EOF) {
+		return
+	}
+	tracing.DefaultErrRegisterFunc(err, span, log)
+}
+
+// startSpan starts a span for the given operation, capturing the error that retErr
+// points to when the span ends.
+func startSpan(ctx context.Context, op, query string, argsCount int, retErr *error) (context.Context, tracing.Span) {
+	attrs := make([]attribute.KeyValue, 0, 2)
+	if len(query) != 0 {
+		attrs = append(attrs, semconv.DBStatementKey.String(query))
+	}
+	if argsCount >= 0 {
+		attrs = append(attrs, ArgsCountKey.Int(argsCount))
+	}
+	tr := tracing.Tracer().
+		WithActor(TracerName).
+		Capture(retErr).
+		ErrRegisterFunc(errRegisterFunc)
+	if len(attrs) != 0 {
+		tr = tr.WithAttributes(attrs...)
+	}
+	return tr.Start(ctx, op)
+}
+
+// Assert that tracedConn implements the optional interfaces it forwards.
+var (
+	_ driver.ConnPrepareContext = &tracedConn{}
+	_ driver.ConnBeginTx        = &tracedConn{}
+	_ driver.QueryerContext     = &tracedQueryerExecerConn{}
+	_ driver.ExecerContext      = &tracedQueryerExecerConn{}
+	_ driver.Pinger             = &tracedConn{}
+	_ driver.SessionResetter    = &tracedConn{}
+	_ driver.Validator          = &tracedConn{}
+	_ driver.NamedValueChecker  = &tracedConn{}
+)
+
+// newTracedConn wraps c. database/sql converts the arguments of a query
+// differently depending on if the connection implements driver.QueryerContext
+// and driver.ExecerContext, hence the returned connection only implements them
+// if c does.
+func newTracedConn(c driver.Conn) driver.Conn {
+	tc := &tracedConn{c}
+	_, queryer := c.(driver.QueryerContext)
+	_, execer := c.(driver.ExecerContext)
+	switch {
+	case queryer && execer:
+		return &tracedQueryerExecerConn{tc}
+	case queryer:
+		return &tracedQueryerConn{tc}
+	case execer:
+		return &tracedExecerConn{tc}
+	default:
+		return tc
+	}
+}
+
+type tracedConn struct{ driver.Conn }
+
+type tracedQueryerConn struct{ *tracedConn }
+
+func (c *tracedQueryerConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.queryContext(ctx, query, args)
+}
+
+type tracedExecerConn struct{ *tracedConn }
+
+func (c *tracedExecerConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.execContext(ctx, query, args)
+}
+
+type tracedQueryerExecerConn struct{ *tracedConn }
+
+func (c *tracedQueryerExecerConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.queryContext(ctx, query, args)
+}
+
+func (c *tracedQueryerExecerConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.execContext(ctx, query, args)
+}
+
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if pc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = pc.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newTracedStmt(stmt, query, c.Conn), nil
+}
+
+func (c *tracedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (_ driver.Tx, retErr error) {
+	ctx, span := startSpan(ctx, "Tx", "", -1, &retErr)
+
+	var tx driver.Tx
+	var err error
+	if bc, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = bc.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.Conn.Begin() //nolint:staticcheck
+	}
+	if err != nil {
+		retErr = err
+		span.End()
+		return nil, err
+	}
+	return &tracedTx{tx, span}, nil
+}
+
+func (c *tracedConn) queryContext(ctx context.Context, query string, args []driver.NamedValue) (_ driver.Rows, retErr error) {
+	qc := c.Conn.(driver.QueryerContext)
+	ctx, span := startSpan(ctx, "Query", query, len(args), &retErr)
+	rows, err := qc.QueryContext(ctx, query, args)
+	if err != nil {
+		retErr = err
+		span.End()
+		return nil, err
+	}
+	return newTracedRows(rows, span), nil
+}
+
+func (c *tracedConn) execContext(ctx context.Context, query string, args []driver.NamedValue) (_ driver.Result, retErr error) {
+	ec := c.Conn.(driver.ExecerContext)
+	ctx, span := startSpan(ctx, "Exec", query, len(args), &retErr)
+	defer span.End()
+
+	res, err := ec.ExecContext(ctx, query, args)
+	registerResult(span, res, err)
+	return res, err
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if sr, ok := c.Conn.(driver.SessionResetter); ok {
+		return sr.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid forwards the validity check to the underlying connection, if
+// supported. Otherwise, like database/sql, the connection is valid.
+func (c *tracedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue forwards the argument conversion to the underlying
+// connection, if supported.
+func (c *tracedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	return checkNamedValue(c.Conn, nv)
+}
+
+// checkNamedValue forwards the argument conversion to v, if supported.
+// Otherwise, driver.ErrSkip makes database/sql use the next converter.
+func checkNamedValue(v interface{}, nv *driver.NamedValue) error {
+	if nvc, ok := v.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func registerResult(span tracing.Span, res driver.Result, err error) {
+	if err != nil || res == nil {
+		return
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		span.SetAttributes(RowsKey.Int64(n))
+	}
+}
+
+// Assert that tracedStmt implements the optional interfaces it forwards.
+var (
+	_ driver.StmtExecContext   = &tracedStmt{}
+	_ driver.StmtQueryContext  = &tracedStmt{}
+	_ driver.NamedValueChecker = &tracedStmt{}
+	_ driver.ColumnConverter   = &tracedColumnConverterStmt{}
+)
+
+// newTracedStmt wraps stmt, prepared on conn. As database/sql uses another
+// argument conversion path if the statement implements
+// driver.ColumnConverter, the returned statement only implements it if stmt
+// does.
+func newTracedStmt(stmt driver.Stmt, query string, conn driver.Conn) driver.Stmt {
+	s := &tracedStmt{stmt, query, conn}
+	if cc, ok := stmt.(driver.ColumnConverter); ok { //nolint:staticcheck
+		return &tracedColumnConverterStmt{s, cc}
+	}
+	return s
+}
+
+type tracedStmt struct {
+	driver.Stmt
+	query string
+	// conn is the underlying connection the statement was prepared on.
+	conn driver.Conn
+}
+
+// CheckNamedValue forwards the argument conversion to the underlying
+// statement, or else the underlying connection, if supported. This is the
+// same order as database/sql uses.
+func (s *tracedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if _, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checkNamedValue(s.Stmt, nv)
+	}
+	return checkNamedValue(s.conn, nv)
+}
+
+type tracedColumnConverterStmt struct {
+	*tracedStmt
+	cc driver.ColumnConverter //nolint:staticcheck
+}
+
+func (s *tracedColumnConverterStmt) ColumnConverter(idx int) driver.ValueConverter {
+	return s.cc.ColumnConverter(idx)
+}
+
+func (s *tracedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), toNamedValues(args))
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (_ driver.Result, retErr error) {
+	ctx, span := startSpan(ctx, "Exec", s.query, len(args), &retErr)
+	defer span.End()
+
+	var res driver.Result
+	var err error
+	if sec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = sec.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = toValues(args); err == nil {
+			res, err = s.Stmt.Exec(values) //nolint:staticcheck
+		}
+	}
+	registerResult(span, res, err)
+	return res, err
+}
+
+func (s *tracedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), toNamedValues(args))
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (_ driver.Rows, retErr error) {
+	ctx, span := startSpan(ctx, "Query", s.query, len(args), &retErr)
+
+	var rows driver.Rows
+	var err error
+	if sqc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = sqc.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = toValues(args); err == nil {
+			rows, err = s.Stmt.Query(values) //nolint:staticcheck
+		}
+	}
+	if err != nil {
+		retErr = err
+		span.End()
+		return nil, err
+	}
+	return newTracedRows(rows, span), nil
+}
+
+// Assert that tracedRows implements the optional interfaces it forwards.
+var (
+	_ driver.RowsColumnTypeScanType         = &tracedRows{}
+	_ driver.RowsColumnTypeDatabaseTypeName = &tracedRows{}
+	_ driver.RowsColumnTypeLength           = &tracedRows{}
+	_ driver.RowsColumnTypeNullable         = &tracedRows{}
+	_ driver.RowsColumnTypePrecisionScale   = &tracedRows{}
+	_ driver.RowsNextResultSet              = &tracedNextResultSetRows{}
+)
+
+// newTracedRows wraps rows, returned from the query that span traces. As
+// database/sql closes the rows at the end of the result set unless the rows
+// implement driver.RowsNextResultSet, the returned rows only implement it if
+// rows does.
+func newTracedRows(rows driver.Rows, span tracing.Span) driver.Rows {
+	r := &tracedRows{Rows: rows, span: span}
+	if nrs, ok := rows.(driver.RowsNextResultSet); ok {
+		return &tracedNextResultSetRows{r, nrs}
+	}
+	return r
+}
+
+// tracedRows counts the returned rows, and ends the query span when closed.
+//
+// The column type information is forwarded to the underlying rows, if
+// supported. Otherwise, the same defaults as in database/sql are returned.
+type tracedRows struct {
+	driver.Rows
+
+	span  tracing.Span
+	count int64
+}
+
+func (r *tracedRows) ColumnTypeScanType(index int) reflect.Type {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return ct.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+func (r *tracedRows) ColumnTypeDatabaseTypeName(index int) string {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return ct.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *tracedRows) ColumnTypeLength(index int) (length int64, ok bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return ct.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *tracedRows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return ct.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *tracedRows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return ct.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+// tracedNextResultSetRows counts the returned rows of all result sets.
+type tracedNextResultSetRows struct {
+	*tracedRows
+	nrs driver.RowsNextResultSet
+}
+
+func (r *tracedNextResultSetRows) HasNextResultSet() bool { return r.nrs.HasNextResultSet() }
+func (r *tracedNextResultSetRows) NextResultSet() error   { return r.nrs.NextResultSet() }
+
+func (r *tracedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err == nil {
+		r.count++
+	}
+	return err
+}
+
+func (r *tracedRows) Close() (retErr error) {
+	r.span.SetAttributes(RowsKey.Int64(r.count))
+	defer r.span.End()
+
+	err := r.Rows.Close()
+	if err != nil {
+		r.span.RecordError(err)
+	}
+	return err
+}
+
+// tracedTx ends the transaction span on commit or rollback.
+type tracedTx struct {
+	driver.Tx
+	span tracing.Span
+}
+
+func (t *tracedTx) Commit() error {
+	defer t.span.End()
+	t.span.AddEvent("commit")
+	return recordErr(t.span, t.Tx.Commit())
+}
+
+func (t *tracedTx) Rollback() error {
+	defer t.span.End()
+	t.span.AddEvent("rollback")
+	return recordErr(t.span, t.Tx.Rollback())
+}
+
+func recordErr(span tracing.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
+func toNamedValues(args []driver.Value) []driver.NamedValue {
+	nvs := make([]driver.NamedValue, 0, len(args))
+	for i, arg := range args {
+		nvs = append(nvs, driver.NamedValue{Ordinal: i + 1, Value: arg})
+	}
+	return nvs
+}
+
+// ErrNamedArgsNotSupported is returned when named arguments are passed to a
+// driver.Stmt that only supports positional arguments.
+var ErrNamedArgsNotSupported = errors.New("sqltrace: driver does not support the use of named parameters")
+
+func toValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, 0, len(args))
+	for _, arg := range args {
+		if len(arg.Name) != 0 {
+			return nil, ErrNamedArgsNotSupported
+		}
+		values = append(values, arg.Value)
+	}
+	return values, nil
+}
@@ -0,0 +1,250 @@
+package sqltrace
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/luxas/deklarative/tracing"
+	"github.com/luxas/deklarative/tracing/filetest"
+	"github.com/sebdah/goldie/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrap(t *testing.T) {
+	g := filetest.New(t, goldie.WithNameSuffix(""))
+	defer g.Assert()
+
+	tp, err := tracing.Provider().TestYAML(g).Build()
+	require.Nil(t, err)
+	ctx := tracing.Context().WithTracerProvider(tp).Build()
+
+	sql.Register("fake-traced", Wrap(fakeDriver{}))
+	db, err := sql.Open("fake-traced", "")
+	require.Nil(t, err)
+	defer db.Close()
+
+	res, err := db.ExecContext(ctx, "DELETE FROM foo WHERE id = ?", 1)
+	require.Nil(t, err)
+	n, err := res.RowsAffected()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), n)
+
+	rows, err := db.QueryContext(ctx, "SELECT id FROM foo")
+	require.Nil(t, err)
+	for rows.Next() {
+		var id int64
+		assert.Nil(t, rows.Scan(&id))
+	}
+	assert.Nil(t, rows.Err())
+	assert.Nil(t, rows.Close())
+
+	_, err = db.ExecContext(ctx, "fail")
+	assert.ErrorIs(t, err, errFake)
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.Nil(t, err)
+	assert.Nil(t, tx.Commit())
+
+	assert.Nil(t, tp.Shutdown(ctx))
+}
+
+var errFake = errors.New("fake error")
+
+// fakeDriver is a minimal driver only implementing the required interfaces.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+type fakeStmt struct{ query string }
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	if s.query == "fail" {
+		return nil, errFake
+	}
+	return driver.RowsAffected(3), nil
+}
+
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error) { return &fakeRows{}, nil }
+
+type fakeRows struct{ i int64 }
+
+func (*fakeRows) Columns() []string { return []string{"id"} }
+func (*fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i == 2 {
+		return io.EOF
+	}
+	r.i++
+	dest[0] = r.i
+	return nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func TestWrap_optionalInterfaces(t *testing.T) {
+	d := &richDriver{}
+	sql.Register("rich-traced", Wrap(d))
+	db, err := sql.Open("rich-traced", "")
+	require.Nil(t, err)
+	defer db.Close()
+
+	rows, err := db.Query("SELECT id FROM foo", point{1, 2}, 3)
+	require.Nil(t, err)
+	// The statement converts the point, and the column converter the int
+	assert.Equal(t, []driver.Value{"1,2", "col1:3"}, d.args)
+
+	cts, err := rows.ColumnTypes()
+	require.Nil(t, err)
+	require.Len(t, cts, 1)
+	assert.Equal(t, reflect.TypeOf(int64(0)), cts[0].ScanType())
+	assert.Equal(t, "BIGINT", cts[0].DatabaseTypeName())
+	length, ok := cts[0].Length()
+	assert.Equal(t, []interface{}{int64(8), true}, []interface{}{length, ok})
+	nullable, ok := cts[0].Nullable()
+	assert.Equal(t, []interface{}{true, true}, []interface{}{nullable, ok})
+	precision, scale, ok := cts[0].DecimalSize()
+	assert.Equal(t, []interface{}{int64(19), int64(0), true}, []interface{}{precision, scale, ok})
+
+	var ids []int64
+	for {
+		for rows.Next() {
+			var id int64
+			assert.Nil(t, rows.Scan(&id))
+			ids = append(ids, id)
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	assert.Nil(t, rows.Err())
+	assert.Nil(t, rows.Close())
+	assert.Equal(t, []int64{1, 2, 1, 2}, ids)
+
+	c, err := Wrap(d).Open("")
+	require.Nil(t, err)
+	v, ok := c.(driver.Validator)
+	require.True(t, ok)
+	assert.False(t, v.IsValid())
+}
+
+func TestWrap_defaultInterfaces(t *testing.T) {
+	c, err := Wrap(fakeDriver{}).Open("")
+	require.Nil(t, err)
+	assert.True(t, c.(driver.Validator).IsValid())
+	_, ok := c.(driver.QueryerContext)
+	assert.False(t, ok)
+	_, ok = newTracedConn(queryerConn{}).(driver.QueryerContext)
+	assert.True(t, ok)
+	_, ok = newTracedConn(queryerConn{}).(driver.ExecerContext)
+	assert.False(t, ok)
+
+	stmt, err := c.Prepare("SELECT id FROM foo")
+	require.Nil(t, err)
+	_, ok = stmt.(driver.ColumnConverter) //nolint:staticcheck
+	assert.False(t, ok)
+	assert.ErrorIs(t, stmt.(driver.NamedValueChecker).CheckNamedValue(&driver.NamedValue{}), driver.ErrSkip)
+
+	rows, err := stmt.Query(nil) //nolint:staticcheck
+	require.Nil(t, err)
+	_, ok = rows.(driver.RowsNextResultSet)
+	assert.False(t, ok)
+	ct := rows.(driver.RowsColumnTypeScanType)
+	assert.Equal(t, reflect.TypeOf(new(interface{})).Elem(), ct.ColumnTypeScanType(0))
+}
+
+type queryerConn struct{ fakeConn }
+
+func (queryerConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &fakeRows{}, nil
+}
+
+type point struct{ x, y int }
+
+// richDriver is a driver implementing the optional interfaces that the
+// wrappers forward.
+type richDriver struct {
+	// args are the arguments given to the last query.
+	args []driver.Value
+}
+
+func (d *richDriver) Open(string) (driver.Conn, error) { return richConn{d}, nil }
+
+type richConn struct{ d *richDriver }
+
+func (c richConn) Prepare(query string) (driver.Stmt, error) { return richStmt{c.d}, nil }
+func (richConn) Close() error                                { return nil }
+func (richConn) Begin() (driver.Tx, error)                   { return fakeTx{}, nil }
+func (richConn) IsValid() bool                               { return false }
+
+type richStmt struct{ d *richDriver }
+
+func (richStmt) Close() error  { return nil }
+func (richStmt) NumInput() int { return 2 }
+
+func (richStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(0), nil }
+
+func (s richStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.args = args
+	return &richRows{}, nil
+}
+
+func (richStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if p, ok := nv.Value.(point); ok {
+		nv.Value = fmt.Sprintf("%d,%d", p.x, p.y)
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+func (richStmt) ColumnConverter(idx int) driver.ValueConverter {
+	return converterFunc(func(v interface{}) (driver.Value, error) {
+		return fmt.Sprintf("col%d:%v", idx, v), nil
+	})
+}
+
+type converterFunc func(interface{}) (driver.Value, error)
+
+func (f converterFunc) ConvertValue(v interface{}) (driver.Value, error) { return f(v) }
+
+// richRows returns two result sets with the rows 1 and 2.
+type richRows struct {
+	fakeRows
+	set int
+}
+
+func (*richRows) ColumnTypeScanType(int) reflect.Type               { return reflect.TypeOf(int64(0)) }
+func (*richRows) ColumnTypeDatabaseTypeName(int) string             { return "BIGINT" }
+func (*richRows) ColumnTypeLength(int) (int64, bool)                { return 8, true }
+func (*richRows) ColumnTypeNullable(int) (bool, bool)               { return true, true }
+func (*richRows) ColumnTypePrecisionScale(int) (int64, int64, bool) { return 19, 0, true }
+
+func (r *richRows) HasNextResultSet() bool { return r.set == 0 }
+
+func (r *richRows) NextResultSet() error {
+	if r.set != 0 {
+		return io.EOF
+	}
+	r.set++
+	r.i = 0
+	return nil
+}
@@ -0,0 +1,134 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerProviderStats contains statistics about the spans processed by
+// a TracerProvider built by the TracerProviderBuilder. It is useful for
+// e.g. debug endpoints and readiness checks.
+type TracerProviderStats struct {
+	// StartedSpans is the amount of spans started.
+	StartedSpans uint64
+	// EndedSpans is the amount of spans ended.
+	EndedSpans uint64
+	// ExportedSpans is the amount of spans successfully exported
+	// by an exporter. If multiple exporters are registered, a span is
+	// counted once per exporter that exported it.
+	ExportedSpans uint64
+	// DroppedSpans is the amount of spans that an exporter failed
+	// to export. If multiple exporters are registered, a span is
+	// counted once per exporter that failed to export it.
+	DroppedSpans uint64
+	// LastExportError is the latest error returned from an exporter,
+	// or nil if no export has failed.
+	LastExportError error
+}
+
+// StatsProvider is implemented by TracerProviders that collect statistics
+// about the spans they process, e.g. the ones built by the
+// TracerProviderBuilder. It is kept separate from TracerProvider, such that
+// TracerProvider implementations outside of this package don't need to
+// implement it.
+type StatsProvider interface {
+	// Stats returns statistics about the spans processed by the TracerProvider.
+	Stats() TracerProviderStats
+}
+
+// Stats returns the statistics of tp, if it implements StatsProvider.
+// Otherwise, empty stats are returned.
+func Stats(tp trace.TracerProvider) TracerProviderStats {
+	if sp, ok := tp.(StatsProvider); ok {
+		return sp.Stats()
+	}
+	return TracerProviderStats{}
+}
+
+// newStatsCollector returns a new, empty *statsCollector.
+func newStatsCollector() *statsCollector {
+	return &statsCollector{errMu: &sync.Mutex{}}
+}
+
+var _ tracesdk.SpanProcessor = &statsCollector{}
+
+// statsCollector is a SpanProcessor that counts the started and ended
+// spans. It also collects export statistics from the statsExporters
+// wrapping the registered exporters.
+type statsCollector struct {
+	started  uint64
+	ended    uint64
+	exported uint64
+	dropped  uint64
+
+	lastErr error
+	errMu   *sync.Mutex
+}
+
+func (c *statsCollector) OnStart(context.Context, tracesdk.ReadWriteSpan) {
+	atomic.AddUint64(&c.started, 1)
+}
+
+func (c *statsCollector) OnEnd(tracesdk.ReadOnlySpan) {
+	atomic.AddUint64(&c.ended, 1)
+}
+
+func (c *statsCollector) Shutdown(context.Context) error   { return nil }
+func (c *statsCollector) ForceFlush(context.Context) error { return nil }
+
+func (c *statsCollector) exportDone(spans int, err error) {
+	if err == nil {
+		atomic.AddUint64(&c.exported, uint64(spans))
+		return
+	}
+	atomic.AddUint64(&c.dropped, uint64(spans))
+
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	c.lastErr = err
+}
+
+// Stats returns a snapshot of the collected statistics.
+func (c *statsCollector) Stats() TracerProviderStats {
+	c.errMu.Lock()
+	lastErr := c.lastErr
+	c.errMu.Unlock()
+
+	return TracerProviderStats{
+		StartedSpans:    atomic.LoadUint64(&c.started),
+		EndedSpans:      atomic.LoadUint64(&c.ended),
+		ExportedSpans:   atomic.LoadUint64(&c.exported),
+		DroppedSpans:    atomic.LoadUint64(&c.dropped),
+		LastExportError: lastErr,
+	}
+}
+
+// statsExporter is a composite SpanExporter that reports the result of
+// each export to the statsCollector.
+type statsExporter struct {
+	// embedding is important; this automatically exposes all inherited functionality from the
+	// underlying resource.
+	tracesdk.SpanExporter
+
+	stats *statsCollector
+}
+
+func (e *statsExporter) ExportSpans(ctx context.Context, spans []tracesdk.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	e.stats.exportDone(len(spans), err)
+	return err
+}
+
+// statsProvider is a composite TracerProvider that exposes the statistics
+// collected by the statsCollector through the Stats method.
+type statsProvider struct {
+	*tracesdk.TracerProvider
+
+	stats *statsCollector
+}
+
+func (tp *statsProvider) Stats() TracerProviderStats { return tp.stats.Stats() }
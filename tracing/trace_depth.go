@@ -2,6 +2,7 @@ package tracing
 
 import (
 	"context"
+	"path"
 
 	"github.com/go-logr/logr"
 )
@@ -31,12 +32,29 @@ func LoggerEnabler() TraceEnabler {
 	})
 }
 
-func isDiscard(log Logger) bool { return log == logr.Discard() }
+// isDiscard returns true if log is backed by the LogSink of logr.Discard(), or
+// if it is the zero value.
+func isDiscard(log Logger) bool {
+	sink := log.GetSink()
+	return sink == nil || sink == logr.Discard().GetSink()
+}
 
 type traceDepthKeyStruct struct{}
 
 var traceDepthKey = traceDepthKeyStruct{} //nolint:gochecknoglobals
 
+// DepthFromContext returns the trace depth of the currently-executing Span in
+// the context, as started by the TracerBuilder. If there is no such Span, or it
+// is a root span, zero is returned. A child Span started from ctx gets depth
+// DepthFromContext(ctx) + 1.
+//
+// Note that the depth is not registered when neither tracing nor logging is
+// enabled; see TracerBuilder.Trace.
+func DepthFromContext(ctx context.Context) Depth {
+	d, _ := ctx.Value(traceDepthKey).(Depth)
+	return d
+}
+
 func getDepth(ctx context.Context, isNewRoot bool) Depth {
 	if isNewRoot {
 		return 0
@@ -109,3 +127,48 @@ func NthLogLevelIncrease(n uint64) LogLevelIncreaser {
 		return 0
 	})
 }
+
+// CappedLogLevelIncrease returns a LogLevelIncreaser that increases the verbosity
+// of the logger once every n traces of depth, like NthLogLevelIncrease, but never
+// past maxLevel. This ensures extremely deep (e.g. recursive) traces don't push the
+// log level into unusable territory.
+//
+// For example, CappedLogLevelIncrease(1, 3) means log = log.V(1) for each child trace
+// down to depth 3; spans of depth 4 and deeper are logged at log level 3 as well.
+func CappedLogLevelIncrease(n uint64, maxLevel uint64) LogLevelIncreaser {
+	nth := NthLogLevelIncrease(n)
+	return logLevelIncreaserFunc(func(ctx context.Context, cfg *TracerConfig) int {
+		if uint64(cfg.Depth)/n > maxLevel {
+			return 0
+		}
+		return nth.GetVIncrease(ctx, cfg)
+	})
+}
+
+// SpanNameLogLevelIncrease returns a LogLevelIncreaser that delegates to matched for
+// spans whose name or actor (tracer name) match the given path.Match pattern, and to
+// fallback otherwise. If fallback is nil, NoLogLevelIncrease() is used.
+//
+// For example, to raise the verbosity every second trace depth only for the methods
+// of the *Decoder actor in any package, while not raising the verbosity for other
+// spans:
+//
+//	SpanNameLogLevelIncrease("*Decoder.*", NthLogLevelIncrease(2), nil)
+//
+// A malformed pattern never matches.
+func SpanNameLogLevelIncrease(pattern string, matched, fallback LogLevelIncreaser) LogLevelIncreaser {
+	if fallback == nil {
+		fallback = NoLogLevelIncrease()
+	}
+	return logLevelIncreaserFunc(func(ctx context.Context, cfg *TracerConfig) int {
+		if matchesPattern(pattern, cfg.SpanName()) || matchesPattern(pattern, cfg.TracerName) {
+			return matched.GetVIncrease(ctx, cfg)
+		}
+		return fallback.GetVIncrease(ctx, cfg)
+	})
+}
+
+func matchesPattern(pattern, name string) bool {
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanNameLogLevelIncrease(t *testing.T) {
+	lli := SpanNameLogLevelIncrease("*Decoder.*", NthLogLevelIncrease(1), nil)
+	tests := []struct {
+		tracerName string
+		funcName   string
+		depth      Depth
+		want       int
+	}{
+		{tracerName: "*json.Decoder", funcName: "Decode", depth: 1, want: 1},
+		{tracerName: "*json.Decoder", funcName: "Decode", depth: 0, want: 0},
+		{tracerName: "*json.Encoder", funcName: "Encode", depth: 1, want: 0},
+		{tracerName: "Decoder.Decode", funcName: "", depth: 1, want: 1},
+		{tracerName: "", funcName: "Decode", depth: 1, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(fmtSpanName(tt.tracerName, tt.funcName), func(t *testing.T) {
+			cfg := &TracerConfig{TracerName: tt.tracerName, FuncName: tt.funcName, Depth: tt.depth}
+			assert.Equal(t, tt.want, lli.GetVIncrease(context.Background(), cfg))
+		})
+	}
+}
+
+func TestCappedLogLevelIncrease(t *testing.T) {
+	tests := []struct {
+		n, maxLevel uint64
+		want        []int
+	}{
+		{n: 1, maxLevel: 3, want: []int{0, 1, 1, 1, 0, 0}},
+		{n: 2, maxLevel: 1, want: []int{0, 0, 1, 0, 0, 0}},
+		{n: 2, maxLevel: 2, want: []int{0, 0, 1, 0, 1, 0}},
+		{n: 1, maxLevel: 0, want: []int{0, 0, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		lli := CappedLogLevelIncrease(tt.n, tt.maxLevel)
+		got := make([]int, 0, len(tt.want))
+		for d := range tt.want {
+			got = append(got, lli.GetVIncrease(context.Background(), &TracerConfig{Depth: Depth(d)}))
+		}
+		assert.Equal(t, tt.want, got, "n=%d maxLevel=%d", tt.n, tt.maxLevel)
+	}
+}
@@ -3,9 +3,15 @@ package tracing
 import (
 	"context"
 
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// defaultGlobalProvider is the global TracerProvider registered by default
+// in OpenTelemetry. It delegates to no-op Tracers until a global
+// TracerProvider is registered.
+var defaultGlobalProvider = otel.GetTracerProvider() //nolint:gochecknoglobals
+
 func fromUpstream(upstream trace.TracerProvider) TracerProvider {
 	return composite(upstream, nil)
 }
@@ -69,5 +75,27 @@ func (c *upstreamConverter) IsNoop() bool {
 	if c.underlying != nil {
 		return c.underlying.IsNoop()
 	}
-	return c.TracerProvider == noopProvider
+	return isNoopUpstream(c.TracerProvider)
+}
+
+// isNoopUpstream returns true if tp is the no-op TracerProvider, the default
+// global TracerProvider registered before any SetGlobalTracerProvider call, or
+// a TracerProvider reporting IsNoop() == true.
+func isNoopUpstream(tp trace.TracerProvider) bool {
+	if noopable, ok := tp.(interface {
+		IsNoop() bool
+	}); ok {
+		return noopable.IsNoop()
+	}
+	return tp == noopProvider || tp == defaultGlobalProvider
+}
+
+func (c *upstreamConverter) Stats() TracerProviderStats {
+	if sp, ok := c.TracerProvider.(StatsProvider); ok {
+		return sp.Stats()
+	}
+	if c.underlying != nil {
+		return Stats(c.underlying)
+	}
+	return TracerProviderStats{}
 }
@@ -2,8 +2,10 @@ package tracing
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -11,6 +13,7 @@ import (
 //nolint:gochecknoglobals
 var (
 	noopProvider = trace.NewNoopTracerProvider()
+	noopSpan     = trace.SpanFromContext(context.Background())
 )
 
 // TracerBuilder implements trace.Tracer.
@@ -20,6 +23,8 @@ type TracerBuilder struct {
 	errFn ErrRegisterFunc // default: DefaultErrRegisterFunc
 
 	spanStartOpts []trace.SpanStartOption
+	tracerOpts    []trace.TracerOption
+	pooled        bool
 }
 
 var _ trace.Tracer = &TracerBuilder{}
@@ -59,6 +64,32 @@ func (b *TracerBuilder) WithAttributes(attrs ...attribute.KeyValue) *TracerBuild
 	return b
 }
 
+// WithTracerOptions registers trace.TracerOptions that are used when
+// acquiring the Tracer from the TracerProvider, for example
+// trace.WithInstrumentationVersion and trace.WithSchemaURL.
+//
+// A call to this function appends to the list of previous values.
+func (b *TracerBuilder) WithTracerOptions(opts ...trace.TracerOption) *TracerBuilder {
+	b.tracerOpts = append(b.tracerOpts, opts...)
+	return b
+}
+
+// Pooled makes the composite Span and Logger returned from Start and Trace be
+// acquired from, and released back to, a sync.Pool when the Span ends. This cuts
+// allocations in high-frequency instrumentation, such as per-frame decode loops.
+//
+// When Pooled is used, neither the returned Span nor Logger may be used after
+// Span.End has been called, including through the returned context, and End
+// must only be called once. As the wrappers are reused by spans started later,
+// any use after End, e.g. a RecordError call deferred before a deferred End, or
+// a log call using the returned Logger, is registered with an unrelated span.
+// Hence, only use Pooled where the Span, Logger and context don't outlive the
+// call to End, e.g. when End is called at the end of a loop iteration.
+func (b *TracerBuilder) Pooled() *TracerBuilder {
+	b.pooled = true
+	return b
+}
+
 // Capture is used to capture a named error return value from the
 // function this TracerBuilder is executing in. It is possible to
 // "expose" a return value like "func foo() (retErr error) {}"
@@ -93,7 +124,7 @@ func (b *TracerBuilder) ErrRegisterFunc(fn ErrRegisterFunc) *TracerBuilder {
 // this trace.Tracer works. The only difference between this function and
 // Trace is the signature; Trace also returns a Logger.
 func (b *TracerBuilder) Start(ctx context.Context, fnName string, opts ...trace.SpanStartOption) (context.Context, Span) {
-	ctx, span, _ := b.Trace(ctx, fnName, opts...)
+	ctx, span, _ := b.trace(ctx, fnName, 1, opts)
 	return ctx, span
 }
 
@@ -119,7 +150,28 @@ func (b *TracerBuilder) Start(ctx context.Context, fnName string, opts ...trace.
 //
 // If Capture and possibly ErrRegisterFunc are set, the error return value will be
 // automatically registered to the Span.
+//
+// If the TracerProvider is a no-op one and the Logger is logr.Discard(), the given
+// context, a no-op Span and logr.Discard() are returned as-is without allocating
+// anything. If Capture is set, the returned no-op Span still runs the
+// ErrRegisterFunc when it ends, which costs one allocation.
 func (b *TracerBuilder) Trace(ctx context.Context, fnName string, opts ...trace.SpanStartOption) (context.Context, Span, Logger) {
+	return b.trace(ctx, fnName, 1, opts)
+}
+
+// trace implements Trace. callDepth is the number of stack frames between the
+// caller of interest and this function, such that the "starting span" log entry
+// is attributed to the caller.
+func (b *TracerBuilder) trace(ctx context.Context, fnName string, callDepth int, opts []trace.SpanStartOption) (context.Context, Span, Logger) {
+	// Fast path: If neither tracing nor logging is enabled, there's nothing to do
+	// but registering the captured error, if any
+	if isNoopContext(ctx) {
+		if b.err != nil && b.errFn != nil {
+			return ctx, &capturingSpan{Span: noopSpan, err: b.err, errFn: b.errFn}, logr.Discard()
+		}
+		return ctx, noopSpan, logr.Discard()
+	}
+
 	// Prepend the options from the builder, such that the options
 	// specified in the params have higher priority.
 	opts = append(b.spanStartOpts, opts...)
@@ -127,7 +179,7 @@ func (b *TracerBuilder) Trace(ctx context.Context, fnName string, opts ...trace.
 
 	cfg := TracerConfig{
 		SpanConfig:   sc,
-		TracerConfig: trace.NewTracerConfig(), // TODO
+		TracerConfig: trace.NewTracerConfig(b.tracerOpts...),
 
 		TracerName: tracerName(b.actor), // TODO: Unify funcName, actorName, spanName and tracerName
 		FuncName:   fnName,
@@ -158,37 +210,95 @@ func (b *TracerBuilder) Trace(ctx context.Context, fnName string, opts ...trace.
 	// but don't propagate the name downwards.
 	log := cfg.Logger.WithName(cfg.SpanName())
 
+	// Register the static key/value pairs from the context both with the
+	// logger and the span. They are not part of cfg.SpanConfig, as they
+	// shouldn't be logged twice when the span starts.
+	spanOpts := opts
+	if kvs := valuesFromContext(ctx); len(kvs) != 0 {
+		log = log.WithValues(kvs...)
+		// Limit the capacity, such that appending never modifies the builder's options
+		spanOpts = append(spanOpts[:len(spanOpts):len(spanOpts)],
+			trace.WithAttributes(keysAndValuesToAttrs("", kvs)...))
+	}
+
 	// Send a "span start" log entry, together with the attributes in the beginning
 	// These attributes won't be shown for every log entry in this
-	startLog := log
+	startLog := log.WithCallDepth(callDepth + 1)
 	if attrs := cfg.SpanConfig.Attributes(); len(attrs) != 0 {
 		startLog = startLog.WithValues(kvListToLogAttrs(attrs)...)
 	}
 	startLog.Info("starting span")
 
 	// Acquire the TracerProvider; and construct a Tracer from there
-	tracer := cfg.Provider.Tracer(cfg.TracerName) // TODO: Allow registering trace.TracerOptions?
+	tracer := cfg.Provider.Tracer(cfg.TracerName, b.tracerOpts...)
 
 	// Call the composite tracer, but swap out the returned span for ours, both in the
 	// return value and context.
-	ctx, span := tracer.Start(ctx, cfg.SpanName(), opts...)
+	ctx, span := tracer.Start(ctx, cfg.SpanName(), spanOpts...)
+
+	// Correlate the log entries of the span with it, e.g. when exporting them
+	// using OTLP. The "starting span" entry is logged before the span exists.
+	log = withSpanRef(log, cfg.SpanName(), span.SpanContext())
 
 	// Construct a composite Logger that also registers information
 	// to the Span.
-	spanLog := &spanLogger{
-		Logger: log,
-		span:   span,
+	spanLog := newSpanLogger(b.pooled)
+	*spanLog = spanLogger{
+		span:      span,
+		logEvents: logEventsFromContext(ctx),
 	}
 	// Construct a composite Span that also logs using the Logger.
-	logSpan := &loggingSpan{
+	logSpan := newLoggingSpan(b.pooled)
+	*logSpan = loggingSpan{
 		Span:     span,
 		provider: cfg.Provider,
 		log:      log,
 		err:      b.err,
 		errFn:    b.errFn,
 	}
+	if b.pooled {
+		logSpan.pooledLogger = spanLog
+	}
+	if spanDurationsFromContext(ctx) {
+		logSpan.start = time.Now()
+	}
 	// The Span needs to be re-registered with the ctx to propagate
 	// downwards. The Logger is already re-registered with the Span
 	// after a potential log level increase above.
-	return trace.ContextWithSpan(ctx, logSpan), logSpan, spanLog
+	return trace.ContextWithSpan(ctx, logSpan), logSpan, withSpanLogger(log, spanLog)
+}
+
+// isNoopContext returns true if the TracerProvider from the context is a no-op,
+// and the Logger from the context is logr.Discard(). It is equivalent to
+//
+//	TracerProviderFromContext(ctx).IsNoop() && isDiscard(LoggerFromContext(ctx))
+//
+// but doesn't allocate.
+func isNoopContext(ctx context.Context) bool {
+	if !isNoopUpstream(trace.SpanFromContext(ctx).TracerProvider()) {
+		return false
+	}
+	if !isNoopUpstream(otel.GetTracerProvider()) {
+		return false
+	}
+	return isDiscard(LoggerFromContext(ctx))
+}
+
+// capturingSpan is a composite no-op Span that runs the ErrRegisterFunc for
+// the captured error when it ends.
+type capturingSpan struct {
+	// embedding is important; this automatically exposes all inherited functionality from the
+	// underlying resource.
+	Span
+
+	err   *error
+	errFn ErrRegisterFunc
+}
+
+func (s *capturingSpan) End(options ...trace.SpanEndOption) {
+	s.errFn(*s.err, s, logr.Discard())
+	s.Span.End(options...)
 }
+
+// AddLink implements LinkAdder.
+func (s *capturingSpan) AddLink(link trace.Link) { AddLink(s.Span, link) }
@@ -5,6 +5,7 @@ import (
 	"io"
 	"math/rand"
 	"sync"
+	"time"
 
 	"github.com/luxas/deklarative/tracing/filetest"
 	"github.com/luxas/deklarative/tracing/traceyaml"
@@ -41,8 +42,12 @@ type TracerProviderBuilder struct {
 	errs         []error
 	tpOpts       []tracesdk.TracerProviderOption
 	attrs        []attribute.KeyValue
+	detected     []attribute.KeyValue
 	sync         bool
+	batchOpts    []tracesdk.BatchSpanProcessorOption
+	clock        Clock
 	compositeFns []CompositeTracerProviderFunc
+	yamlOpts     traceyaml.Options
 }
 
 // WithInsecureOTelExporter registers an exporter to an OpenTelemetry Collector on the
@@ -111,11 +116,51 @@ func (b *TracerProviderBuilder) WithOptions(opts ...tracesdk.TracerProviderOptio
 
 // WithAttributes allows registering more default attributes for traces created by this TracerProvider.
 // By default semantic conventions of version v1.4.0 are used, with "service.name" => "libgitops".
+// To set the service name, version and environment, WithServiceInfo can be used.
 func (b *TracerProviderBuilder) WithAttributes(attrs ...attribute.KeyValue) *TracerProviderBuilder {
 	b.attrs = append(b.attrs, attrs...)
 	return b
 }
 
+// WithServiceInfo registers the standard "service.name", "service.version" and
+// "deployment.environment" semantic convention attributes for traces created by this
+// TracerProvider. Empty arguments are ignored, e.g. if name is empty, the default
+// service name "libgitops" is kept.
+//
+// This is a shorthand for WithAttributes with the respective semconv attributes.
+func (b *TracerProviderBuilder) WithServiceInfo(name, version, environment string) *TracerProviderBuilder {
+	attrs := make([]attribute.KeyValue, 0, 3)
+	if len(name) != 0 {
+		attrs = append(attrs, semconv.ServiceNameKey.String(name))
+	}
+	if len(version) != 0 {
+		attrs = append(attrs, semconv.ServiceVersionKey.String(version))
+	}
+	if len(environment) != 0 {
+		attrs = append(attrs, semconv.DeploymentEnvironmentKey.String(environment))
+	}
+	return b.WithAttributes(attrs...)
+}
+
+// WithResourceDetectors runs the given resource detectors, and registers the detected
+// attributes as default attributes for traces created by this TracerProvider. Attributes
+// registered using WithAttributes take precedence over detected ones.
+//
+// Built-in detectors are ProcessDetector, HostDetector, BuildInfoDetector,
+// ContainerDetector and KubernetesDownwardAPIDetector.
+//
+// If a detector returns an error wrapping resource.ErrPartialResource, the
+// partial resource is kept and the error is only reported to the global
+// OpenTelemetry error handler as a warning. Other errors make Build fail.
+//
+// A call to this function appends to the list of previous values.
+func (b *TracerProviderBuilder) WithResourceDetectors(ctx context.Context, detectors ...resource.Detector) *TracerProviderBuilder {
+	attrs, err := detectResources(ctx, detectors)
+	b.detected = append(b.detected, attrs...)
+	b.errs = append(b.errs, err)
+	return b
+}
+
 // Synchronous allows configuring whether the exporters should export in synchronous mode,
 // which is useful for avoiding flakes in unit tests. The default mode is batching.
 // DO NOT use in production.
@@ -124,6 +169,28 @@ func (b *TracerProviderBuilder) Synchronous() *TracerProviderBuilder {
 	return b
 }
 
+// WithBatchOptions tunes the batch span processor used for each exporter in the
+// default (non-Synchronous) mode. Zero values mean that the default for the given
+// parameter is used, i.e. tracesdk.DefaultMaxQueueSize, tracesdk.DefaultBatchTimeout,
+// tracesdk.DefaultExportTimeout and tracesdk.DefaultMaxExportBatchSize, respectively.
+//
+// A call to this function appends to the list of previous values.
+func (b *TracerProviderBuilder) WithBatchOptions(maxQueueSize int, batchTimeout, exportTimeout time.Duration, maxBatchSize int) *TracerProviderBuilder {
+	if maxQueueSize != 0 {
+		b.batchOpts = append(b.batchOpts, tracesdk.WithMaxQueueSize(maxQueueSize))
+	}
+	if batchTimeout != 0 {
+		b.batchOpts = append(b.batchOpts, tracesdk.WithBatchTimeout(batchTimeout))
+	}
+	if exportTimeout != 0 {
+		b.batchOpts = append(b.batchOpts, tracesdk.WithExportTimeout(exportTimeout))
+	}
+	if maxBatchSize != 0 {
+		b.batchOpts = append(b.batchOpts, tracesdk.WithMaxExportBatchSize(maxBatchSize))
+	}
+	return b
+}
+
 // Composite builds a composite TracerProvider from the resulting SDKTracerProvider
 // when Build() is called. If the returned TracerProvider implements SDKTracerProvider,
 // it'll be used as-is. If the returned TracerProvider doesn't implement Shutdown or
@@ -141,10 +208,35 @@ func (b *TracerProviderBuilder) Composite(fn CompositeTracerProviderFunc) *Trace
 // This is useful for unit tests.
 func (b *TracerProviderBuilder) TestYAMLTo(w io.Writer) *TracerProviderBuilder {
 	return b.Composite(func(tp TracerProvider) trace.TracerProvider {
-		return traceyaml.New(tp, w)
+		return traceyaml.NewWithOptions(tp, w, b.yamlOpts)
 	})
 }
 
+// WithTestYAMLOptions customizes what span data TestYAMLTo and TestYAML capture,
+// e.g. to exclude volatile attributes that would make golden files churn. See
+// traceyaml.Options for more information.
+//
+// A call to this function overwrites any previous value.
+func (b *TracerProviderBuilder) WithTestYAMLOptions(opts traceyaml.Options) *TracerProviderBuilder {
+	b.yamlOpts = opts
+	return b
+}
+
+// WithSpanInfoTransform registers a transform that post-processes the span data
+// captured by TestYAMLTo and TestYAML before it is written, e.g. to rename
+// spans or collapse retries. See traceyaml.SpanInfoTransform for more
+// information.
+//
+// A call to this function appends to the list of previous values. Transforms
+// are registered with the options given to WithTestYAMLOptions, hence call
+// WithTestYAMLOptions first.
+func (b *TracerProviderBuilder) WithSpanInfoTransform(fn traceyaml.SpanInfoTransform) *TracerProviderBuilder {
+	// Limit the capacity, such that appending never modifies previously given options
+	transforms := b.yamlOpts.Transforms
+	b.yamlOpts.Transforms = append(transforms[:len(transforms):len(transforms)], fn)
+	return b
+}
+
 // WithTraceEnabler registers a TraceEnabler that determines if tracing shall
 // be enabled for a given TracerConfig.
 func (b *TracerProviderBuilder) WithTraceEnabler(te TraceEnabler) *TracerProviderBuilder {
@@ -174,25 +266,39 @@ func (b *TracerProviderBuilder) TestYAML(g *filetest.Tester) *TracerProviderBuil
 	return b.TestYAMLTo(g.Add(g.T.Name() + ".yaml").Writer())
 }
 
-// TestJSON enables Synchronous mode, exports using WithStdoutExporter without
-// timestamps to a filetest.Tester file under testdata/ with the current test
-// name and a ".json" suffix. Deterministic IDs are used with a static seed.
+// TestJSON enables Synchronous mode, exports using WithStdoutExporter to a
+// filetest.Tester file under testdata/ with the current test name and a ".json"
+// suffix. Deterministic IDs are used with a static seed, and a DeterministicClock
+// starting at 2021-01-01T00:00:00Z advancing one millisecond per timestamp is used.
 //
 // This is useful for unit tests.
 func (b *TracerProviderBuilder) TestJSON(g *filetest.Tester) *TracerProviderBuilder {
 	return b.Synchronous().WithStdoutExporter(
-		stdouttrace.WithWriter(g.Add(g.T.Name()+".json").Writer()),
-		stdouttrace.WithoutTimestamps(),
-	).DeterministicIDs(1234)
+		stdouttrace.WithWriter(g.Add(g.T.Name() + ".json").Writer()),
+	).DeterministicIDs(1234).
+		WithClock(DeterministicClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), time.Millisecond))
+}
+
+// WithClock makes the TracerProvider use the given Clock for the timestamps of
+// span starts and ends, events and errors, unless a timestamp is explicitly given
+// using trace.WithTimestamp. Useful for unit tests, together with DeterministicClock.
+//
+// A call to this function overwrites any previous value.
+func (b *TracerProviderBuilder) WithClock(clock Clock) *TracerProviderBuilder {
+	b.clock = clock
+	return b
 }
 
-// DeterministicIDs enables deterministic trace and span IDs. Useful for unit tests.
+// DeterministicIDs enables deterministic trace and span IDs. Useful for unit tests,
+// e.g. together with traceyaml.Options.IncludeIDs to verify span linkage.
 // DO NOT use in production.
 func (b *TracerProviderBuilder) DeterministicIDs(seed int64) *TracerProviderBuilder {
 	return b.WithOptions(tracesdk.WithIDGenerator(deterministicWithSeed(seed)))
 }
 
-// Build builds the SDKTracerProvider.
+// Build builds the SDKTracerProvider. The returned TracerProvider collects
+// statistics about the spans it processes, available through Stats(), as
+// it implements StatsProvider.
 func (b *TracerProviderBuilder) Build() (TracerProvider, error) {
 	// Default to discard all trace output, if no exporter is configured
 	if len(b.exporters) == 0 {
@@ -204,11 +310,13 @@ func (b *TracerProviderBuilder) Build() (TracerProvider, error) {
 	}
 
 	// By default, set the service name to "libgitops".
-	// This can be overridden through WithAttributes
+	// This can be overridden through WithResourceDetectors or WithAttributes
 	attrs := []attribute.KeyValue{
 		semconv.ServiceNameKey.String("libgitops"),
 	}
-	// Make sure to order the default attrs first, so b.attrs can override the default ones
+	// Make sure to order the default attrs first, then the detected ones, so b.attrs can
+	// override both the default and detected ones
+	attrs = append(attrs, b.detected...)
 	attrs = append(attrs, b.attrs...)
 
 	// By default, register a resource with the given attributes
@@ -217,15 +325,20 @@ func (b *TracerProviderBuilder) Build() (TracerProvider, error) {
 		tracesdk.WithResource(resource.NewWithAttributes(semconv.SchemaURL, attrs...)),
 	}
 
+	// Collect statistics about started, ended and exported spans
+	stats := newStatsCollector()
+	tpOpts = append(tpOpts, tracesdk.WithSpanProcessor(stats))
+
 	// Register all exporters with the options list
-	for _, exporter := range b.exporters {
+	for _, exp := range b.exporters {
+		exporter := &statsExporter{exp, stats}
 		// The non-syncing mode shall only be used in testing. The batching mode must be used in production.
 		if b.sync {
 			tpOpts = append(tpOpts, tracesdk.WithSyncer(exporter))
 			continue
 		}
 
-		tpOpts = append(tpOpts, tracesdk.WithBatcher(exporter))
+		tpOpts = append(tpOpts, tracesdk.WithBatcher(exporter, b.batchOpts...))
 	}
 
 	// Make sure to order the defaultTpOpts first, so b.tpOpts can override the default ones
@@ -234,7 +347,12 @@ func (b *TracerProviderBuilder) Build() (TracerProvider, error) {
 	sdktp := tracesdk.NewTracerProvider(tpOpts...)
 
 	// Compose a set of SDKTracerProviders on top of each other
-	tp := fromUpstream(sdktp)
+	tp := fromUpstream(&statsProvider{sdktp, stats})
+	// The clock is applied before any composite TracerProviders, such that the
+	// timestamps aren't visible to them.
+	if b.clock != nil {
+		tp = &clockProvider{tp, b.clock}
+	}
 	for _, fn := range b.compositeFns {
 		tp = composite(fn(tp), tp)
 	}
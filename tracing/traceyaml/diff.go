@@ -0,0 +1,242 @@
+package traceyaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/luxas/deklarative/tracing/filetest"
+	"gopkg.in/yaml.v2"
+)
+
+// Diff returns a human-readable description of how the actual trace YAML (or
+// JSON) differs from the expected, as written by a TracerProvider returned from
+// New. Instead of a raw text diff, which is unreadable for deep traces, the
+// difference is described per span: missing and unexpected spans, and changed
+// attributes, errors, events, configuration, status and name changes.
+//
+// If either actual or expected can't be parsed as trace YAML or JSON, or the
+// spans are equal (e.g. only comments differ), a unified text diff is returned.
+//
+// Diff implements goldie.DiffFn, and can hence be used for golden files using
+// goldie.WithDiffFn(traceyaml.Diff).
+func Diff(actual, expected string) string {
+	actualSpans, actualErr := Parse([]byte(actual))
+	expectedSpans, expectedErr := Parse([]byte(expected))
+	if actualErr == nil && expectedErr == nil {
+		if diff := DiffSpans(actualSpans, expectedSpans); len(diff) != 0 {
+			return diff
+		}
+	}
+
+	return filetest.UnifiedDiff(actual, expected, 1, false)
+}
+
+// DiffSpans returns a human-readable description of how the actual span trees
+// differ from the expected, one line per difference. Spans are identified by
+// the path of span names from the root span, separated by " > ". If the span
+// trees are equal, an empty string is returned.
+//
+// Spans in a list are matched by name, in order; an expected span that has no
+// matching actual span is reported missing, and vice versa.
+func DiffSpans(actual, expected []*SpanInfo) string {
+	d := &spanDiffer{}
+	d.diffSpanLists("", actual, expected)
+	return d.String()
+}
+
+// Parse loads SpanInfo trees back from trace YAML or JSON, as written by a
+// TracerProvider returned from New, e.g. a golden file. This allows previously
+// recorded traces to be inspected or compared programmatically, e.g. using
+// Expect or DiffSpans. The format is detected automatically.
+func Parse(data []byte) ([]*SpanInfo, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) != 0 && trimmed[0] == '{' {
+		return parseJSON(data)
+	}
+
+	var spans []*SpanInfo
+	if err := yaml.UnmarshalStrict(data, &spans); err != nil {
+		return nil, err
+	}
+	return spans, nil
+}
+
+type spanDiffer struct {
+	lines []string
+}
+
+func (d *spanDiffer) String() string {
+	if len(d.lines) == 0 {
+		return ""
+	}
+	return strings.Join(d.lines, "\n") + "\n"
+}
+
+func (d *spanDiffer) addf(format string, args ...interface{}) {
+	d.lines = append(d.lines, fmt.Sprintf(format, args...))
+}
+
+func spanPath(parent, name string) string {
+	if len(parent) == 0 {
+		return fmt.Sprintf("%q", name)
+	}
+	return fmt.Sprintf("%s > %q", parent, name)
+}
+
+func (d *spanDiffer) diffSpanLists(parent string, actual, expected []*SpanInfo) {
+	// i is the index of the next actual span to match
+	i := 0
+	for _, exp := range expected {
+		j := i
+		for j < len(actual) && actual[j].SpanName != exp.SpanName {
+			j++
+		}
+		if j == len(actual) {
+			d.addf("missing span %s", spanPath(parent, exp.SpanName))
+			continue
+		}
+		// All actual spans skipped over are unexpected
+		for ; i < j; i++ {
+			d.addf("unexpected span %s", spanPath(parent, actual[i].SpanName))
+		}
+		d.diffSpans(spanPath(parent, exp.SpanName), actual[j], exp)
+		i = j + 1
+	}
+	for ; i < len(actual); i++ {
+		d.addf("unexpected span %s", spanPath(parent, actual[i].SpanName))
+	}
+}
+
+func (d *spanDiffer) diffSpans(path string, actual, expected *SpanInfo) {
+	d.diffValues(path+": duration", actual.Duration, expected.Duration)
+	d.diffValues(path+": trace ID", actual.TraceID, expected.TraceID)
+	d.diffValues(path+": span ID", actual.SpanID, expected.SpanID)
+	d.diffValues(path+": parent ID", actual.ParentID, expected.ParentID)
+	d.diffAttributes(path+": attribute", actual.Attributes, expected.Attributes)
+	d.diffErrors(path, actual.Errors, expected.Errors)
+	d.diffEvents(path, actual.Events, expected.Events)
+	d.diffValues(path+": logs", actual.Logs, expected.Logs)
+	d.diffValues(path+": links", actual.Links, expected.Links)
+	d.diffSpanConfigs(path+": start config", actual.StartConfig, expected.StartConfig)
+	d.diffSpanConfigs(path+": end config", actual.EndConfig, expected.EndConfig)
+	d.diffValues(path+": status changes", actual.StatusChanges, expected.StatusChanges)
+	d.diffValues(path+": name changes", actual.NameChanges, expected.NameChanges)
+	d.diffValues(path+": warnings", actual.Warnings, expected.Warnings)
+	d.diffSpanLists(path, actual.Children, expected.Children)
+	d.diffValues(path+": truncated children", actual.TruncatedChildren, expected.TruncatedChildren)
+}
+
+func (d *spanDiffer) diffAttributes(prefix string, actual, expected Attributes) {
+	keys := make([]string, 0, len(actual)+len(expected))
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	for key := range actual {
+		if _, ok := expected[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		actualVal, inActual := actual[key]
+		expectedVal, inExpected := expected[key]
+		switch {
+		case !inActual:
+			d.addf("%s %q: missing, expected %s", prefix, key, compact(expectedVal))
+		case !inExpected:
+			d.addf("%s %q: unexpected, got %s", prefix, key, compact(actualVal))
+		case !equalValues(actualVal, expectedVal):
+			d.addf("%s %q: expected %s, got %s", prefix, key, compact(expectedVal), compact(actualVal))
+		}
+	}
+}
+
+func (d *spanDiffer) diffErrors(path string, actual, expected []Error) {
+	for i := 0; i < len(actual) || i < len(expected); i++ {
+		switch {
+		case i >= len(actual):
+			d.addf("%s: missing error %q", path, expected[i].Error)
+		case i >= len(expected):
+			d.addf("%s: unexpected error %q", path, actual[i].Error)
+		case actual[i].Error != expected[i].Error:
+			d.addf("%s: error %d: expected %q, got %q", path, i, expected[i].Error, actual[i].Error)
+		default:
+			d.diffAttributes(fmt.Sprintf("%s: error %q: attribute", path, actual[i].Error),
+				actual[i].Attributes, expected[i].Attributes)
+		}
+	}
+}
+
+func (d *spanDiffer) diffEvents(path string, actual, expected []Event) {
+	for i := 0; i < len(actual) || i < len(expected); i++ {
+		switch {
+		case i >= len(actual):
+			d.addf("%s: missing event %q", path, expected[i].Name)
+		case i >= len(expected):
+			d.addf("%s: unexpected event %q", path, actual[i].Name)
+		case actual[i].Name != expected[i].Name:
+			d.addf("%s: event %d: expected %q, got %q", path, i, expected[i].Name, actual[i].Name)
+		default:
+			d.diffAttributes(fmt.Sprintf("%s: event %q: attribute", path, actual[i].Name),
+				actual[i].Attributes, expected[i].Attributes)
+		}
+	}
+}
+
+func (d *spanDiffer) diffSpanConfigs(prefix string, actual, expected *SpanConfig) {
+	if actual == nil {
+		actual = &SpanConfig{}
+	}
+	if expected == nil {
+		expected = &SpanConfig{}
+	}
+	d.diffAttributes(prefix+": attribute", actual.Attributes, expected.Attributes)
+	d.diffValues(prefix+": links", actual.Links, expected.Links)
+	d.diffValues(prefix+": new root", actual.NewRoot, expected.NewRoot)
+	d.diffValues(prefix+": span kind", actual.SpanKind, expected.SpanKind)
+}
+
+func (d *spanDiffer) diffValues(prefix string, actual, expected interface{}) {
+	// Treat nil and empty lists the same, as they're marshalled the same
+	if reflect.DeepEqual(actual, expected) || (isEmpty(actual) && isEmpty(expected)) {
+		return
+	}
+	d.addf("%s: expected %s, got %s", prefix, compact(expected), compact(actual))
+}
+
+func isEmpty(obj interface{}) bool {
+	v := reflect.ValueOf(obj)
+	return v.Kind() == reflect.Slice && v.Len() == 0
+}
+
+// compact returns obj as single-line JSON.
+func compact(obj interface{}) string {
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Sprintf("%v", obj)
+	}
+	return string(out)
+}
+
+// parseJSON unmarshals a stream of JSON SpanInfo objects.
+func parseJSON(data []byte) ([]*SpanInfo, error) {
+	var spans []*SpanInfo
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	for {
+		si := &SpanInfo{}
+		err := dec.Decode(si)
+		if errors.Is(err, io.EOF) {
+			return spans, nil
+		} else if err != nil {
+			return nil, err
+		}
+		spans = append(spans, si)
+	}
+}
@@ -0,0 +1,188 @@
+package traceyaml
+
+import (
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/codes"
+)
+
+// TestingT is the subset of *testing.T that Expect uses.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Expect returns an Expectation for making targeted assertions about the given
+// SpanInfo trees, e.g. as recorded by a Recorder. This complements golden
+// files, for example:
+//
+//	traceyaml.Expect(t, rec.Spans()...).
+//		Span("doWork").
+//		WithAttr("result", "foo").
+//		WithStatus(codes.Ok)
+//
+// Failed assertions are reported using t.Errorf.
+func Expect(t TestingT, spans ...*SpanInfo) *Expectation {
+	return &Expectation{t: t, spans: spans}
+}
+
+// Expectation makes assertions about a list of SpanInfo trees.
+type Expectation struct {
+	t     TestingT
+	spans []*SpanInfo
+}
+
+// Span returns a SpanExpectation for the first span named name, searching the
+// SpanInfo trees depth-first. If there is no such span, the test fails, and
+// all assertions on the returned SpanExpectation are no-ops.
+func (e *Expectation) Span(name string) *SpanExpectation {
+	e.t.Helper()
+	si := findSpan(e.spans, name)
+	if si == nil {
+		e.t.Errorf("traceyaml: no span named %q", name)
+	}
+	return &SpanExpectation{t: e.t, span: si, path: fmt.Sprintf("%q", name)}
+}
+
+// NoSpan asserts that no span is named name.
+func (e *Expectation) NoSpan(name string) *Expectation {
+	e.t.Helper()
+	if findSpan(e.spans, name) != nil {
+		e.t.Errorf("traceyaml: unexpected span named %q", name)
+	}
+	return e
+}
+
+func findSpan(spans []*SpanInfo, name string) *SpanInfo {
+	for _, si := range spans {
+		if si.SpanName == name {
+			return si
+		}
+		if child := findSpan(si.Children, name); child != nil {
+			return child
+		}
+	}
+	return nil
+}
+
+// SpanExpectation makes assertions about a span. All methods return the
+// SpanExpectation itself, such that assertions can be chained.
+type SpanExpectation struct {
+	t    TestingT
+	span *SpanInfo
+	path string
+}
+
+// Info returns the SpanInfo the assertions are made about, or nil if the span
+// wasn't found.
+func (e *SpanExpectation) Info() *SpanInfo { return e.span }
+
+// Child returns a SpanExpectation for the first direct child span named name.
+// If there is no such child, the test fails, and all assertions on the returned
+// SpanExpectation are no-ops.
+func (e *SpanExpectation) Child(name string) *SpanExpectation {
+	e.t.Helper()
+	child := &SpanExpectation{t: e.t, path: e.path + fmt.Sprintf(" > %q", name)}
+	if e.span == nil {
+		return child
+	}
+	for _, si := range e.span.Children {
+		if si.SpanName == name {
+			child.span = si
+			return child
+		}
+	}
+	e.t.Errorf("traceyaml: span %s has no child span named %q", e.path, name)
+	return child
+}
+
+// WithAttr asserts that the span has attribute key set to value. Numbers are
+// compared by value regardless of their type, and arrays and slices element by
+// element, e.g. 1 equals int64(1), and []string{"a"} equals [1]string{"a"}.
+func (e *SpanExpectation) WithAttr(key string, value interface{}) *SpanExpectation {
+	e.t.Helper()
+	if e.span == nil {
+		return e
+	}
+	actual, ok := e.span.Attributes[key]
+	if !ok {
+		e.t.Errorf("traceyaml: span %s has no attribute %q", e.path, key)
+		return e
+	}
+	if !equalValues(actual, value) {
+		e.t.Errorf("traceyaml: span %s attribute %q: expected %v, got %v", e.path, key, value, actual)
+	}
+	return e
+}
+
+// WithoutAttr asserts that the span doesn't have attribute key.
+func (e *SpanExpectation) WithoutAttr(key string) *SpanExpectation {
+	e.t.Helper()
+	if e.span == nil {
+		return e
+	}
+	if actual, ok := e.span.Attributes[key]; ok {
+		e.t.Errorf("traceyaml: span %s has unexpected attribute %q: %v", e.path, key, actual)
+	}
+	return e
+}
+
+// WithStatus asserts that the last status set for the span has the given code.
+// If no status is set, the code is codes.Unset.
+func (e *SpanExpectation) WithStatus(code codes.Code) *SpanExpectation {
+	e.t.Helper()
+	if e.span == nil {
+		return e
+	}
+	actual := codes.Unset
+	if n := len(e.span.StatusChanges); n != 0 {
+		actual = e.span.StatusChanges[n-1].Code
+	}
+	if actual != code {
+		e.t.Errorf("traceyaml: span %s status: expected %v, got %v", e.path, code, actual)
+	}
+	return e
+}
+
+// WithEvent asserts that an event named name was added to the span.
+func (e *SpanExpectation) WithEvent(name string) *SpanExpectation {
+	e.t.Helper()
+	if e.span == nil {
+		return e
+	}
+	for _, ev := range e.span.Events {
+		if ev.Name == name {
+			return e
+		}
+	}
+	e.t.Errorf("traceyaml: span %s has no event named %q", e.path, name)
+	return e
+}
+
+// WithError asserts that an error containing substr was recorded for the span.
+func (e *SpanExpectation) WithError(substr string) *SpanExpectation {
+	e.t.Helper()
+	if e.span == nil {
+		return e
+	}
+	for _, err := range e.span.Errors {
+		if strings.Contains(err.Error, substr) {
+			return e
+		}
+	}
+	e.t.Errorf("traceyaml: span %s has no error containing %q", e.path, substr)
+	return e
+}
+
+// WithChildren asserts that the span has exactly n direct child spans.
+func (e *SpanExpectation) WithChildren(n int) *SpanExpectation {
+	e.t.Helper()
+	if e.span == nil {
+		return e
+	}
+	if actual := len(e.span.Children); actual != n {
+		e.t.Errorf("traceyaml: span %s: expected %d child spans, got %d", e.path, n, actual)
+	}
+	return e
+}
@@ -2,66 +2,100 @@ package traceyaml
 
 import (
 	"sync"
+	"time"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
-func (td *SpanInfo) newChild(spanName string, opts ...trace.SpanStartOption) *SpanInfo {
+func (td *SpanInfo) addChild(child *SpanInfo) {
 	td.mu.Lock()
 	defer td.mu.Unlock()
 
-	child := newSpanInfo(spanName, opts...)
 	child.isChild = true
+	child.parent = td
 	td.Children = append(td.Children, child)
-	return child
 }
 
-func eventConfigFrom(opts ...trace.EventOption) EventConfig {
+// snapshot returns a deep copy of td and its child spans, that can be read
+// while the spans are still being modified. td.mu must be held by the caller.
+func (td *SpanInfo) snapshot() *SpanInfo {
+	cp := *td
+	cp.Attributes = make(Attributes, len(td.Attributes))
+	for key, val := range td.Attributes {
+		cp.Attributes[key] = val
+	}
+	if td.Children != nil {
+		cp.Children = make([]*SpanInfo, 0, len(td.Children))
+	}
+	for _, child := range td.Children {
+		child.mu.Lock()
+		cp.Children = append(cp.Children, child.snapshot())
+		child.mu.Unlock()
+	}
+	return &cp
+}
+
+func (o *Options) eventConfigFrom(opts ...trace.EventOption) EventConfig {
 	ec := trace.NewEventConfig(opts...)
-	return EventConfig{Attributes: newAttrs(ec.Attributes())}
+	return EventConfig{Attributes: o.newAttrs(ec.Attributes())}
 }
 
-func newSpanInfo(spanName string, opts ...trace.SpanStartOption) *SpanInfo {
+func (o *Options) newSpanInfo(r *spanRegistry, spanName string, opts ...trace.SpanStartOption) *SpanInfo {
 	return &SpanInfo{
 		SpanName:    spanName,
-		StartConfig: spanConfigFromStart(opts...),
+		StartConfig: o.spanConfigFromStart(r, opts...),
 		Attributes:  make(Attributes),
 		mu:          &sync.Mutex{},
+		startTime:   timestampOrNow(trace.NewSpanStartConfig(opts...).Timestamp()),
 	}
 }
 
-func newAttrs(attrList []attribute.KeyValue) Attributes {
-	attrMap := make(Attributes, len(attrList))
-	attrsInto(attrList, attrMap)
-	return attrMap
+func (o *Options) durationFrom(td *SpanInfo) string {
+	if o.Duration == nil {
+		return ""
+	}
+	return o.Duration(td.endTime.Sub(td.startTime))
 }
 
-func attrsInto(attrList []attribute.KeyValue, attrMap Attributes) {
-	for _, attr := range attrList {
-		attrMap[string(attr.Key)] = attr.Value.AsInterface()
+func timestampOrNow(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now()
 	}
+	return t
 }
 
-func spanConfigFromStart(opts ...trace.SpanStartOption) *SpanConfig {
+func (o *Options) newAttrs(attrList []attribute.KeyValue) Attributes {
+	attrMap := make(Attributes, len(attrList))
+	o.attrsInto(attrList, attrMap)
+	return attrMap
+}
+
+func (o *Options) spanConfigFromStart(r *spanRegistry, opts ...trace.SpanStartOption) *SpanConfig {
 	if len(opts) == 0 {
 		return nil
 	}
-	return spanConfigFrom(trace.NewSpanStartConfig(opts...))
+	return o.spanConfigFrom(r, trace.NewSpanStartConfig(opts...))
 }
 
-func spanConfigFromEnd(opts ...trace.SpanEndOption) *SpanConfig {
+func (o *Options) spanConfigFromEnd(r *spanRegistry, opts ...trace.SpanEndOption) *SpanConfig {
 	if len(opts) == 0 {
 		return nil
 	}
-	return spanConfigFrom(trace.NewSpanEndConfig(opts...))
+	return o.spanConfigFrom(r, trace.NewSpanEndConfig(opts...))
 }
 
-func spanConfigFrom(sc *trace.SpanConfig) *SpanConfig {
-	return &SpanConfig{
-		Attributes: newAttrs(sc.Attributes()),
-		Links:      sc.Links(),
+// spanConfigFrom returns nil if no captured configuration is set, e.g. if only
+// the timestamp or excluded attributes are set.
+func (o *Options) spanConfigFrom(r *spanRegistry, sc *trace.SpanConfig) *SpanConfig {
+	cfg := &SpanConfig{
+		Attributes: o.newAttrs(sc.Attributes()),
+		Links:      o.linksFrom(r, sc.Links()),
 		NewRoot:    sc.NewRoot(),
 		SpanKind:   sc.SpanKind(),
 	}
+	if len(cfg.Attributes) == 0 && len(cfg.Links) == 0 && !cfg.NewRoot && cfg.SpanKind == trace.SpanKindUnspecified {
+		return nil
+	}
+	return cfg
 }
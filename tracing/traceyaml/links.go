@@ -0,0 +1,66 @@
+package traceyaml
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanRegistry keeps track of all spans captured by a TracerProvider, such
+// that links to them can be resolved to a span path.
+type spanRegistry struct {
+	mu    sync.Mutex
+	spans map[trace.SpanID]*SpanInfo
+}
+
+func (r *spanRegistry) register(sc trace.SpanContext, si *SpanInfo) {
+	if !sc.IsValid() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.spans == nil {
+		r.spans = make(map[trace.SpanID]*SpanInfo)
+	}
+	r.spans[sc.SpanID()] = si
+}
+
+func (r *spanRegistry) lookup(sc trace.SpanContext) *SpanInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.spans[sc.SpanID()]
+}
+
+// path returns the path of span names from the root span to si, in the same
+// format as used by Diff.
+func (si *SpanInfo) path() string {
+	if si.parent == nil {
+		return spanPath("", si.SpanName)
+	}
+	return spanPath(si.parent.path(), si.SpanName)
+}
+
+func (o *Options) linkFrom(r *spanRegistry, link trace.Link) Link {
+	l := Link{Attributes: o.newAttrs(link.Attributes)}
+	if si := r.lookup(link.SpanContext); si != nil {
+		l.Span = si.path()
+	}
+	if o.IncludeIDs && link.SpanContext.IsValid() {
+		l.TraceID = link.SpanContext.TraceID().String()
+		l.SpanID = link.SpanContext.SpanID().String()
+	}
+	return l
+}
+
+func (o *Options) linksFrom(r *spanRegistry, links []trace.Link) []Link {
+	if len(links) == 0 {
+		return nil
+	}
+	result := make([]Link, 0, len(links))
+	for _, link := range links {
+		result = append(result, o.linkFrom(r, link))
+	}
+	return result
+}
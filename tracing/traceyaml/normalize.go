@@ -0,0 +1,43 @@
+package traceyaml
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// normalizeValue converts an attribute value to a canonical form, such that
+// values captured from spans, parsed from YAML or JSON, and given by the user
+// can be compared. Integers are converted to int64, floats to float64, and
+// arrays and slices to []interface{}.
+func normalizeValue(obj interface{}) interface{} {
+	if stringer, ok := obj.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	rv := reflect.ValueOf(obj)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		// JSON numbers are always parsed as float64
+		if f == float64(int64(f)) {
+			return int64(f)
+		}
+		return f
+	case reflect.Array, reflect.Slice:
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = normalizeValue(rv.Index(i).Interface())
+		}
+		return list
+	default:
+		return obj
+	}
+}
+
+// equalValues returns true if a and b are equal after normalization.
+func equalValues(a, b interface{}) bool {
+	return reflect.DeepEqual(normalizeValue(a), normalizeValue(b))
+}
@@ -0,0 +1,363 @@
+package traceyaml
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OutputFormat is the format SpanInfo is output in.
+type OutputFormat string
+
+const (
+	// FormatYAML outputs every root span as a YAML list item, preceded by a
+	// comment with the span name. This is the default.
+	FormatYAML OutputFormat = "yaml"
+	// FormatJSON outputs every root span as an indented JSON object, followed by
+	// a newline. The output is hence a stream of JSON objects.
+	FormatJSON OutputFormat = "json"
+)
+
+// WriteMode is when SpanInfo is written.
+type WriteMode string
+
+const (
+	// WriteOnEnd writes every root span as soon as it ends. This is the default.
+	// Root spans ended concurrently from multiple goroutines are written in the
+	// order End was called, and never interleave. Changes to child spans after
+	// their root span has ended are not captured.
+	WriteOnEnd WriteMode = ""
+	// WriteOnShutdown buffers all root spans, and writes them at once when the
+	// TracerProvider is shut down, ordered according to Options.RootOrder. This
+	// makes the output deterministic when root spans are ended concurrently,
+	// e.g. from multiple goroutines.
+	WriteOnShutdown WriteMode = "shutdown"
+)
+
+// SpanOrder is the order in which child spans are output.
+type SpanOrder string
+
+const (
+	// OrderByStart orders child spans in the order they were started. This is
+	// the default.
+	OrderByStart SpanOrder = ""
+	// OrderByName orders child spans by name. Child spans with the same name are
+	// ordered by their content. This makes the output deterministic when child
+	// spans are started concurrently, e.g. from multiple goroutines.
+	OrderByName SpanOrder = "name"
+	// OrderByStartTime orders spans by their start time, as given by
+	// trace.WithTimestamp or the current time when the span was started. Spans
+	// started at the same time keep their relative order. This makes nested
+	// traces read top-down, in the order the operations began.
+	OrderByStartTime SpanOrder = "startTime"
+)
+
+// ValueFormat is how attribute values are output.
+type ValueFormat string
+
+const (
+	// NativeValues outputs attribute values using their native types, e.g.
+	// booleans, integers, floats and lists. This is the default.
+	NativeValues ValueFormat = ""
+	// StringValues outputs all attribute values as strings, e.g. "true" and
+	// "1.5". This avoids golden file churn when the type of an attribute
+	// changes, e.g. from an integer to a float, but the value doesn't.
+	StringValues ValueFormat = "string"
+)
+
+// RedactedValue is the value that redacted attribute values are replaced with.
+const RedactedValue = "[REDACTED]"
+
+// Options customize what span data is captured by a TracerProvider returned
+// from NewWithOptions. The zero value captures all span data.
+//
+// Patterns use the syntax of path.Match, e.g. "*.path" matches both "file.path"
+// and "dir.path".
+type Options struct {
+	// Format is the output format. The default is FormatYAML.
+	Format OutputFormat
+	// WriteMode is when root spans are written. The default is WriteOnEnd.
+	WriteMode WriteMode
+	// ExcludeAttributes lists patterns of attribute keys that are not captured.
+	// This is useful for volatile attributes, like hostnames, which would
+	// otherwise make the output churn.
+	ExcludeAttributes []string
+	// RedactAttributes lists patterns of attribute keys whose values are
+	// replaced with RedactedValue, i.e. only the presence of the attribute is
+	// captured.
+	RedactAttributes []string
+	// ValueFormat is how attribute values are output. The default is
+	// NativeValues.
+	ValueFormat ValueFormat
+	// FlattenArrays outputs array attribute values as a single string, with the
+	// elements separated by commas, instead of as a list.
+	FlattenArrays bool
+	// DropEvents lists patterns of event names that are not captured.
+	DropEvents []string
+	// Duration, if set, records the duration of each span in SpanInfo.Duration,
+	// formatted using this function. Durations are computed from the timestamps
+	// given in the start and end options, defaulting to the current time.
+	Duration DurationFunc
+	// IncludeIDs records the trace, span and parent span IDs of each span, such
+	// that parent/child linkage and new root spans can be verified explicitly.
+	// Only set this if the IDs are deterministic, e.g. using
+	// tracing.TracerProviderBuilder.DeterministicIDs, as the output otherwise
+	// changes every time.
+	IncludeIDs bool
+	// ChildOrder is the order in which child spans are output. The default is
+	// OrderByStart. Attributes are always ordered by key.
+	ChildOrder SpanOrder
+	// RootOrder is the order in which root spans are written if WriteMode is
+	// WriteOnShutdown. The default is OrderByName; OrderByStartTime orders the
+	// root spans by start time instead of by name. Root spans with the same
+	// start time are ordered by name.
+	RootOrder SpanOrder
+	// CaptureLogs records the log lines emitted through the Logger of a span,
+	// as returned from tracing.TracerBuilder.Trace, in SpanInfo.Logs. This
+	// gives a single golden file for both logs and traces. Log fields are
+	// filtered like attributes.
+	CaptureLogs bool
+	// MaxDepth, if non-zero, is the maximum number of levels of child spans
+	// captured below a root span. MaxChildren, if non-zero, is the maximum
+	// number of child spans captured per span. Child spans beyond these limits
+	// are left out, and counted in SpanInfo.TruncatedChildren of their parent.
+	// This keeps the output of very deep or wide traces reviewable.
+	MaxDepth    int
+	MaxChildren int
+	// AttributeBudget and EventBudget, if non-zero, are the maximum number of
+	// attributes and events a span should have. Spans exceeding them are
+	// marked with a warning in SpanInfo.Warnings, which helps catching
+	// over-instrumentation when reviewing golden files. Attributes given in
+	// the start and end options count towards the attribute budget, and errors
+	// towards the event budget.
+	AttributeBudget int
+	EventBudget     int
+	// Transforms are applied in order to every root span before it is
+	// recorded and written. See SpanInfoTransform.
+	Transforms []SpanInfoTransform
+	// Recorder, if set, records the SpanInfo trees of all root spans as they
+	// end, in addition to writing them.
+	Recorder *Recorder
+}
+
+// SpanInfoTransform post-processes a root span and its child spans, e.g. to
+// apply project-specific normalization like renaming spans or collapsing
+// retries, before it is recorded and written. The transform may modify si in
+// place and return it, or return a new SpanInfo. If nil is returned, the root
+// span is dropped. Child spans are reachable through si.Children.
+//
+// Transforms are applied before child spans are ordered and truncated.
+type SpanInfoTransform func(si *SpanInfo) *SpanInfo
+
+// transform applies o.Transforms to si.
+func (o *Options) transform(si *SpanInfo) *SpanInfo {
+	for _, fn := range o.Transforms {
+		if si = fn(si); si == nil {
+			return nil
+		}
+	}
+	return si
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		// Invalid patterns never match; just like path.Match
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Options) attrsInto(attrList []attribute.KeyValue, attrMap Attributes) {
+	for _, attr := range attrList {
+		key := string(attr.Key)
+		switch {
+		case matchAny(o.ExcludeAttributes, key):
+			continue
+		case matchAny(o.RedactAttributes, key):
+			attrMap[key] = RedactedValue
+		default:
+			attrMap[key] = o.attrValue(attr.Value)
+		}
+	}
+}
+
+// attrValue converts v according to o.ValueFormat and o.FlattenArrays.
+func (o *Options) attrValue(v attribute.Value) interface{} {
+	obj := v.AsInterface()
+	if v.Type() != attribute.ARRAY {
+		return o.scalarValue(obj)
+	}
+	if o.ValueFormat == NativeValues && !o.FlattenArrays {
+		return obj
+	}
+
+	rv := reflect.ValueOf(obj)
+	list := make([]interface{}, rv.Len())
+	strs := make([]string, rv.Len())
+	for i := range list {
+		list[i] = o.scalarValue(rv.Index(i).Interface())
+		strs[i] = fmt.Sprint(list[i])
+	}
+	if o.FlattenArrays {
+		return strings.Join(strs, ",")
+	}
+	return list
+}
+
+func (o *Options) scalarValue(obj interface{}) interface{} {
+	if o.ValueFormat == StringValues {
+		return fmt.Sprint(obj)
+	}
+	return obj
+}
+
+func (o *Options) dropEvent(name string) bool {
+	return matchAny(o.DropEvents, name)
+}
+
+// DurationFunc formats the duration of a span for SpanInfo.Duration. For the
+// output to be deterministic, the duration should be normalized, as done by
+// BucketDuration and FixedDuration.
+type DurationFunc func(d time.Duration) string
+
+// BucketDuration returns a DurationFunc that formats a duration as the bucket
+// it belongs to, as delimited by the given ascending bounds. For example, with
+// bounds 1ms and 10ms, the buckets are "<1ms", "1ms-10ms" and ">=10ms". This
+// allows asserting rough timing behavior deterministically.
+func BucketDuration(bounds ...time.Duration) DurationFunc {
+	return func(d time.Duration) string {
+		for i, bound := range bounds {
+			if d >= bound {
+				continue
+			}
+			if i == 0 {
+				return "<" + bound.String()
+			}
+			return bounds[i-1].String() + "-" + bound.String()
+		}
+		if len(bounds) == 0 {
+			return ""
+		}
+		return ">=" + bounds[len(bounds)-1].String()
+	}
+}
+
+// FixedDuration returns a DurationFunc that formats every duration as the
+// fake value d. This records that the span has ended, without making the output
+// depend on timing.
+func FixedDuration(d time.Duration) DurationFunc {
+	return func(time.Duration) string { return d.String() }
+}
+
+// sortChildren recursively orders the child spans of si according to
+// o.ChildOrder.
+func (o *Options) sortChildren(si *SpanInfo) {
+	if o.ChildOrder == OrderByStart {
+		return
+	}
+	for _, child := range si.Children {
+		o.sortChildren(child)
+	}
+	switch o.ChildOrder {
+	case OrderByName:
+		sortByName(si.Children)
+	case OrderByStartTime:
+		sortByStartTime(si.Children)
+	}
+}
+
+// sortRoots orders the root spans written at Shutdown according to
+// o.RootOrder.
+func (o *Options) sortRoots(spans []*SpanInfo) {
+	sortByName(spans)
+	if o.RootOrder == OrderByStartTime {
+		sortByStartTime(spans)
+	}
+}
+
+// sortByStartTime orders spans by start time, keeping the relative order of
+// spans started at the same time.
+func sortByStartTime(spans []*SpanInfo) {
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].startTime.Before(spans[j].startTime)
+	})
+}
+
+// truncate recursively leaves out the child spans of si that are beyond
+// o.MaxDepth or o.MaxChildren. depth is the level of si below its root span.
+func (o *Options) truncate(si *SpanInfo, depth int) {
+	keep := len(si.Children)
+	if o.MaxDepth != 0 && depth >= o.MaxDepth {
+		keep = 0
+	} else if o.MaxChildren != 0 && keep > o.MaxChildren {
+		keep = o.MaxChildren
+	}
+	si.TruncatedChildren += len(si.Children) - keep
+	si.Children = si.Children[:keep]
+	if keep == 0 {
+		si.Children = nil
+	}
+
+	for _, child := range si.Children {
+		o.truncate(child, depth+1)
+	}
+}
+
+// checkBudgets recursively marks si and its child spans with warnings if they
+// exceed o.AttributeBudget or o.EventBudget.
+func (o *Options) checkBudgets(si *SpanInfo) {
+	if o.AttributeBudget == 0 && o.EventBudget == 0 {
+		return
+	}
+	attrs := len(si.Attributes)
+	for _, cfg := range []*SpanConfig{si.StartConfig, si.EndConfig} {
+		if cfg != nil {
+			attrs += len(cfg.Attributes)
+		}
+	}
+	if o.AttributeBudget != 0 && attrs > o.AttributeBudget {
+		si.Warnings = append(si.Warnings,
+			fmt.Sprintf("%d attributes exceed the budget of %d", attrs, o.AttributeBudget))
+	}
+	events := len(si.Events) + len(si.Errors)
+	if o.EventBudget != 0 && events > o.EventBudget {
+		si.Warnings = append(si.Warnings,
+			fmt.Sprintf("%d events exceed the budget of %d", events, o.EventBudget))
+	}
+
+	for _, child := range si.Children {
+		o.checkBudgets(child)
+	}
+}
+
+// sortByName orders spans by name, and spans with the same name by content.
+func sortByName(spans []*SpanInfo) {
+	sort.SliceStable(spans, func(i, j int) bool {
+		a, b := spans[i], spans[j]
+		if a.SpanName != b.SpanName {
+			return a.SpanName < b.SpanName
+		}
+		return compact(a) < compact(b)
+	})
+}
+
+// setIDs sets the IDs of si if o.IncludeIDs is set, and the span context sc
+// is valid. parent is the span context of the parent span, if any.
+func (o *Options) setIDs(si *SpanInfo, sc, parent trace.SpanContext) {
+	if !o.IncludeIDs || !sc.IsValid() {
+		return
+	}
+	si.TraceID = sc.TraceID().String()
+	si.SpanID = sc.SpanID().String()
+	if parent.IsValid() {
+		si.ParentID = parent.SpanID().String()
+	}
+}
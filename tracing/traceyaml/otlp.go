@@ -0,0 +1,332 @@
+package traceyaml
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	// OTLPServiceName is the service name registered for spans exported using
+	// WriteOTLPJSON.
+	OTLPServiceName = "traceyaml"
+	// OTLPInstrumentationName is the name of the instrumentation scope
+	// registered for spans exported using WriteOTLPJSON.
+	OTLPInstrumentationName = "github.com/luxas/deklarative/tracing/traceyaml"
+)
+
+// otlpBaseTime is the start time of root spans whose timing is unknown, e.g.
+// spans loaded using Parse. It's the same as the start of the
+// tracing.DeterministicClock.
+//
+//nolint:gochecknoglobals
+var otlpBaseTime = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// WriteOTLPJSON writes the span trees as an OpenTelemetry protocol (OTLP)
+// JSON document to w, such that e.g. a failing test's trace can be loaded
+// into Jaeger or Grafana Tempo for visual debugging.
+//
+// Spans captured by a TracerProvider returned from New keep their trace and
+// span IDs if Options.IncludeIDs is set, and their start and end times. Spans
+// loaded using Parse don't know about timing; they are instead laid out one
+// microsecond apart in the order they were started, such that they nest
+// properly. Spans without IDs get deterministic IDs.
+//
+// Errors are exported as "exception" events, and the last status change as
+// the span status.
+func WriteOTLPJSON(w io.Writer, spans []*SpanInfo) error {
+	c := &otlpConverter{
+		traceIDs: make(map[*SpanInfo]string),
+		spanIDs:  make(map[*SpanInfo]string),
+		paths:    make(map[string]*SpanInfo),
+	}
+	// Assign all IDs first, such that links to later spans can be resolved
+	for _, si := range spans {
+		c.assignIDs(si, "")
+	}
+
+	scope := otlpScopeSpans{Scope: otlpScope{Name: OTLPInstrumentationName}}
+	for _, si := range spans {
+		c.tick = otlpBaseTime
+		scope.Spans = c.convert(scope.Spans, si, "", "")
+	}
+
+	doc := otlpTracesData{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpKeyValue{
+			{Key: "service.name", Value: otlpValue(OTLPServiceName)},
+		}},
+		ScopeSpans: []otlpScopeSpans{scope},
+	}}}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// The otlp* types mirror the OTLP JSON encoding, as specified in
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/docs/specification.md#json-protobuf-encoding.
+// Trace and span IDs are hex-encoded, and 64-bit integers are strings.
+
+type otlpTracesData struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Links             []otlpLink     `json:"links,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpLink struct {
+	TraceID    string         `json:"traceId"`
+	SpanID     string         `json:"spanId"`
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+// otlpConverter converts SpanInfo trees to OTLP spans.
+type otlpConverter struct {
+	// traceIDs and spanIDs are the IDs assigned to spans, generated or not.
+	traceIDs map[*SpanInfo]string
+	spanIDs  map[*SpanInfo]string
+	// paths maps span paths, as used by Link.Span, to the span.
+	paths map[string]*SpanInfo
+	// lastID is the last generated ID.
+	lastID uint64
+	// tick is the current time for spans whose timing is unknown.
+	tick time.Time
+}
+
+func (c *otlpConverter) generateID(size int) string {
+	c.lastID++
+	id := make([]byte, size)
+	binary.BigEndian.PutUint64(id[size-8:], c.lastID)
+	return fmt.Sprintf("%x", id)
+}
+
+func (c *otlpConverter) assignIDs(si *SpanInfo, path string) {
+	path = spanPath(path, si.SpanName)
+	c.paths[path] = si
+
+	spanID := si.SpanID
+	if len(spanID) == 0 {
+		spanID = c.generateID(8)
+	}
+	c.spanIDs[si] = spanID
+
+	for _, child := range si.Children {
+		c.assignIDs(child, path)
+	}
+}
+
+// convert appends si and its child spans to spans. traceID and parentID are
+// the trace and span IDs of the parent span, if any.
+func (c *otlpConverter) convert(spans []otlpSpan, si *SpanInfo, traceID, parentID string) []otlpSpan {
+	switch {
+	case len(si.TraceID) != 0:
+		traceID = si.TraceID
+	case len(traceID) == 0:
+		traceID = c.generateID(16)
+	}
+	c.traceIDs[si] = traceID
+	if len(si.ParentID) != 0 {
+		parentID = si.ParentID
+	}
+
+	span := otlpSpan{
+		TraceID:      traceID,
+		SpanID:       c.spanIDs[si],
+		ParentSpanID: parentID,
+		Name:         si.SpanName,
+	}
+	if n := len(si.NameChanges); n != 0 {
+		span.Name = si.NameChanges[n-1]
+	}
+
+	attrs := Attributes{}
+	for _, cfg := range []*SpanConfig{si.StartConfig, si.EndConfig} {
+		if cfg == nil {
+			continue
+		}
+		for key, val := range cfg.Attributes {
+			attrs[key] = val
+		}
+		if cfg.SpanKind != 0 {
+			span.Kind = int(cfg.SpanKind)
+		}
+		span.Links = append(span.Links, c.links(cfg.Links)...)
+	}
+	for key, val := range si.Attributes {
+		attrs[key] = val
+	}
+	span.Attributes = otlpAttributes(attrs)
+	span.Links = append(span.Links, c.links(si.Links)...)
+
+	if n := len(si.StatusChanges); n != 0 {
+		span.Status = otlpStatusFrom(si.StatusChanges[n-1])
+	}
+
+	start, end := si.startTime, si.endTime
+	if start.IsZero() {
+		start = c.nextTick()
+	}
+	for _, ev := range si.Events {
+		span.Events = append(span.Events, otlpEvent{
+			TimeUnixNano: otlpTime(start),
+			Name:         ev.Name,
+			Attributes:   otlpAttributes(ev.Attributes),
+		})
+	}
+	for _, e := range si.Errors {
+		attrs := Attributes{"exception.message": e.Error}
+		for key, val := range e.Attributes {
+			attrs[key] = val
+		}
+		span.Events = append(span.Events, otlpEvent{
+			TimeUnixNano: otlpTime(start),
+			Name:         "exception",
+			Attributes:   otlpAttributes(attrs),
+		})
+	}
+
+	// Reserve the position of the span, such that it's ordered before its
+	// children
+	i := len(spans)
+	spans = append(spans, otlpSpan{})
+	for _, child := range si.Children {
+		spans = c.convert(spans, child, traceID, span.SpanID)
+	}
+
+	if end.IsZero() {
+		end = start
+		if si.startTime.IsZero() {
+			end = c.nextTick()
+		}
+	}
+	span.StartTimeUnixNano = otlpTime(start)
+	span.EndTimeUnixNano = otlpTime(end)
+	spans[i] = span
+	return spans
+}
+
+func (c *otlpConverter) nextTick() time.Time {
+	c.tick = c.tick.Add(time.Microsecond)
+	return c.tick
+}
+
+// links converts links that can be resolved to a trace and span ID.
+func (c *otlpConverter) links(links []Link) []otlpLink {
+	var result []otlpLink
+	for _, l := range links {
+		traceID, spanID := l.TraceID, l.SpanID
+		if si, ok := c.paths[l.Span]; ok && len(spanID) == 0 {
+			traceID, spanID = c.traceIDs[si], c.spanIDs[si]
+		}
+		// Links to spans in later traces have no trace ID yet
+		if len(traceID) == 0 || len(spanID) == 0 {
+			continue
+		}
+		result = append(result, otlpLink{
+			TraceID:    traceID,
+			SpanID:     spanID,
+			Attributes: otlpAttributes(l.Attributes),
+		})
+	}
+	return result
+}
+
+func otlpStatusFrom(s Status) otlpStatus {
+	// The OTLP status codes are ordered differently from codes.Code
+	switch s.Code {
+	case codes.Ok:
+		return otlpStatus{Code: 1}
+	case codes.Error:
+		return otlpStatus{Code: 2, Message: s.Description}
+	default:
+		return otlpStatus{}
+	}
+}
+
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func otlpAttributes(attrs Attributes) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	kvs := make([]otlpKeyValue, 0, len(attrs))
+	for key, val := range attrs {
+		kvs = append(kvs, otlpKeyValue{Key: key, Value: otlpValue(val)})
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs
+}
+
+// otlpValue converts an attribute value to an OTLP AnyValue.
+func otlpValue(obj interface{}) map[string]interface{} {
+	switch v := normalizeValue(obj).(type) {
+	case string:
+		return map[string]interface{}{"stringValue": v}
+	case bool:
+		return map[string]interface{}{"boolValue": v}
+	case int64:
+		return map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return map[string]interface{}{"doubleValue": v}
+	case []interface{}:
+		values := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			values = append(values, otlpValue(item))
+		}
+		return map[string]interface{}{"arrayValue": map[string]interface{}{"values": values}}
+	default:
+		return map[string]interface{}{"stringValue": fmt.Sprintf("%v", v)}
+	}
+}
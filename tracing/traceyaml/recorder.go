@@ -0,0 +1,41 @@
+package traceyaml
+
+import "sync"
+
+// Recorder records the SpanInfo trees of all root spans as they end, such that
+// they can be inspected programmatically, e.g. using Expect. Set
+// Options.Recorder for a TracerProvider to record to it. A Recorder is safe for
+// concurrent use.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*SpanInfo
+}
+
+// Spans returns the SpanInfo trees of the root spans that have ended so far,
+// in the order they ended.
+func (r *Recorder) Spans() []*SpanInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	spans := make([]*SpanInfo, len(r.spans))
+	copy(spans, r.spans)
+	return spans
+}
+
+// Reset forgets all recorded spans.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.spans = nil
+}
+
+func (r *Recorder) record(si *SpanInfo) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.spans = append(r.spans, si)
+}
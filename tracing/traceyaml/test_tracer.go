@@ -1,5 +1,7 @@
 // Package traceyaml provides a means to unit test a trace flow, using a YAML file
 // structure that is representative and as close to human-readable as it gets.
+// When such a golden YAML file doesn't match, Diff describes the difference per
+// span.
 //
 // This package is tested by unit tests in the above tracing package.
 package traceyaml
@@ -7,8 +9,10 @@ package traceyaml
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"sync"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -29,8 +33,29 @@ import (
 //
 // 	# Trace2
 //	- {Trace2 data}
+//
+// Options.Format can be set to FormatJSON for the same data to be output as JSON
+// instead, and Options.WriteMode to WriteOnShutdown for all root spans to be
+// output ordered when the TracerProvider is shut down; see NewWithOptions.
 func New(tp trace.TracerProvider, w io.Writer) trace.TracerProvider {
-	return &testTracerProvider{tp, zapcore.Lock(zapcore.AddSync(w))}
+	return NewWithOptions(tp, w, Options{})
+}
+
+// NewWithOptions is like New, but customizes what span data is captured using
+// opts.
+//
+// If w is nil, the output is discarded; this is useful together with
+// Options.Recorder.
+func NewWithOptions(tp trace.TracerProvider, w io.Writer, opts Options) trace.TracerProvider {
+	if w == nil {
+		w = io.Discard
+	}
+	return &testTracerProvider{
+		TracerProvider: tp,
+		ws:             zapcore.Lock(zapcore.AddSync(w)),
+		opts:           opts,
+		spans:          &spanRegistry{},
+	}
 }
 
 type testTracerProvider struct {
@@ -38,7 +63,80 @@ type testTracerProvider struct {
 	// underlying resource.
 	trace.TracerProvider
 	// ws is a race-free writer
-	ws zapcore.WriteSyncer
+	ws   zapcore.WriteSyncer
+	opts Options
+	// spans resolves links to captured spans
+	spans *spanRegistry
+
+	// endMu serializes the ending of root spans, such that they're written
+	// in a total order.
+	endMu sync.Mutex
+
+	// buffered holds the root spans to write at Shutdown, if
+	// opts.WriteMode is WriteOnShutdown.
+	bufferedMu sync.Mutex
+	buffered   []*SpanInfo
+}
+
+// Shutdown writes any buffered root spans, and shuts down the underlying
+// TracerProvider, if it supports it.
+func (tp *testTracerProvider) Shutdown(ctx context.Context) error {
+	tp.bufferedMu.Lock()
+	spans := tp.buffered
+	tp.buffered = nil
+	tp.bufferedMu.Unlock()
+
+	tp.opts.sortRoots(spans)
+	var errs []error
+	for _, si := range spans {
+		errs = append(errs, tp.write(si))
+	}
+
+	if shutdownable, ok := tp.TracerProvider.(interface {
+		Shutdown(ctx context.Context) error
+	}); ok {
+		errs = append(errs, shutdownable.Shutdown(ctx))
+	}
+	return multierr.Combine(errs...)
+}
+
+// endRoot records and writes, or buffers, a snapshot of the root span si.
+// si.mu must be held by the caller.
+func (tp *testTracerProvider) endRoot(si *SpanInfo) error {
+	tp.endMu.Lock()
+	defer tp.endMu.Unlock()
+
+	// Child spans might still be modified from other goroutines after the
+	// root span has ended; hence, only a snapshot is processed further.
+	if si = tp.opts.transform(si.snapshot()); si == nil {
+		return nil
+	}
+	tp.opts.checkBudgets(si)
+	tp.opts.sortChildren(si)
+	tp.opts.truncate(si, 0)
+	tp.opts.Recorder.record(si)
+	if tp.opts.WriteMode == WriteOnShutdown {
+		tp.buffer(si)
+		return nil
+	}
+	return tp.write(si)
+}
+
+// write marshals and writes the root span si to the underlying writer.
+func (tp *testTracerProvider) write(si *SpanInfo) error {
+	out, err := tp.opts.marshal(si)
+	if err != nil {
+		return err
+	}
+	return writeNoLength(tp.ws, out)
+}
+
+// buffer stores the root span si until Shutdown.
+func (tp *testTracerProvider) buffer(si *SpanInfo) {
+	tp.bufferedMu.Lock()
+	defer tp.bufferedMu.Unlock()
+
+	tp.buffered = append(tp.buffered, si)
 }
 
 func (tp *testTracerProvider) Tracer(instrumentationName string, opts ...trace.TracerOption) trace.Tracer {
@@ -55,16 +153,21 @@ type testTracer struct {
 }
 
 func (t *testTracer) Start(ctx context.Context, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	var parentSC trace.SpanContext
+	if !cfg.NewRoot() {
+		parentSC = trace.SpanContextFromContext(ctx)
+	}
+
 	ctx, span := t.Tracer.Start(ctx, spanName, opts...)
 	newSpan := &testSpan{span, t.provider, nil}
 
-	cfg := trace.NewSpanStartConfig(opts...)
-
+	newSpan.data = t.provider.opts.newSpanInfo(t.provider.spans, spanName, opts...)
+	t.provider.opts.setIDs(newSpan.data, span.SpanContext(), parentSC)
 	if parentData := getSpanInfo(ctx); parentData != nil && !cfg.NewRoot() {
-		newSpan.data = parentData.newChild(spanName, opts...)
-	} else {
-		newSpan.data = newSpanInfo(spanName, opts...)
+		parentData.addChild(newSpan.data)
 	}
+	t.provider.spans.register(span.SpanContext(), newSpan.data)
 	ctx = withSpanInfo(ctx, newSpan.data)
 
 	return trace.ContextWithSpan(ctx, newSpan), newSpan
@@ -83,20 +186,12 @@ func (s *testSpan) End(options ...trace.SpanEndOption) {
 	s.data.mu.Lock()
 	defer s.data.mu.Unlock()
 
-	s.data.EndConfig = spanConfigFromEnd(options...)
+	s.data.EndConfig = s.provider.opts.spanConfigFromEnd(s.provider.spans, options...)
+	s.data.endTime = timestampOrNow(trace.NewSpanEndConfig(options...).Timestamp())
+	s.data.Duration = s.provider.opts.durationFrom(s.data)
 
 	if !s.data.isChild {
-		listItem := []*SpanInfo{s.data}
-		// Deliberately use yaml.v2 here as it marshals lists on the same
-		// indentation level as the list key.
-		// TODO: When "our own" YAML library is ready, use that.
-		out, err := yaml.Marshal(listItem)
-		if err == nil {
-			header := fmt.Sprintf("# %s", s.data.SpanName)
-			out = bytes.Join([][]byte{[]byte(header), out, nil}, []byte{'\n'})
-			err = multierr.Combine(err, writeNoLength(s.provider.ws, out))
-		}
-		if err != nil {
+		if err := s.provider.endRoot(s.data); err != nil {
 			s.Span.RecordError(err)
 		}
 	}
@@ -104,6 +199,30 @@ func (s *testSpan) End(options ...trace.SpanEndOption) {
 	s.Span.End(options...)
 }
 
+// marshal marshals the root span in the configured output format.
+func (o *Options) marshal(si *SpanInfo) ([]byte, error) {
+	if o.Format == FormatJSON {
+		out, err := json.MarshalIndent(si, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(out, '\n'), nil
+	}
+
+	listItem := []*SpanInfo{si}
+	// Deliberately use yaml.v2 here as it marshals lists on the same
+	// indentation level as the list key.
+	// TODO: When "our own" YAML library is ready, use that. The
+	// github.com/luxas/deklarative/yaml module is not yet available to this
+	// module, so yaml.v2 is used both here and in Parse until it is.
+	out, err := yaml.Marshal(listItem)
+	if err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf("# %s", si.SpanName)
+	return bytes.Join([][]byte{[]byte(header), out, nil}, []byte{'\n'}), nil
+}
+
 func writeNoLength(w io.Writer, p []byte) error {
 	_, err := w.Write(p)
 	return err
@@ -113,21 +232,59 @@ func (s *testSpan) AddEvent(name string, options ...trace.EventOption) {
 	s.data.mu.Lock()
 	defer s.data.mu.Unlock()
 
-	s.data.Events = append(s.data.Events, Event{
-		Name:        name,
-		EventConfig: eventConfigFrom(options...),
-	})
+	if !s.provider.opts.dropEvent(name) {
+		s.data.Events = append(s.data.Events, Event{
+			Name:        name,
+			EventConfig: s.provider.opts.eventConfigFrom(options...),
+		})
+	}
 
 	s.Span.AddEvent(name, options...)
 }
 
+// AddLink links the span to another span after it was started. The link is
+// captured in SpanInfo.Links, and passed on to the underlying span if it
+// supports adding links.
+func (s *testSpan) AddLink(link trace.Link) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	s.data.Links = append(s.data.Links, s.provider.opts.linkFrom(s.provider.spans, link))
+
+	// The interface is the same as tracing.LinkAdder, which can't be
+	// referenced as the tracing package imports this package.
+	if linker, ok := s.Span.(interface{ AddLink(trace.Link) }); ok {
+		linker.AddLink(link)
+	}
+}
+
+// RecordLog captures a log line emitted through the Logger of the span, if
+// Options.CaptureLogs is set. err is nil for non-error log lines.
+func (s *testSpan) RecordLog(level int, msg string, err error, fields ...attribute.KeyValue) {
+	if !s.provider.opts.CaptureLogs {
+		return
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	l := Log{
+		Message: msg,
+		Level:   level,
+		Fields:  s.provider.opts.newAttrs(fields),
+	}
+	if err != nil {
+		l.Error = err.Error()
+	}
+	s.data.Logs = append(s.data.Logs, l)
+}
+
 func (s *testSpan) RecordError(err error, options ...trace.EventOption) {
 	s.data.mu.Lock()
 	defer s.data.mu.Unlock()
 
 	s.data.Errors = append(s.data.Errors, Error{
 		Error:       fmt.Sprintf("%v", err),
-		EventConfig: eventConfigFrom(options...),
+		EventConfig: s.provider.opts.eventConfigFrom(options...),
 	})
 
 	s.Span.RecordError(err, options...)
@@ -161,7 +318,7 @@ func (s *testSpan) SetAttributes(kv ...attribute.KeyValue) {
 	s.data.mu.Lock()
 	defer s.data.mu.Unlock()
 
-	attrsInto(kv, s.data.Attributes)
+	s.provider.opts.attrsInto(kv, s.data.Attributes)
 	s.Span.SetAttributes(kv...)
 }
 
@@ -1,7 +1,10 @@
 package traceyaml
 
 import (
+	"encoding/json"
+	"strconv"
 	"sync"
+	"time"
 
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
@@ -13,10 +16,23 @@ import (
 // to JSON and/or YAML easily.
 type SpanInfo struct {
 	SpanName string `json:"spanName" yaml:"spanName"`
+	// Duration is only set if Options.Duration is set.
+	Duration string `json:"duration,omitempty" yaml:"duration,omitempty"`
+
+	// TraceID, SpanID and ParentID are only set if Options.IncludeIDs is set.
+	// ParentID is empty for root spans.
+	TraceID  string `json:"traceID,omitempty" yaml:"traceID,omitempty"`
+	SpanID   string `json:"spanID,omitempty" yaml:"spanID,omitempty"`
+	ParentID string `json:"parentID,omitempty" yaml:"parentID,omitempty"`
 
 	Attributes Attributes `json:"attributes,omitempty" yaml:"attributes,omitempty"`
 	Errors     []Error    `json:"errors,omitempty" yaml:"errors,omitempty"`
 	Events     []Event    `json:"events,omitempty" yaml:"events,omitempty"`
+	// Logs are only captured if Options.CaptureLogs is set.
+	Logs []Log `json:"logs,omitempty" yaml:"logs,omitempty"`
+	// Links are the links added using AddLink after the span was started.
+	// Links given when starting the span are part of StartConfig.
+	Links []Link `json:"links,omitempty" yaml:"links,omitempty"`
 
 	StartConfig *SpanConfig `json:"startConfig,omitempty" yaml:"startConfig,omitempty"`
 	EndConfig   *SpanConfig `json:"endConfig,omitempty" yaml:"endConfig,omitempty"`
@@ -24,9 +40,20 @@ type SpanInfo struct {
 	StatusChanges []Status `json:"statusChanges,omitempty" yaml:"statusChanges,omitempty"`
 	NameChanges   []string `json:"nameChanges,omitempty" yaml:"nameChanges,omitempty"`
 
+	// Warnings are only set if the span exceeds Options.AttributeBudget or
+	// Options.EventBudget.
+	Warnings []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+
 	Children []*SpanInfo `json:"children,omitempty" yaml:"children,omitempty"`
-	mu       *sync.Mutex
-	isChild  bool
+	// TruncatedChildren is the number of child spans that were left out due to
+	// Options.MaxDepth or Options.MaxChildren.
+	TruncatedChildren int `json:"truncatedChildren,omitempty" yaml:"truncatedChildren,omitempty"`
+
+	mu        *sync.Mutex
+	isChild   bool
+	parent    *SpanInfo
+	startTime time.Time
+	endTime   time.Time
 }
 
 // Event represents an event registered using span.AddEvent().
@@ -41,25 +68,99 @@ type Error struct {
 	EventConfig `json:",inline,omitempty" yaml:",inline,omitempty"`
 }
 
+// Log represents a log line emitted through the Logger of a span, as returned
+// from tracing.TracerBuilder.Trace.
+type Log struct {
+	Message string `json:"message" yaml:"message"`
+	// Level is the logr verbosity level of the Logger.
+	Level int `json:"level,omitempty" yaml:"level,omitempty"`
+	// Error is set for error log lines.
+	Error  string     `json:"error,omitempty" yaml:"error,omitempty"`
+	Fields Attributes `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
 // EventConfig is created from []trace.EventOption.
 type EventConfig struct {
 	Attributes Attributes `json:"attributes,omitempty" yaml:"attributes,omitempty"`
 }
 
 // Status represents a status update registered using s.Span.SetStatus().
+// The code is serialized by name, i.e. "Unset", "Error" or "Ok", such that
+// golden files are self-describing. Numeric codes are accepted when parsing.
 type Status struct {
 	Code        codes.Code `json:"code" yaml:"code"`
 	Description string     `json:"description,omitempty" yaml:"description,omitempty"`
 }
 
+// namedStatus is the serialized form of Status.
+type namedStatus struct {
+	Code        string `json:"code" yaml:"code"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(namedStatus{s.Code.String(), s.Description})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Status) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Code        codes.Code `json:"code"`
+		Description string     `json:"description,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*s = Status(raw)
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (s Status) MarshalYAML() (interface{}, error) {
+	return namedStatus{s.Code.String(), s.Description}, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (s *Status) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw namedStatus
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	// codes.Code only knows how to unmarshal JSON, where names are quoted
+	code := []byte(raw.Code)
+	if _, err := strconv.Atoi(raw.Code); err != nil {
+		code = []byte(strconv.Quote(raw.Code))
+	}
+	if err := s.Code.UnmarshalJSON(code); err != nil {
+		return err
+	}
+	s.Description = raw.Description
+	return nil
+}
+
 // SpanConfig is created from []trace.SpanStartOption or []trace.SpanEndOption.
 type SpanConfig struct {
 	Attributes Attributes     `json:"attributes,omitempty" yaml:"attributes,omitempty"`
-	Links      []trace.Link   `json:"links,omitempty" yaml:"links,omitempty"`
+	Links      []Link         `json:"links,omitempty" yaml:"links,omitempty"`
 	NewRoot    bool           `json:"newRoot,omitempty" yaml:"newRoot,omitempty"`
 	SpanKind   trace.SpanKind `json:"spanKind,omitempty" yaml:"spanKind,omitempty"`
 }
 
+// Link represents a link to another span, registered either using
+// trace.WithLinks when starting the span, or using AddLink afterwards.
+type Link struct {
+	// Span references the linked span using the path of span names from its
+	// root span, in the same format as used by Diff, e.g. "parent" > "child".
+	// Span is empty if the linked span was not captured by the same
+	// TracerProvider.
+	Span string `json:"span,omitempty" yaml:"span,omitempty"`
+	// TraceID and SpanID are only set if Options.IncludeIDs is set.
+	TraceID    string     `json:"traceID,omitempty" yaml:"traceID,omitempty"`
+	SpanID     string     `json:"spanID,omitempty" yaml:"spanID,omitempty"`
+	Attributes Attributes `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
 // Attributes is a map between an attribute key and value, as defined by
 // OpenTelemetry. If the same key is added twice, the latter value is persisted.
 type Attributes map[string]interface{}
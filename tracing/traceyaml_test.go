@@ -0,0 +1,939 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luxas/deklarative/tracing/traceyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceYAMLDiff(t *testing.T) {
+	expected, err := os.ReadFile("testdata/TestTracer/TraceUptoLogger1.yaml")
+	require.Nil(t, err)
+
+	actual := `# worker.doWork
+- spanName: worker.doWork
+  attributes:
+    log-attr-hello: -1.2
+    log-attr-op-result: -2
+    new: true
+  errors:
+  - error: 'some operation failed: unexpected thing happened'
+  startConfig:
+    attributes:
+      hello: true
+  statusChanges:
+  - code: Ok
+  nameChanges:
+  - foo
+  children:
+  - spanName: someOperationPre
+    attributes:
+      arr:
+      - foo
+      - bar
+    events:
+    - name: extra
+    statusChanges:
+    - code: Error
+      description: this will be visible
+  - spanName: unknown
+  - spanName: errorOperator
+    events:
+    - name: SomeOperationError
+    statusChanges:
+    - code: Ok
+    nameChanges:
+    - newname
+
+# errorOperator
+- spanName: errorOperator
+  events:
+  - name: SomeOperationError
+  statusChanges:
+  - code: Ok
+  nameChanges:
+  - newname
+
+# afterShutdown
+- spanName: afterShutdown
+`
+	assert.Equal(t, `"worker.doWork": attribute "log-attr-op-result": expected -1, got -2
+"worker.doWork": attribute "new": unexpected, got true
+"worker.doWork": attribute "result": missing, expected "result"
+"worker.doWork" > "someOperationPre": unexpected event "extra"
+unexpected span "worker.doWork" > "unknown"
+missing span "someOperationPre"
+`, traceyaml.Diff(actual, string(expected)))
+
+	// Equal spans only differing in comments fall back to a text diff
+	assert.Equal(t, `--- Expected
++++ Actual
+@@ -1,2 +1,2 @@
+-# foo
++# bar
+ - spanName: foo
+`, traceyaml.Diff("# bar\n- spanName: foo\n", "# foo\n- spanName: foo\n"))
+
+	// Content that isn't trace YAML falls back to a text diff
+	assert.Equal(t, `--- Expected
++++ Actual
+@@ -1 +1 @@
+-foo
++bar
+`, traceyaml.Diff("bar", "foo"))
+
+	// The trailing newline doesn't add an empty context line
+	assert.Equal(t, `--- Expected
++++ Actual
+@@ -2,2 +2,2 @@
+ b
+-C
++c
+`, traceyaml.Diff("a\nb\nc\n", "a\nb\nC\n"))
+}
+
+func TestWithTestYAMLOptions(t *testing.T) {
+	var yamlTrace bytes.Buffer
+	tp, err := Provider().
+		WithTestYAMLOptions(traceyaml.Options{
+			ExcludeAttributes: []string{"host.*"},
+			RedactAttributes:  []string{"file.path"},
+			DropEvents:        []string{"retry *"},
+		}).
+		TestYAMLTo(&yamlTrace).
+		Build()
+	require.Nil(t, err)
+	ctx := Context().WithTracerProvider(tp).Build()
+
+	_, span := Tracer().Start(ctx, "filtered", trace.WithAttributes(attribute.String("host.name", "foo")))
+	span.SetAttributes(
+		attribute.String("host.ip", "127.0.0.1"),
+		attribute.String("file.path", "/tmp/foo"),
+		attribute.Int("count", 1),
+	)
+	span.AddEvent("retry 1")
+	span.AddEvent("done", trace.WithAttributes(attribute.String("file.path", "/tmp/foo")))
+	span.End()
+
+	assert.Equal(t, `# filtered
+- spanName: filtered
+  attributes:
+    count: 1
+    file.path: '[REDACTED]'
+  events:
+  - name: done
+    attributes:
+      file.path: '[REDACTED]'
+
+`, yamlTrace.String())
+}
+
+func TestTestYAMLDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration traceyaml.DurationFunc
+		want     []string
+	}{
+		{
+			name:     "buckets",
+			duration: traceyaml.BucketDuration(time.Millisecond, 10*time.Millisecond),
+			want:     []string{"<1ms", "1ms-10ms", "1ms-10ms", "'>=10ms'"},
+		},
+		{
+			name:     "fixed",
+			duration: traceyaml.FixedDuration(time.Second),
+			want:     []string{"1s", "1s", "1s", "1s"},
+		},
+	}
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			var yamlTrace bytes.Buffer
+			tp, err := Provider().
+				WithTestYAMLOptions(traceyaml.Options{Duration: rt.duration}).
+				TestYAMLTo(&yamlTrace).
+				Build()
+			require.Nil(t, err)
+			ctx := Context().WithTracerProvider(tp).Build()
+
+			start := time.Unix(0, 0)
+			want := ""
+			for i, d := range []time.Duration{0, time.Millisecond, 9 * time.Millisecond, time.Hour} {
+				_, span := Tracer().Start(ctx, "timed", trace.WithTimestamp(start))
+				span.End(trace.WithTimestamp(start.Add(d)))
+
+				want += "# timed\n- spanName: timed\n  duration: " + rt.want[i] + "\n\n"
+			}
+			assert.Equal(t, want, yamlTrace.String())
+		})
+	}
+}
+
+func TestTestYAMLFormatJSON(t *testing.T) {
+	var jsonTrace bytes.Buffer
+	tp, err := Provider().
+		WithTestYAMLOptions(traceyaml.Options{Format: traceyaml.FormatJSON}).
+		TestYAMLTo(&jsonTrace).
+		Build()
+	require.Nil(t, err)
+	ctx := Context().WithTracerProvider(tp).Build()
+
+	ctx, span := Tracer().Start(ctx, "parent")
+	span.SetAttributes(attribute.Int("count", 1))
+	_, child := Tracer().Start(ctx, "child")
+	child.AddEvent("foo")
+	child.End()
+	span.End()
+
+	want := `{
+  "spanName": "parent",
+  "attributes": {
+    "count": 1
+  },
+  "children": [
+    {
+      "spanName": "child",
+      "events": [
+        {
+          "name": "foo"
+        }
+      ]
+    }
+  ]
+}
+`
+	assert.Equal(t, want, jsonTrace.String())
+
+	// The structured diff supports JSON too
+	assert.Equal(t, `"parent" > "child": event 0: expected "bar", got "foo"
+missing span "other"
+`, traceyaml.Diff(want, `{"spanName": "parent", "attributes": {"count": 1}, "children": [{"spanName": "child", "events": [{"name": "bar"}]}]}
+{"spanName": "other"}`))
+}
+
+type fakeT struct {
+	errs []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errs = append(t.errs, fmt.Sprintf(format, args...))
+}
+
+func TestTraceYAMLExpect(t *testing.T) {
+	rec := &traceyaml.Recorder{}
+	tp, err := Provider().
+		WithTestYAMLOptions(traceyaml.Options{Recorder: rec}).
+		TestYAMLTo(nil).
+		Build()
+	require.Nil(t, err)
+	ctx := Context().WithTracerProvider(tp).Build()
+
+	assert.ErrorIs(t, doWork(ctx, t, false), errSomeOperation)
+	require.Len(t, rec.Spans(), 1)
+
+	traceyaml.Expect(t, rec.Spans()...).
+		Span("worker.doWork").
+		WithAttr("result", "result").
+		WithoutAttr("foo").
+		WithStatus(codes.Ok).
+		WithError("unexpected thing happened").
+		WithChildren(2).
+		Child("errorOperator").
+		WithEvent("SomeOperationError").
+		WithStatus(codes.Ok)
+
+	ft := &fakeT{}
+	traceyaml.Expect(ft, rec.Spans()...).
+		NoSpan("ignoreMe").
+		Span("someOperationPre").
+		WithAttr("arr", []string{"foo"}).
+		WithStatus(codes.Ok).
+		WithEvent("foo").
+		WithError("foo").
+		Child("bar").
+		WithAttr("ignored", true)
+	traceyaml.Expect(ft, rec.Spans()...).Span("unknown").WithChildren(1)
+	assert.Equal(t, []string{
+		`traceyaml: unexpected span named "ignoreMe"`,
+		`traceyaml: span "someOperationPre" attribute "arr": expected [foo], got [foo bar]`,
+		`traceyaml: span "someOperationPre" status: expected Ok, got Error`,
+		`traceyaml: span "someOperationPre" has no event named "foo"`,
+		`traceyaml: span "someOperationPre" has no error containing "foo"`,
+		`traceyaml: span "someOperationPre" has no child span named "bar"`,
+		`traceyaml: no span named "unknown"`,
+	}, ft.errs)
+}
+
+func TestTraceYAMLParse(t *testing.T) {
+	data, err := os.ReadFile("testdata/TestTracer/TraceUptoLogger1.yaml")
+	require.Nil(t, err)
+
+	spans, err := traceyaml.Parse(data)
+	require.Nil(t, err)
+	require.Len(t, spans, 4)
+	assert.Equal(t, "afterShutdown", spans[3].SpanName)
+
+	traceyaml.Expect(t, spans...).
+		Span("worker.doWork").
+		WithAttr("log-attr-op-result", -1).
+		WithChildren(2).
+		Child("someOperationPre").
+		WithAttr("arr", []interface{}{"foo", "bar"}).
+		WithStatus(codes.Error)
+
+	// Parsing the YAML and JSON output of the same trace gives the same result
+	var yamlTrace, jsonTrace bytes.Buffer
+	for _, opts := range []struct {
+		w      io.Writer
+		format traceyaml.OutputFormat
+	}{{&yamlTrace, traceyaml.FormatYAML}, {&jsonTrace, traceyaml.FormatJSON}} {
+		tp, err := Provider().
+			WithTestYAMLOptions(traceyaml.Options{Format: opts.format}).
+			TestYAMLTo(opts.w).
+			Build()
+		require.Nil(t, err)
+		ctx := Context().WithTracerProvider(tp).Build()
+		assert.ErrorIs(t, doWork(ctx, t, false), errSomeOperation)
+	}
+
+	yamlSpans, err := traceyaml.Parse(yamlTrace.Bytes())
+	require.Nil(t, err)
+	jsonSpans, err := traceyaml.Parse(jsonTrace.Bytes())
+	require.Nil(t, err)
+	require.Len(t, yamlSpans, 1)
+	assert.Empty(t, traceyaml.DiffSpans(yamlSpans, jsonSpans))
+
+	_, err = traceyaml.Parse([]byte("foo: bar"))
+	assert.NotNil(t, err)
+}
+
+func TestTraceYAMLStatusCodes(t *testing.T) {
+	for _, data := range []string{
+		"- spanName: foo\n  statusChanges:\n  - code: Ok\n  - code: Error\n    description: bar\n",
+		"- spanName: foo\n  statusChanges:\n  - code: 2\n  - code: 1\n    description: bar\n",
+		`{"spanName": "foo", "statusChanges": [{"code": "Ok"}, {"code": "Error", "description": "bar"}]}`,
+		`{"spanName": "foo", "statusChanges": [{"code": 2}, {"code": 1, "description": "bar"}]}`,
+	} {
+		spans, err := traceyaml.Parse([]byte(data))
+		require.Nil(t, err)
+		require.Len(t, spans, 1)
+		assert.Equal(t, []traceyaml.Status{
+			{Code: codes.Ok},
+			{Code: codes.Error, Description: "bar"},
+		}, spans[0].StatusChanges)
+	}
+
+	_, err := traceyaml.Parse([]byte("- spanName: foo\n  statusChanges:\n  - code: Unknown\n"))
+	assert.NotNil(t, err)
+
+	out, err := json.Marshal(traceyaml.Status{Code: codes.Error, Description: "bar"})
+	require.Nil(t, err)
+	assert.Equal(t, `{"code":"Error","description":"bar"}`, string(out))
+}
+
+func TestTestYAMLOrderByName(t *testing.T) {
+	var yamlTrace bytes.Buffer
+	tp, err := Provider().
+		WithTestYAMLOptions(traceyaml.Options{ChildOrder: traceyaml.OrderByName}).
+		TestYAMLTo(&yamlTrace).
+		Build()
+	require.Nil(t, err)
+	ctx := Context().WithTracerProvider(tp).Build()
+
+	ctx, span := Tracer().Start(ctx, "parent")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, child := Tracer().Start(ctx, fmt.Sprintf("child-%d", i%2))
+			child.SetAttributes(attribute.Int("i", i))
+			child.End()
+		}(i)
+	}
+	wg.Wait()
+	span.End()
+
+	assert.Equal(t, `# parent
+- spanName: parent
+  children:
+  - spanName: child-0
+    attributes:
+      i: 0
+  - spanName: child-0
+    attributes:
+      i: 2
+  - spanName: child-1
+    attributes:
+      i: 1
+  - spanName: child-1
+    attributes:
+      i: 3
+
+`, yamlTrace.String())
+}
+
+func TestTestYAMLIncludeIDs(t *testing.T) {
+	rec := &traceyaml.Recorder{}
+	var yamlTrace bytes.Buffer
+	tp, err := Provider().
+		DeterministicIDs(1234).
+		WithTestYAMLOptions(traceyaml.Options{IncludeIDs: true, Recorder: rec}).
+		TestYAMLTo(&yamlTrace).
+		Build()
+	require.Nil(t, err)
+	ctx := Context().WithTracerProvider(tp).Build()
+
+	ctx, span := Tracer().Start(ctx, "parent")
+	_, child := Tracer().Start(ctx, "child")
+	child.End()
+	_, newRoot := Tracer().Start(ctx, "newRoot", trace.WithNewRoot())
+	newRoot.End()
+	span.End()
+
+	spans := rec.Spans()
+	require.Len(t, spans, 2)
+	newRootInfo, parent := spans[0], spans[1]
+	require.Len(t, parent.Children, 1)
+	childInfo := parent.Children[0]
+
+	assert.Len(t, parent.TraceID, 32)
+	assert.Len(t, parent.SpanID, 16)
+	assert.Empty(t, parent.ParentID)
+	assert.Equal(t, parent.TraceID, childInfo.TraceID)
+	assert.Equal(t, parent.SpanID, childInfo.ParentID)
+	assert.NotEqual(t, parent.SpanID, childInfo.SpanID)
+	assert.NotEqual(t, parent.TraceID, newRootInfo.TraceID)
+	assert.Empty(t, newRootInfo.ParentID)
+
+	assert.Equal(t, `# newRoot
+- spanName: newRoot
+  traceID: `+newRootInfo.TraceID+`
+  spanID: `+newRootInfo.SpanID+`
+  startConfig:
+    newRoot: true
+
+# parent
+- spanName: parent
+  traceID: `+parent.TraceID+`
+  spanID: `+parent.SpanID+`
+  children:
+  - spanName: child
+    traceID: `+childInfo.TraceID+`
+    spanID: `+childInfo.SpanID+`
+    parentID: `+parent.SpanID+`
+
+`, yamlTrace.String())
+}
+
+func TestTestYAMLLinks(t *testing.T) {
+	var yamlTrace bytes.Buffer
+	tp, err := Provider().TestYAMLTo(&yamlTrace).Build()
+	require.Nil(t, err)
+	ctx := Context().WithTracerProvider(tp).Build()
+
+	ctx, span := Tracer().Start(ctx, "parent")
+	childCtx, child := Tracer().Start(ctx, "child")
+	child.End()
+	_, linked := Tracer().Start(ctx, "linked", trace.WithNewRoot(),
+		trace.WithLinks(trace.LinkFromContext(childCtx, attribute.String("reason", "batch"))))
+	require.True(t, AddLink(linked, trace.LinkFromContext(ctx)))
+	linked.End()
+	span.End()
+
+	assert.Equal(t, `# linked
+- spanName: linked
+  links:
+  - span: '"parent"'
+  startConfig:
+    links:
+    - span: '"parent" > "child"'
+      attributes:
+        reason: batch
+    newRoot: true
+
+# parent
+- spanName: parent
+  children:
+  - spanName: child
+
+`, yamlTrace.String())
+}
+
+func TestTestYAMLLinks_Wrappers(t *testing.T) {
+	var yamlTrace bytes.Buffer
+	tp, err := Provider().TestYAMLTo(&yamlTrace).
+		WithClock(DeterministicClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), time.Millisecond)).
+		Build()
+	require.Nil(t, err)
+	ctx := Context().WithTracerProvider(tp).WithLogger(ZapLogger().LogTo(io.Discard).Build()).Build()
+
+	targetCtx, target := Tracer().Start(ctx, "target")
+	target.End()
+	link := trace.LinkFromContext(targetCtx)
+
+	// Links are forwarded through the timeout span
+	_, timeout := Tracer().StartWithTimeout(ctx, "timeout", time.Hour)
+	require.True(t, AddLink(timeout, link))
+	timeout.End()
+
+	// And through the spans wrapped by SpanFromContext
+	foreignCtx, foreign := tp.Tracer("foreign").Start(ctx, "foreign")
+	require.True(t, AddLink(SpanFromContext(foreignCtx), link))
+	foreign.End()
+
+	// Spans not supporting links are reported as such
+	assert.False(t, AddLink(trace.SpanFromContext(context.Background()), link))
+
+	assert.Equal(t, `# target
+- spanName: target
+
+# timeout
+- spanName: timeout
+  links:
+  - span: '"target"'
+
+# foreign
+- spanName: foreign
+  links:
+  - span: '"target"'
+
+`, yamlTrace.String())
+}
+
+func TestTestYAMLWriteOnShutdown(t *testing.T) {
+	var yamlTrace bytes.Buffer
+	tp, err := Provider().
+		WithTestYAMLOptions(traceyaml.Options{WriteMode: traceyaml.WriteOnShutdown}).
+		TestYAMLTo(&yamlTrace).
+		Build()
+	require.Nil(t, err)
+	ctx := Context().WithTracerProvider(tp).Build()
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"c", "a", "b"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			_, span := Tracer().Start(ctx, name)
+			span.End()
+		}(name)
+	}
+	wg.Wait()
+	assert.Empty(t, yamlTrace.String())
+
+	require.Nil(t, tp.Shutdown(ctx))
+	assert.Equal(t, `# a
+- spanName: a
+
+# b
+- spanName: b
+
+# c
+- spanName: c
+
+`, yamlTrace.String())
+}
+
+func TestTestYAMLValueFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     traceyaml.Options
+		expected string
+	}{
+		{
+			name: "native",
+			expected: `    arr:
+    - 1
+    - 2
+    count: 1
+    ok: true
+    ratio: 1.5
+`,
+		},
+		{
+			name: "strings",
+			opts: traceyaml.Options{ValueFormat: traceyaml.StringValues},
+			expected: `    arr:
+    - "1"
+    - "2"
+    count: "1"
+    ok: "true"
+    ratio: "1.5"
+`,
+		},
+		{
+			name: "flattened",
+			opts: traceyaml.Options{FlattenArrays: true},
+			expected: `    arr: 1,2
+    count: 1
+    ok: true
+    ratio: 1.5
+`,
+		},
+	}
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			var yamlTrace bytes.Buffer
+			tp, err := Provider().WithTestYAMLOptions(rt.opts).TestYAMLTo(&yamlTrace).Build()
+			require.Nil(t, err)
+			ctx := Context().WithTracerProvider(tp).Build()
+
+			_, span := Tracer().Start(ctx, "typed")
+			span.SetAttributes(
+				attribute.Array("arr", []int64{1, 2}),
+				attribute.Int("count", 1),
+				attribute.Bool("ok", true),
+				attribute.Float64("ratio", 1.5),
+			)
+			span.End()
+
+			assert.Equal(t, "# typed\n- spanName: typed\n  attributes:\n"+rt.expected+"\n", yamlTrace.String())
+		})
+	}
+}
+
+func TestTestYAMLTruncate(t *testing.T) {
+	var yamlTrace bytes.Buffer
+	tp, err := Provider().
+		WithTestYAMLOptions(traceyaml.Options{MaxDepth: 2, MaxChildren: 2}).
+		TestYAMLTo(&yamlTrace).
+		Build()
+	require.Nil(t, err)
+	ctx := Context().WithTracerProvider(tp).Build()
+
+	ctx, root := Tracer().Start(ctx, "root")
+	for _, name := range []string{"a", "b", "c"} {
+		childCtx, child := Tracer().Start(ctx, name)
+		grandchildCtx, grandchild := Tracer().Start(childCtx, name+"1")
+		_, leaf := Tracer().Start(grandchildCtx, name+"11")
+		leaf.End()
+		grandchild.End()
+		child.End()
+	}
+	root.End()
+
+	assert.Equal(t, `# root
+- spanName: root
+  children:
+  - spanName: a
+    children:
+    - spanName: a1
+      truncatedChildren: 1
+  - spanName: b
+    children:
+    - spanName: b1
+      truncatedChildren: 1
+  truncatedChildren: 1
+
+`, yamlTrace.String())
+}
+
+func TestTestYAMLCaptureLogs(t *testing.T) {
+	var yamlTrace bytes.Buffer
+	tp, err := Provider().
+		WithTestYAMLOptions(traceyaml.Options{CaptureLogs: true, RedactAttributes: []string{"password"}}).
+		TestYAMLTo(&yamlTrace).
+		Build()
+	require.Nil(t, err)
+	log := ZapLogger().Example().LogTo(io.Discard).Build()
+	ctx := Context().WithTracerProvider(tp).WithLogger(log).Build()
+
+	_, span, log := Tracer().Trace(ctx, "logging")
+	log.WithValues("user", "foo").Info("logged in", "password", "hunter2")
+	log.Error(fmt.Errorf("oops"), "failed")
+	span.End()
+
+	assert.Equal(t, `# logging
+- spanName: logging
+  attributes:
+    log-attr-password: hunter2
+    log-attr-user: foo
+  errors:
+  - error: oops
+  logs:
+  - message: logged in
+    fields:
+      password: '[REDACTED]'
+      user: foo
+  - message: failed
+    error: oops
+
+`, yamlTrace.String())
+}
+
+func TestTestYAMLConcurrentEnd(t *testing.T) {
+	rec := &traceyaml.Recorder{}
+	var yamlTrace bytes.Buffer
+	tp, err := Provider().
+		WithTestYAMLOptions(traceyaml.Options{Recorder: rec}).
+		TestYAMLTo(&yamlTrace).
+		Build()
+	require.Nil(t, err)
+	ctx := Context().WithTracerProvider(tp).Build()
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			ctx, root := Tracer().Start(ctx, fmt.Sprintf("root%d", i))
+			_, child := Tracer().Start(ctx, "child")
+			// The child span is still modified after the root span has ended
+			go func() {
+				defer wg.Done()
+				child.SetAttributes(attribute.Int("i", i))
+				child.AddEvent("event")
+				child.End()
+			}()
+			root.SetAttributes(attribute.Int("i", i))
+			root.End()
+		}(i)
+	}
+	wg.Wait()
+
+	spans, err := traceyaml.Parse(yamlTrace.Bytes())
+	require.Nil(t, err)
+	recorded := rec.Spans()
+	require.Len(t, spans, n)
+	require.Len(t, recorded, n)
+	// The spans are written in the same total order as they're recorded
+	for i := range spans {
+		assert.Equal(t, recorded[i].SpanName, spans[i].SpanName)
+		assert.Empty(t, traceyaml.DiffSpans(spans[i:i+1], recorded[i:i+1]))
+	}
+}
+
+func TestTestYAMLOrderByStartTime(t *testing.T) {
+	var yamlTrace bytes.Buffer
+	tp, err := Provider().
+		WithTestYAMLOptions(traceyaml.Options{
+			WriteMode:  traceyaml.WriteOnShutdown,
+			ChildOrder: traceyaml.OrderByStartTime,
+			RootOrder:  traceyaml.OrderByStartTime,
+		}).
+		TestYAMLTo(&yamlTrace).
+		Build()
+	require.Nil(t, err)
+	ctx := Context().WithTracerProvider(tp).Build()
+
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) trace.SpanStartOption { return trace.WithTimestamp(start.Add(d)) }
+
+	ctx2, second := Tracer().Start(ctx, "b", at(time.Second))
+	_, late := Tracer().Start(ctx2, "late", at(3*time.Second))
+	_, early := Tracer().Start(ctx2, "early", at(2*time.Second))
+	early.End()
+	late.End()
+	second.End()
+	_, first := Tracer().Start(ctx, "z", at(0))
+	first.End()
+	require.Nil(t, tp.Shutdown(ctx))
+
+	spans, err := traceyaml.Parse(yamlTrace.Bytes())
+	require.Nil(t, err)
+	require.Len(t, spans, 2)
+	assert.Equal(t, "z", spans[0].SpanName)
+	assert.Equal(t, "b", spans[1].SpanName)
+	require.Len(t, spans[1].Children, 2)
+	assert.Equal(t, "early", spans[1].Children[0].SpanName)
+	assert.Equal(t, "late", spans[1].Children[1].SpanName)
+}
+
+func TestTraceYAMLWriteOTLPJSON(t *testing.T) {
+	spans, err := traceyaml.Parse([]byte(`- spanName: parent
+  attributes:
+    count: 1
+  errors:
+  - error: oops
+  startConfig:
+    spanKind: 3
+  statusChanges:
+  - code: Error
+    description: failed
+  children:
+  - spanName: child
+    links:
+    - span: '"parent"'
+`))
+	require.Nil(t, err)
+
+	var buf bytes.Buffer
+	require.Nil(t, traceyaml.WriteOTLPJSON(&buf, spans))
+	assert.Equal(t, `{
+  "resourceSpans": [
+    {
+      "resource": {
+        "attributes": [
+          {
+            "key": "service.name",
+            "value": {
+              "stringValue": "traceyaml"
+            }
+          }
+        ]
+      },
+      "scopeSpans": [
+        {
+          "scope": {
+            "name": "github.com/luxas/deklarative/tracing/traceyaml"
+          },
+          "spans": [
+            {
+              "traceId": "00000000000000000000000000000003",
+              "spanId": "0000000000000001",
+              "name": "parent",
+              "kind": 3,
+              "startTimeUnixNano": "1609459200000001000",
+              "endTimeUnixNano": "1609459200000004000",
+              "attributes": [
+                {
+                  "key": "count",
+                  "value": {
+                    "intValue": "1"
+                  }
+                }
+              ],
+              "events": [
+                {
+                  "timeUnixNano": "1609459200000001000",
+                  "name": "exception",
+                  "attributes": [
+                    {
+                      "key": "exception.message",
+                      "value": {
+                        "stringValue": "oops"
+                      }
+                    }
+                  ]
+                }
+              ],
+              "status": {
+                "code": 2,
+                "message": "failed"
+              }
+            },
+            {
+              "traceId": "00000000000000000000000000000003",
+              "spanId": "0000000000000002",
+              "parentSpanId": "0000000000000001",
+              "name": "child",
+              "kind": 0,
+              "startTimeUnixNano": "1609459200000002000",
+              "endTimeUnixNano": "1609459200000003000",
+              "links": [
+                {
+                  "traceId": "00000000000000000000000000000003",
+                  "spanId": "0000000000000001"
+                }
+              ],
+              "status": {}
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`, buf.String())
+}
+
+func TestWithSpanInfoTransform(t *testing.T) {
+	var yamlTrace bytes.Buffer
+	tp, err := Provider().
+		WithTestYAMLOptions(traceyaml.Options{ChildOrder: traceyaml.OrderByName}).
+		WithSpanInfoTransform(func(si *traceyaml.SpanInfo) *traceyaml.SpanInfo {
+			if si.SpanName == "dropped" {
+				return nil
+			}
+			return si
+		}).
+		WithSpanInfoTransform(func(si *traceyaml.SpanInfo) *traceyaml.SpanInfo {
+			// Collapse retries into one child span
+			var children []*traceyaml.SpanInfo
+			for _, child := range si.Children {
+				if child.SpanName != "retry" {
+					children = append(children, child)
+				}
+			}
+			si.Children = append(children, &traceyaml.SpanInfo{SpanName: "retries"})
+			return si
+		}).
+		TestYAMLTo(&yamlTrace).
+		Build()
+	require.Nil(t, err)
+	ctx := Context().WithTracerProvider(tp).Build()
+
+	_, dropped := Tracer().Start(ctx, "dropped")
+	dropped.End()
+	ctx, root := Tracer().Start(ctx, "root")
+	for i := 0; i < 3; i++ {
+		_, retry := Tracer().Start(ctx, "retry")
+		retry.End()
+	}
+	_, done := Tracer().Start(ctx, "done")
+	done.End()
+	root.End()
+
+	assert.Equal(t, `# root
+- spanName: root
+  children:
+  - spanName: done
+  - spanName: retries
+
+`, yamlTrace.String())
+}
+
+func TestTestYAMLBudgets(t *testing.T) {
+	var yamlTrace bytes.Buffer
+	tp, err := Provider().
+		WithTestYAMLOptions(traceyaml.Options{AttributeBudget: 2, EventBudget: 1}).
+		TestYAMLTo(&yamlTrace).
+		Build()
+	require.Nil(t, err)
+	ctx := Context().WithTracerProvider(tp).Build()
+
+	ctx, root := Tracer().Start(ctx, "root", trace.WithAttributes(attribute.Int("a", 1)))
+	root.SetAttributes(attribute.Int("b", 2), attribute.Int("c", 3))
+	_, child := Tracer().Start(ctx, "child")
+	child.AddEvent("event")
+	child.RecordError(fmt.Errorf("oops"))
+	child.End()
+	root.End()
+
+	assert.Equal(t, `# root
+- spanName: root
+  attributes:
+    b: 2
+    c: 3
+  startConfig:
+    attributes:
+      a: 1
+  warnings:
+  - 3 attributes exceed the budget of 2
+  children:
+  - spanName: child
+    errors:
+    - error: oops
+    events:
+    - name: event
+    warnings:
+    - 2 events exceed the budget of 1
+
+`, yamlTrace.String())
+}
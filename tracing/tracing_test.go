@@ -1,19 +1,27 @@
 package tracing
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/luxas/deklarative/tracing/filetest"
+	"github.com/luxas/deklarative/tracing/traceyaml"
 	"github.com/luxas/deklarative/tracing/zaplog"
 	"github.com/sebdah/goldie/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -84,7 +92,7 @@ func TestTracer(t *testing.T) {
 
 	for _, rt := range tests {
 		t.Run(rt.name, func(t *testing.T) {
-			g := filetest.New(t, goldie.WithNameSuffix(""))
+			g := filetest.New(t, goldie.WithNameSuffix(""), goldie.WithDiffFn(traceyaml.Diff))
 			defer g.Assert()
 
 			tp := NoopTracerProvider()
@@ -189,3 +197,326 @@ func someOperation(ctx context.Context) (_ int64, retErr error) {
 
 	return -1, fmt.Errorf("%w: unexpected thing happened", errSomeOperation)
 }
+
+var errExport = errors.New("export failed")
+
+type failingExporter struct{}
+
+func (failingExporter) ExportSpans(context.Context, []tracesdk.ReadOnlySpan) error { return errExport }
+func (failingExporter) Shutdown(context.Context) error                             { return nil }
+
+func TestStats(t *testing.T) {
+	tp, err := Provider().Synchronous().Build()
+	require.Nil(t, err)
+	assert.Equal(t, TracerProviderStats{}, Stats(tp))
+
+	ctx := Context().WithTracerProvider(tp).Build()
+	ctx, span := Tracer().Start(ctx, "parent")
+	_, child := Tracer().Start(ctx, "child")
+	child.End()
+
+	assert.Equal(t, TracerProviderStats{
+		StartedSpans:  2,
+		EndedSpans:    1,
+		ExportedSpans: 1,
+	}, Stats(tp))
+	span.End()
+	assert.Equal(t, uint64(2), Stats(tp).ExportedSpans)
+
+	// Composite TracerProviders forward the stats of the underlying provider
+	tp, err = Provider().Synchronous().TestYAMLTo(io.Discard).Build()
+	require.Nil(t, err)
+	_, span = Tracer().Start(Context().WithTracerProvider(tp).Build(), "composite")
+	span.End()
+	assert.Equal(t, uint64(1), Stats(tp).ExportedSpans)
+
+	// With multiple exporters, a span is counted once per exporter
+	tp, err = Provider().Synchronous().
+		WithStdoutExporter(stdouttrace.WithWriter(io.Discard)).
+		WithStdoutExporter(stdouttrace.WithWriter(io.Discard)).
+		Build()
+	require.Nil(t, err)
+	_, span = Tracer().Start(Context().WithTracerProvider(tp).Build(), "twice")
+	span.End()
+	assert.Equal(t, uint64(2), Stats(tp).ExportedSpans)
+
+	assert.Equal(t, TracerProviderStats{}, Stats(NoopTracerProvider()))
+}
+
+func Test_statsExporter(t *testing.T) {
+	stats := newStatsCollector()
+	exp := &statsExporter{failingExporter{}, stats}
+	assert.ErrorIs(t, exp.ExportSpans(context.Background(), make([]tracesdk.ReadOnlySpan, 3)), errExport)
+	assert.Equal(t, TracerProviderStats{
+		DroppedSpans:    3,
+		LastExportError: errExport,
+	}, stats.Stats())
+}
+
+func TestShutdownOnSignal(t *testing.T) {
+	var buf bytes.Buffer
+	// Use the batching mode, such that the span is only exported when flushed
+	tp, err := Provider().WithStdoutExporter(stdouttrace.WithWriter(&buf)).Build()
+	require.Nil(t, err)
+
+	_, span := Tracer().Start(Context().WithTracerProvider(tp).Build(), "batched")
+	span.End()
+	assert.Equal(t, 0, buf.Len())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Nil(t, ShutdownOnSignal(ctx, tp))
+	assert.NotEqual(t, 0, buf.Len())
+	assert.Equal(t, uint64(1), Stats(tp).ExportedSpans)
+}
+
+func TestDepthFromContext(t *testing.T) {
+	ctx := Context().WithLogger(ZapLogger().LogTo(io.Discard).Build()).Build()
+	assert.Equal(t, Depth(0), DepthFromContext(ctx))
+
+	ctx, span := Tracer().Start(ctx, "root")
+	defer span.End()
+	assert.Equal(t, Depth(0), DepthFromContext(ctx))
+
+	childCtx, child := Tracer().Start(ctx, "child")
+	defer child.End()
+	assert.Equal(t, Depth(1), DepthFromContext(childCtx))
+
+	rootCtx, newRoot := Tracer().Start(childCtx, "newRoot", trace.WithNewRoot())
+	defer newRoot.End()
+	assert.Equal(t, Depth(0), DepthFromContext(rootCtx))
+}
+
+func TestDetach(t *testing.T) {
+	log := ZapLogger().LogTo(io.Discard).Build()
+	parent, cancel := context.WithTimeout(Context().WithLogger(log).Build(), time.Hour)
+	parent, span := Tracer().Start(parent, "parent")
+	defer span.End()
+	cancel()
+
+	ctx := Detach(parent)
+	assert.NotNil(t, parent.Err())
+	assert.Nil(t, ctx.Err())
+	assert.Nil(t, ctx.Done())
+	_, hasDeadline := ctx.Deadline()
+	assert.False(t, hasDeadline)
+
+	assert.Equal(t, span, SpanFromContext(ctx))
+	assert.Equal(t, log, LoggerFromContext(ctx))
+
+	ctx, child := Tracer().Start(ctx, "child")
+	defer child.End()
+	assert.Equal(t, Depth(1), DepthFromContext(ctx))
+}
+
+func TestStartWithTimeout(t *testing.T) {
+	var yamlTrace bytes.Buffer
+	tp, err := Provider().TestYAMLTo(&yamlTrace).Build()
+	require.Nil(t, err)
+	ctx := Context().WithTracerProvider(tp).Build()
+
+	timeoutCtx, span := Tracer().StartWithTimeout(ctx, "timesOut", time.Millisecond)
+	<-timeoutCtx.Done()
+	span.End()
+
+	_, span = Tracer().StartWithTimeout(ctx, "inTime", time.Hour)
+	span.End()
+
+	assert.Equal(t, `# timesOut
+- spanName: timesOut
+  events:
+  - name: deadline exceeded
+  statusChanges:
+  - code: Error
+    description: deadline of 1ms exceeded
+
+# inTime
+- spanName: inTime
+
+`, yamlTrace.String())
+}
+
+func TestTraceNoopAllocs(t *testing.T) {
+	ctx := context.Background()
+	tr := Tracer().WithActor("noop")
+	allocs := testing.AllocsPerRun(100, func() {
+		_, span, log := tr.Trace(ctx, "Trace")
+		log.Info("discarded")
+		span.End()
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func TestTraceNoopCapture(t *testing.T) {
+	var registered []error
+	errFn := func(err error, span Span, log Logger) { registered = append(registered, err) }
+
+	fn := func(fail bool) (retErr error) {
+		_, span := Tracer().Capture(&retErr).ErrRegisterFunc(errFn).Start(context.Background(), "noop")
+		defer span.End()
+		if fail {
+			return errSample
+		}
+		return nil
+	}
+	assert.Nil(t, fn(false))
+	assert.ErrorIs(t, fn(true), errSample)
+	assert.Equal(t, []error{nil, errSample}, registered)
+}
+
+func TestTracePooled(t *testing.T) {
+	var yamlTrace bytes.Buffer
+	tp, err := Provider().TestYAMLTo(&yamlTrace).Build()
+	require.Nil(t, err)
+	log := ZapLogger().LogTo(io.Discard).Build()
+	ctx := Context().WithTracerProvider(tp).WithLogger(log).Build()
+
+	want := ""
+	for i := 0; i < 3; i++ {
+		_, span, log := Tracer().Pooled().Trace(ctx, "pooled")
+		log.Info("iteration", "i", i)
+		span.End()
+
+		want += fmt.Sprintf("# pooled\n- spanName: pooled\n  attributes:\n    log-attr-i: %d\n\n", i)
+	}
+	assert.Equal(t, want, yamlTrace.String())
+}
+
+func TestTracePooledAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations can't be counted reliably with the race detector")
+	}
+	tp, err := Provider().TestYAMLTo(io.Discard).Build()
+	require.Nil(t, err)
+	log := ZapLogger().LogTo(io.Discard).Build()
+	ctx := Context().WithTracerProvider(tp).WithLogger(log).Build()
+
+	allocs := func(tr *TracerBuilder) float64 {
+		return testing.AllocsPerRun(100, func() {
+			_, span, log := tr.Trace(ctx, "allocs")
+			log.Info("iteration")
+			span.End()
+		})
+	}
+	unpooled, pooled := allocs(Tracer()), allocs(Tracer().Pooled())
+	// Both the loggingSpan and the spanLogger are reused
+	assert.LessOrEqual(t, pooled, unpooled-2, "pooled: %v, unpooled: %v", pooled, unpooled)
+}
+
+func TestWithSpanDurations(t *testing.T) {
+	var buf bytes.Buffer
+	log := ZapLogger().Example().LogTo(&buf).Build()
+	ctx := Context().WithLogger(log).WithSpanDurations().Build()
+
+	_, span := Tracer().Start(ctx, "timed")
+	span.End()
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	require.Len(t, lines, 2)
+	assert.Equal(t, `{"level":"info","logger":"timed","msg":"starting span","v":0}`, string(lines[0]))
+	assert.Contains(t, string(lines[1]), `"msg":"ending span","v":0,"span-duration":`)
+}
+
+func TestSpanFromContext(t *testing.T) {
+	tp, err := Provider().Build()
+	require.Nil(t, err)
+	var buf bytes.Buffer
+	log := ZapLogger().Example().LogTo(&buf).Build()
+	ctx := Context().WithLogger(log).Build()
+
+	// Spans started using the TracerBuilder are returned as-is
+	tracedCtx, span := Tracer().Start(ctx, "traced")
+	assert.Equal(t, span, SpanFromContext(tracedCtx))
+	span.End()
+
+	// Spans started directly using OpenTelemetry are wrapped
+	rawCtx, rawSpan := tp.Tracer("").Start(ctx, "raw")
+	SpanFromContext(rawCtx).SetAttributes(attribute.Bool("foo", true))
+	rawSpan.End()
+
+	// No-op spans are returned as-is
+	assert.Equal(t, noopSpan, SpanFromContext(ctx))
+
+	assert.Equal(t, `{"level":"info","logger":"traced","msg":"starting span","v":0}
+{"level":"info","logger":"traced","msg":"ending span","v":0}
+{"level":"info","logger":"raw","msg":"span attribute change","v":0,"span-attr-foo":true}
+`, buf.String())
+}
+
+func TestRedactSpanAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	log := ZapLogger().Example().LogTo(&buf).Redact("password").Build()
+	ctx := Context().WithLogger(log).Build()
+
+	_, span, log := Tracer().Trace(ctx, "redact")
+	log.Info("logged", "password", "hunter2")
+	span.SetAttributes(attribute.String("password", "hunter2"))
+	span.End()
+
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.Contains(t, buf.String(), `"`+SpanAttributePrefix+`password":"`+zaplog.RedactedValue+`"`)
+}
+
+func TestCallerAttribution(t *testing.T) {
+	tests := []struct {
+		name string
+		b    *zaplog.Builder
+	}{
+		{"default", ZapLogger()},
+		{"with name levels", ZapLogger().WithNameLevels(map[string]int8{"caller": 1, "timeout": 1})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := tt.b.Example().WithCaller(true).LogTo(&buf).Build()
+			ctx := Context().WithLogger(log).Build()
+
+			_, span, log := Tracer().Trace(ctx, "caller")
+			log.Info("info")
+			log.V(0).WithValues("foo", "bar").Info("with values")
+			log.Error(errSample, "error")
+			Warn(log, errSample, "warning")
+			Warn(LoggerFromContext(ctx), nil, "warning without span")
+			span.SetAttributes(attribute.Bool("foo", true))
+			span.End()
+			_, span = Tracer().StartWithTimeout(ctx, "timeout", time.Minute)
+			span.End()
+
+			lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+			require.Len(t, lines, 10)
+			for _, line := range lines {
+				entry := struct {
+					Caller string `json:"caller"`
+				}{}
+				require.Nil(t, json.Unmarshal(line, &entry))
+				assert.True(t, strings.HasPrefix(entry.Caller, "tracing/tracing_test.go:"), string(line))
+			}
+		})
+	}
+}
+
+// linkRecordingSpan is a no-op Span recording the links added to it.
+type linkRecordingSpan struct {
+	Span
+	links []trace.Link
+}
+
+func (s *linkRecordingSpan) AddLink(link trace.Link) { s.links = append(s.links, link) }
+
+func TestLinkAdder_Wrappers(t *testing.T) {
+	link := trace.Link{SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{1},
+	})}
+	for name, wrap := range map[string]func(Span) Span{
+		"loggingSpan":        func(s Span) Span { return &loggingSpan{Span: s, log: logr.Discard()} },
+		"timeoutSpan":        func(s Span) Span { return &timeoutSpan{Span: s} },
+		"clockSpan":          func(s Span) Span { return &clockSpan{Span: s} },
+		"tracerProviderSpan": func(s Span) Span { return &tracerProviderSpan{Span: s} },
+		"capturingSpan":      func(s Span) Span { return &capturingSpan{Span: s} },
+	} {
+		underlying := &linkRecordingSpan{Span: noopSpan}
+		assert.True(t, AddLink(wrap(underlying), link), name)
+		assert.Equal(t, []trace.Link{link}, underlying.links, name)
+	}
+}
@@ -17,6 +17,40 @@ type (
 	Logger = logr.Logger
 )
 
+// LinkAdder is implemented by Spans that can be linked to other spans after
+// they were started, which trace.Span doesn't support in this version of the
+// OpenTelemetry API. Links given using trace.WithLinks when starting a span are
+// supported by all Spans.
+//
+// The Spans wrapped by this package, e.g. the ones returned from TracerBuilder
+// and SpanFromContext, implement LinkAdder. They log the link, and pass it on
+// to the underlying span if it implements LinkAdder, e.g. the span of a
+// TracerProvider built using TracerProviderBuilder.TestYAMLTo. Otherwise, e.g.
+// for spans of the OpenTelemetry SDK, the link is only logged. AddLink can be
+// used for adding links to any Span.
+type LinkAdder interface {
+	AddLink(link trace.Link)
+}
+
+// Assert that all composite Spans forward links to the underlying span.
+var (
+	_ LinkAdder = &loggingSpan{}
+	_ LinkAdder = &timeoutSpan{}
+	_ LinkAdder = &clockSpan{}
+	_ LinkAdder = &tracerProviderSpan{}
+	_ LinkAdder = &capturingSpan{}
+)
+
+// AddLink links span to another span after it was started, if span implements
+// LinkAdder. It returns false if span doesn't support adding links.
+func AddLink(span Span, link trace.Link) bool {
+	linker, ok := span.(LinkAdder)
+	if ok {
+		linker.AddLink(link)
+	}
+	return ok
+}
+
 // TraceEnabler controls if a trace with a given config should be started
 // or not. If Enabled returns false, a no-op span will be returned from
 // TracerBuilder.Start() and TracerBuilder.Trace(). The TraceEnabler is
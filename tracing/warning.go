@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"github.com/go-logr/zapr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	// WarningEvent is the name of the span event registered by Warn.
+	WarningEvent = "warning"
+	// WarningMessageKey is the event attribute key used for the warning message.
+	WarningMessageKey = "warning-message"
+	// WarningErrorKey is the key used for the warning error, both as a span
+	// event attribute, and for logging when the Logger doesn't support warnings.
+	WarningErrorKey = "warning-error"
+)
+
+// Warner is implemented by logr.LogSinks that support logging warnings, that
+// is, events that are more severe than informational messages, but that aren't
+// errors.
+type Warner interface {
+	Warn(err error, msg string, keysAndValues ...interface{})
+}
+
+// Warn logs a warning with the given Logger. If the Logger was returned from
+// TracerBuilder.Trace, a WarningEvent span event is recorded with the message,
+// error and keysAndValues as attributes. If the LogSink of log implements Warner,
+// that is used for logging the warning.
+//
+// If the Logger is backed by zap (e.g. built using the zaplog package), the
+// warning is logged at zap's WarnLevel, regardless of the verbosity of the
+// Logger, as for errors. Otherwise, the warning is logged using log.Info, with
+// the error (if non-nil) registered with the WarningErrorKey key.
+func Warn(log Logger, err error, msg string, keysAndValues ...interface{}) {
+	sink := log.GetSink()
+	// depth is the amount of extra stack frames the LogSink needs to skip to
+	// attribute a log.Info call from this function to the caller.
+	depth := 1
+	if l, ok := sink.(*spanLogger); ok {
+		l.registerWarning(err, msg, keysAndValues)
+		// The underlying LogSink already skips one frame, for spanLogger.Info.
+		sink, depth = l.LogSink, 0
+	}
+
+	// Warner.Warn is called directly from this function, like LogSink.Info is
+	// called directly from logr.Logger.Info.
+	if w, ok := withCallDepth(sink, depth-1).(Warner); ok {
+		w.Warn(err, msg, keysAndValues...)
+		return
+	}
+
+	kvs := make([]interface{}, 0, len(keysAndValues)+2)
+	kvs = append(kvs, keysAndValues...)
+
+	if underlier, ok := sink.(zapr.Underlier); ok {
+		if err != nil {
+			kvs = append(kvs, "error", err)
+		}
+		// The zap.Logger skips two frames for zapr and logr.Logger.Info, while
+		// the SugaredLogger skips its own frames.
+		underlier.GetUnderlying().
+			WithOptions(zap.AddCallerSkip(depth-2)).
+			Sugar().
+			Warnw(msg, kvs...)
+		return
+	}
+
+	if err != nil {
+		kvs = append(kvs, WarningErrorKey, err.Error())
+	}
+	log.WithSink(sink).WithCallDepth(depth).Info(msg, kvs...)
+}
+
+// registerWarning records a WarningEvent span event with the message, error
+// and keysAndValues as attributes.
+func (l *spanLogger) registerWarning(err error, msg string, keysAndValues []interface{}) {
+	attrs := keysAndValuesToAttrs(LogAttributePrefix, append(l.keysAndValues, keysAndValues...))
+	attrs = append(attrs, attribute.String(WarningMessageKey, msg))
+	if err != nil {
+		attrs = append(attrs, attribute.String(WarningErrorKey, err.Error()))
+	}
+	l.span.AddEvent(WarningEvent, trace.WithAttributes(attrs...))
+}
@@ -0,0 +1,76 @@
+package zaplog
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AtomicLevel is a logr log level that can be changed at runtime, for example
+// to raise the verbosity of a production service without restarting it. It is
+// safe for concurrent use.
+//
+// AtomicLevel implements http.Handler. A GET request returns the current level
+// as JSON, e.g. {"v":0}, and a PUT request with the same JSON body changes it.
+type AtomicLevel struct {
+	lvl zap.AtomicLevel
+}
+
+// NewAtomicLevel returns a new AtomicLevel, initially enabling all log messages
+// with a logr level _less than or equal to_ logrLevel.
+func NewAtomicLevel(logrLevel int8) AtomicLevel {
+	return AtomicLevel{zap.NewAtomicLevelAt(toZapLevel(logrLevel))}
+}
+
+// LogUpto returns the current logr level.
+func (l AtomicLevel) LogUpto() int8 { return int8(-1 * l.lvl.Level()) }
+
+// SetLogUpto changes the logr level. Negative logrLevel values are disallowed
+// and ignored, as for Builder.LogUpto.
+func (l AtomicLevel) SetLogUpto(logrLevel int8) {
+	if logrLevel >= 0 {
+		l.lvl.SetLevel(toZapLevel(logrLevel))
+	}
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (l AtomicLevel) Enabled(lvl zapcore.Level) bool { return l.lvl.Enabled(lvl) }
+
+type atomicLevelPayload struct {
+	Level *int8 `json:"v"`
+}
+
+// ServeHTTP implements http.Handler, like zap.AtomicLevel does, but using logr
+// levels instead of zap levels.
+func (l AtomicLevel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	enc := json.NewEncoder(w)
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req atomicLevelPayload
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = enc.Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if req.Level == nil || *req.Level < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = enc.Encode(map[string]string{"error": ErrNegativeLevel.Error()})
+			return
+		}
+		l.SetLogUpto(*req.Level)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = enc.Encode(map[string]string{"error": "only GET and PUT are supported"})
+		return
+	}
+
+	lvl := l.LogUpto()
+	_ = enc.Encode(atomicLevelPayload{&lvl})
+}
+
+func toZapLevel(logrLevel int8) zapcore.Level { return zapcore.Level(-1 * logrLevel) }
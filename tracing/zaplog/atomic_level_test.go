@@ -0,0 +1,66 @@
+package zaplog
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder_AtomicLevel(t *testing.T) {
+	var buf bytes.Buffer
+	b := NewZap().Example().LogTo(&buf)
+	lvl := b.AtomicLevel()
+	log := b.LogUpto(1).Build()
+
+	assert.Equal(t, int8(1), lvl.LogUpto())
+	assert.True(t, log.V(1).Enabled())
+	assert.False(t, log.V(2).Enabled())
+
+	lvl.SetLogUpto(2)
+	assert.True(t, log.V(2).Enabled())
+	log.V(2).Info("now enabled")
+
+	// Negative levels are ignored
+	lvl.SetLogUpto(-1)
+	assert.Equal(t, int8(2), lvl.LogUpto())
+
+	assert.Equal(t, `{"level":"debug","msg":"now enabled","v":2}`+"\n", buf.String())
+}
+
+func TestAtomicLevel_ServeHTTP(t *testing.T) {
+	lvl := NewAtomicLevel(0)
+	tests := []struct {
+		method   string
+		body     string
+		wantCode int
+		wantBody string
+		wantLvl  int8
+	}{
+		{http.MethodGet, "", http.StatusOK, `{"v":0}`, 0},
+		{http.MethodPut, `{"v":3}`, http.StatusOK, `{"v":3}`, 3},
+		{http.MethodGet, "", http.StatusOK, `{"v":3}`, 3},
+		{http.MethodPut, `{"v":-1}`, http.StatusBadRequest, `{"error":"zaplog: the logr level must not be negative"}`, 3},
+		{http.MethodPut, `{}`, http.StatusBadRequest, `{"error":"zaplog: the logr level must not be negative"}`, 3},
+		{http.MethodPut, `foo`, http.StatusBadRequest, `{"error":"invalid character 'o' in literal false (expecting 'a')"}`, 3},
+		{http.MethodPost, `{"v":1}`, http.StatusMethodNotAllowed, `{"error":"only GET and PUT are supported"}`, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.body, func(t *testing.T) {
+			var body io.Reader = http.NoBody
+			if len(tt.body) != 0 {
+				body = strings.NewReader(tt.body)
+			}
+			rec := httptest.NewRecorder()
+			lvl.ServeHTTP(rec, httptest.NewRequest(tt.method, "/", body))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody+"\n", rec.Body.String())
+			assert.Equal(t, tt.wantLvl, lvl.LogUpto())
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package zaplog
+
+import (
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// ConsoleElement is an element of a log line written by the console encoder.
+type ConsoleElement string
+
+const (
+	// ConsoleTime is the timestamp of the log entry.
+	ConsoleTime ConsoleElement = "time"
+	// ConsoleLevel is the level of the log entry.
+	ConsoleLevel ConsoleElement = "level"
+	// ConsoleName is the logger name.
+	ConsoleName ConsoleElement = "name"
+	// ConsoleCaller is the caller, and the function name if
+	// EncoderConfig.FunctionKey is set.
+	ConsoleCaller ConsoleElement = "caller"
+	// ConsoleMessage is the log message.
+	ConsoleMessage ConsoleElement = "message"
+	// ConsoleFields are the structured fields, encoded as JSON.
+	ConsoleFields ConsoleElement = "fields"
+)
+
+// DefaultConsoleOrder is the order in which ConsoleEncoderCreator writes the
+// elements of a log line.
+func DefaultConsoleOrder() []ConsoleElement {
+	return []ConsoleElement{
+		ConsoleTime, ConsoleLevel, ConsoleName, ConsoleCaller, ConsoleMessage, ConsoleFields,
+	}
+}
+
+//nolint:gochecknoglobals
+var consoleBufferPool = buffer.NewPool()
+
+// OrderedConsoleEncoderCreator returns an EncoderCreator for an encoder that
+// works like the encoder from ConsoleEncoderCreator, but writes the elements of a
+// log line in the given order, separated by EncoderConfig.ConsoleSeparator.
+// Elements not in order are omitted. The stack trace, if any, is always written
+// last, on its own lines.
+func OrderedConsoleEncoderCreator(order ...ConsoleElement) EncoderCreator {
+	return func(cfg EncoderConfig) Encoder {
+		// Every element but the fields is encoded using a console encoder
+		// configured to only encode that element.
+		elements := make([]Encoder, len(order))
+		for i, elem := range order {
+			if elem != ConsoleFields {
+				elements[i] = zapcore.NewConsoleEncoder(consoleElementConfig(cfg, elem))
+			}
+		}
+		sep := cfg.ConsoleSeparator
+		if len(sep) == 0 {
+			sep = "\t"
+		}
+		lineEnding := cfg.LineEnding
+		if len(lineEnding) == 0 {
+			lineEnding = zapcore.DefaultLineEnding
+		}
+		if cfg.SkipLineEnding {
+			lineEnding = ""
+		}
+		return &orderedConsoleEncoder{
+			Encoder:       zapcore.NewConsoleEncoder(consoleElementConfig(cfg, ConsoleFields)),
+			order:         order,
+			elements:      elements,
+			separator:     sep,
+			lineEnding:    lineEnding,
+			stacktraceKey: cfg.StacktraceKey,
+		}
+	}
+}
+
+// consoleElementConfig returns a copy of cfg that only encodes elem.
+func consoleElementConfig(cfg EncoderConfig, elem ConsoleElement) EncoderConfig {
+	elemCfg := cfg
+	elemCfg.TimeKey = zapcore.OmitKey
+	elemCfg.LevelKey = zapcore.OmitKey
+	elemCfg.NameKey = zapcore.OmitKey
+	elemCfg.CallerKey = zapcore.OmitKey
+	elemCfg.FunctionKey = zapcore.OmitKey
+	elemCfg.MessageKey = zapcore.OmitKey
+	elemCfg.StacktraceKey = zapcore.OmitKey
+	elemCfg.SkipLineEnding = true
+
+	switch elem {
+	case ConsoleTime:
+		elemCfg.TimeKey = cfg.TimeKey
+	case ConsoleLevel:
+		elemCfg.LevelKey = cfg.LevelKey
+	case ConsoleName:
+		elemCfg.NameKey = cfg.NameKey
+	case ConsoleCaller:
+		elemCfg.CallerKey = cfg.CallerKey
+		elemCfg.FunctionKey = cfg.FunctionKey
+	case ConsoleMessage:
+		elemCfg.MessageKey = cfg.MessageKey
+	case ConsoleFields:
+	}
+	return elemCfg
+}
+
+// orderedConsoleEncoder is a composite Encoder that writes the elements of a
+// log line in a configurable order.
+type orderedConsoleEncoder struct {
+	// embedding is important; this automatically exposes all inherited functionality from the
+	// underlying resource. The embedded Encoder only encodes the fields, and hence
+	// holds the fields added using With.
+	Encoder
+
+	order         []ConsoleElement
+	elements      []Encoder
+	separator     string
+	lineEnding    string
+	stacktraceKey string
+}
+
+func (c *orderedConsoleEncoder) Clone() Encoder {
+	newEnc := *c
+	newEnc.Encoder = c.Encoder.Clone()
+	return &newEnc
+}
+
+func (c *orderedConsoleEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	line := consoleBufferPool.Get()
+	for i, elem := range c.order {
+		var buf *buffer.Buffer
+		var err error
+		if elem == ConsoleFields {
+			buf, err = c.Encoder.EncodeEntry(ent, fields)
+		} else {
+			buf, err = c.elements[i].EncodeEntry(ent, nil)
+		}
+		if err != nil {
+			line.Free()
+			return nil, err
+		}
+		if buf.Len() != 0 {
+			if line.Len() != 0 {
+				line.AppendString(c.separator)
+			}
+			_, _ = line.Write(buf.Bytes())
+		}
+		buf.Free()
+	}
+
+	// If there's no stacktrace key, honor that; like the console encoder
+	if len(ent.Stack) != 0 && len(c.stacktraceKey) != 0 {
+		line.AppendByte('\n')
+		line.AppendString(ent.Stack)
+	}
+	line.AppendString(c.lineEnding)
+	return line, nil
+}
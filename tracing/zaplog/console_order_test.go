@@ -0,0 +1,79 @@
+package zaplog
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fixedClock is a zapcore.Clock that always returns the same time.
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time                         { return time.Time(c) }
+func (c fixedClock) NewTicker(d time.Duration) *time.Ticker { return time.NewTicker(d) }
+
+func zapClock(ts time.Time) zap.Option { return zap.WithClock(fixedClock(ts)) }
+
+func TestBuilder_WithConsoleOrder(t *testing.T) {
+	ts := time.Date(2021, 8, 10, 12, 30, 45, 0, time.UTC)
+	tests := []struct {
+		name  string
+		order []ConsoleElement
+		want  string
+	}{
+		{
+			name:  "default order",
+			order: DefaultConsoleOrder(),
+			want: "2021-08-10T12:30:45.000Z\tINFO\tbar\tzaplog/console_order_test.go:XX\thello\t{\"v\": 0, \"foo\": true}\n" +
+				"2021-08-10T12:30:45.000Z\tERROR\tbar.baz\tzaplog/console_order_test.go:XX\tfailed\t{\"a\": 1, \"error\": \"unexpected error\"}\n",
+		},
+		{
+			name:  "custom order",
+			order: []ConsoleElement{ConsoleLevel, ConsoleTime, ConsoleMessage, ConsoleFields, ConsoleName},
+			want: "INFO\t2021-08-10T12:30:45.000Z\thello\t{\"v\": 0, \"foo\": true}\tbar\n" +
+				"ERROR\t2021-08-10T12:30:45.000Z\tfailed\t{\"a\": 1, \"error\": \"unexpected error\"}\tbar.baz\n",
+		},
+		{
+			name:  "omitted elements",
+			order: []ConsoleElement{ConsoleMessage, ConsoleLevel},
+			want:  "hello\tINFO\nfailed\tERROR\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := NewZap().Console().NoStacktraceOnError().LogTo(&buf).
+				WithCaller(true).
+				WithCallerEncoder(func(c zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+					// Replace the line number for stable output
+					path := c.TrimmedPath()
+					enc.AppendString(path[:strings.LastIndex(path, ":")] + ":XX")
+				}).
+				WithConsoleOrder(tt.order...).
+				WithOptions(zapClock(ts)).
+				Build().WithName("bar")
+
+			log.Info("hello", "foo", true)
+			log.WithName("baz").WithValues("a", 1).Error(errors.New("unexpected error"), "failed") //nolint:goerr113
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestOrderedConsoleEncoderCreator_stacktrace(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewZap().Console().LogTo(&buf).
+		WithConsoleOrder(ConsoleMessage, ConsoleLevel).
+		Build()
+
+	log.Error(nil, "failed")
+	lines := bytes.Split(buf.Bytes(), []byte{'\n'})
+	assert.Equal(t, "failed\tERROR", string(lines[0]))
+	assert.Contains(t, string(lines[1]), "TestOrderedConsoleEncoderCreator_stacktrace")
+}
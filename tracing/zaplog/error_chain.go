@@ -0,0 +1,123 @@
+package zaplog
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrorChainSuffix is appended to the key of an error field, e.g. "error",
+// to form the key of the field holding the chain of wrapped errors, e.g.
+// "errorChain". This is similar to how zap adds the "errorVerbose" field.
+const ErrorChainSuffix = "Chain"
+
+// WithErrorChain makes the logger serialize the chain of wrapped errors of
+// every error field, as unwrapped using errors.Unwrap, into a structured field
+// next to the error field. The field is named after the error field's key and
+// ErrorChainSuffix, e.g. "errorChain", and holds a list of objects with the
+// error message ("msg") and Go type ("type") of each error in the chain, starting
+// with the outermost error.
+//
+// If stacktraces is true, the "%+v" formatting of errors that implement
+// fmt.Formatter (e.g. errors created using github.com/pkg/errors) is included in
+// the "stack" field of the object, when it's different from the error message.
+//
+// This option applies to all destinations, including the ones added using Tee.
+//
+// By default only the flat error message is logged in the error field.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) WithErrorChain(stacktraces bool) *Builder {
+	b.errorChain = &stacktraces
+	return b
+}
+
+func newErrorChainCore(core zapcore.Core, stacktraces bool) zapcore.Core {
+	return &errorChainCore{Core: core, stacktraces: stacktraces}
+}
+
+// errorChainCore is a composite zapcore.Core that adds the error chain field after
+// each error field, before passing the fields to the underlying core.
+type errorChainCore struct {
+	// embedding is important; this automatically exposes all inherited functionality from the
+	// underlying resource.
+	zapcore.Core
+
+	stacktraces bool
+}
+
+func (c *errorChainCore) With(fields []zapcore.Field) zapcore.Core {
+	return newErrorChainCore(c.Core.With(c.addErrorChains(fields)), c.stacktraces)
+}
+
+func (c *errorChainCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *errorChainCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.addErrorChains(fields))
+}
+
+func (c *errorChainCore) addErrorChains(fields []zapcore.Field) []zapcore.Field {
+	// Avoid the allocation in the common case that there are no errors
+	hasErrors := false
+	for _, f := range fields {
+		if f.Type == zapcore.ErrorType {
+			hasErrors = true
+			break
+		}
+	}
+	if !hasErrors {
+		return fields
+	}
+
+	newFields := make([]zapcore.Field, 0, len(fields)+1)
+	for _, f := range fields {
+		newFields = append(newFields, f)
+		if err, ok := f.Interface.(error); ok && f.Type == zapcore.ErrorType {
+			newFields = append(newFields, zapcore.Field{
+				Key:       f.Key + ErrorChainSuffix,
+				Type:      zapcore.ArrayMarshalerType,
+				Interface: errorChain{err: err, stacktraces: c.stacktraces},
+			})
+		}
+	}
+	return newFields
+}
+
+// errorChain encodes err and all errors it wraps as an array.
+type errorChain struct {
+	err         error
+	stacktraces bool
+}
+
+func (c errorChain) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for err := c.err; err != nil; err = errors.Unwrap(err) {
+		if err := enc.AppendObject(errorLink{err: err, stacktraces: c.stacktraces}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errorLink encodes one error of an errorChain as an object.
+type errorLink struct {
+	err         error
+	stacktraces bool
+}
+
+func (l errorLink) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	msg := l.err.Error()
+	enc.AddString("msg", msg)
+	enc.AddString("type", fmt.Sprintf("%T", l.err))
+	if _, ok := l.err.(fmt.Formatter); ok && l.stacktraces {
+		if verbose := fmt.Sprintf("%+v", l.err); verbose != msg {
+			enc.AddString("stack", verbose)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package zaplog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stackError is an error that, like errors from github.com/pkg/errors, prints
+// a stack trace when formatted using "%+v".
+type stackError struct{ msg string }
+
+func (e *stackError) Error() string { return e.msg }
+
+func (e *stackError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprintf(s, "%s\nmain.foo\n\tmain.go:10", e.msg)
+		return
+	}
+	fmt.Fprint(s, e.msg)
+}
+
+func TestBuilder_WithErrorChain(t *testing.T) {
+	root := &stackError{msg: "root cause"}
+	err := fmt.Errorf("outer: %w", fmt.Errorf("inner: %w", root))
+
+	tests := []struct {
+		name        string
+		stacktraces bool
+		want        string
+	}{
+		{
+			name: "without stacktraces",
+			want: `{"level":"error","msg":"failed","error":"outer: inner: root cause","errorChain":[` +
+				`{"msg":"outer: inner: root cause","type":"*fmt.wrapError"},` +
+				`{"msg":"inner: root cause","type":"*fmt.wrapError"},` +
+				`{"msg":"root cause","type":"*zaplog.stackError"}]}` + "\n",
+		},
+		{
+			name:        "with stacktraces",
+			stacktraces: true,
+			want: `{"level":"error","msg":"failed","error":"outer: inner: root cause","errorChain":[` +
+				`{"msg":"outer: inner: root cause","type":"*fmt.wrapError"},` +
+				`{"msg":"inner: root cause","type":"*fmt.wrapError"},` +
+				`{"msg":"root cause","type":"*zaplog.stackError","stack":"root cause\nmain.foo\n\tmain.go:10"}]}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := NewZap().Example().NoStacktraceOnError().LogTo(&buf).WithErrorChain(tt.stacktraces).Build()
+			log.Error(err, "failed")
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestBuilder_WithErrorChain_values(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewZap().Example().LogTo(&buf).WithErrorChain(false).Build()
+
+	// Error values registered using WithValues get a chain as well, other fields are unaffected
+	log.WithValues("cause", errors.New("cause")).Info("hello", "foo", true) //nolint:goerr113
+	assert.Equal(t, `{"level":"info","msg":"hello","cause":"cause","causeChain":[{"msg":"cause","type":"*errors.errorString"}],"v":0,"foo":true}`+"\n", buf.String())
+}
@@ -0,0 +1,131 @@
+package zaplog
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrorReport describes a log entry at the error level or above, as given
+// to an ErrorReporter.
+type ErrorReport struct {
+	// Time is the time the entry was logged.
+	Time time.Time
+	// Level is the zap level of the entry, i.e. zapcore.ErrorLevel or above.
+	Level zapcore.Level
+	// Message is the log message.
+	Message string
+	// Err is the error of the entry, if any. If several error fields are
+	// registered, the last one is used.
+	Err error
+	// LoggerName is the logger name. The tracing package names loggers after
+	// the spans they're created for, prefixed by the names of the parent
+	// loggers.
+	LoggerName string
+	// SpanName is the name of the span the entry was logged in, if a field
+	// holds a SpanRef, as attached by the tracing package to the loggers of
+	// its spans.
+	SpanName string
+	// SpanContext identifies the trace and span the entry was logged in, if a
+	// field holds a SpanRef, a trace.SpanContext or a trace.Span, e.g.
+	// registered using log.WithValues("span", span). Otherwise
+	// SpanContext.IsValid() is false.
+	SpanContext trace.SpanContext
+	// Fields are the other structured fields of the entry.
+	Fields map[string]interface{}
+	// Stack is the stack trace of the entry, if any.
+	Stack string
+}
+
+// ErrorReporter is an integration point for error tracking and crash aggregation
+// services (for example Sentry), such that they can consume the same stream of
+// errors as the logs.
+type ErrorReporter interface {
+	// ReportError is called for every log entry at the error level or above. It
+	// is called synchronously, and hence shouldn't block.
+	ReportError(report ErrorReport)
+}
+
+// ErrorReporterFunc is a function implementing ErrorReporter.
+type ErrorReporterFunc func(report ErrorReport)
+
+// ReportError implements ErrorReporter.
+func (f ErrorReporterFunc) ReportError(report ErrorReport) { f(report) }
+
+// WithErrorReporter forwards all log entries at the error level or above to
+// the given ErrorReporter, in addition to writing them to the destinations.
+// This includes logr.Logger.Error calls, and errors recorded by the tracing
+// package.
+//
+// A call to this function appends to the list of previous values.
+func (b *Builder) WithErrorReporter(reporter ErrorReporter) *Builder {
+	b.errorReporters = append(b.errorReporters, reporter)
+	return b
+}
+
+// errorReporterCore is a zapcore.Core that converts log entries at the error
+// level or above to ErrorReports.
+type errorReporterCore struct {
+	reporter ErrorReporter
+	fields   []zapcore.Field
+}
+
+func newErrorReporterCore(reporter ErrorReporter) zapcore.Core {
+	return &errorReporterCore{reporter: reporter}
+}
+
+func (c *errorReporterCore) Enabled(lvl zapcore.Level) bool { return lvl >= zap.ErrorLevel }
+
+func (c *errorReporterCore) With(fields []zapcore.Field) zapcore.Core {
+	newCore := *c
+	newCore.fields = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	newCore.fields = append(newCore.fields, c.fields...)
+	newCore.fields = append(newCore.fields, fields...)
+	return &newCore
+}
+
+func (c *errorReporterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *errorReporterCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	report := ErrorReport{
+		Time:       ent.Time,
+		Level:      ent.Level,
+		Message:    ent.Message,
+		LoggerName: ent.LoggerName,
+		Stack:      ent.Stack,
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, fieldList := range [][]zapcore.Field{c.fields, fields} {
+		for _, f := range fieldList {
+			if ref, ok := f.Interface.(SpanRef); ok {
+				report.SpanName = ref.Name
+			}
+			if sc, ok := toSpanContext(f.Interface); ok {
+				report.SpanContext = sc
+				continue
+			}
+			if isSpanRef(f) {
+				continue
+			}
+			if err, ok := f.Interface.(error); ok && f.Type == zapcore.ErrorType {
+				report.Err = err
+				continue
+			}
+			f.AddTo(enc)
+		}
+	}
+	report.Fields = enc.Fields
+
+	c.reporter.ReportError(report)
+	return nil
+}
+
+func (c *errorReporterCore) Sync() error { return nil }
@@ -0,0 +1,69 @@
+package zaplog
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+func TestBuilder_WithErrorReporter(t *testing.T) {
+	var reports []ErrorReport
+	reporter := ErrorReporterFunc(func(report ErrorReport) {
+		reports = append(reports, report)
+	})
+	log := NewZap().LogTo(io.Discard).LogUpto(1).WithErrorReporter(reporter).Build().WithName("foo")
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:  trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+	})
+
+	// Only errors are reported
+	log.Info("hello")
+	log.V(1).Info("debug")
+	assert.Empty(t, reports)
+
+	errFoo := errors.New("foo error") //nolint:goerr113
+	log.WithValues("span", sc, "bar", true).Error(errFoo, "unexpected", "count", 3)
+	log.Error(nil, "no error")
+
+	require.Len(t, reports, 2)
+	assert.Equal(t, zap.ErrorLevel, reports[0].Level)
+	assert.Equal(t, "unexpected", reports[0].Message)
+	assert.Equal(t, errFoo, reports[0].Err)
+	assert.Equal(t, "foo", reports[0].LoggerName)
+	assert.Equal(t, sc.TraceID(), reports[0].SpanContext.TraceID())
+	assert.Equal(t, sc.SpanID(), reports[0].SpanContext.SpanID())
+	assert.Equal(t, map[string]interface{}{"bar": true, "count": int64(3)}, reports[0].Fields)
+	assert.NotEmpty(t, reports[0].Stack)
+	assert.False(t, reports[0].Time.IsZero())
+
+	assert.Equal(t, "no error", reports[1].Message)
+	assert.Nil(t, reports[1].Err)
+	assert.False(t, reports[1].SpanContext.IsValid())
+}
+
+func TestBuilder_WithErrorReporter_SpanRef(t *testing.T) {
+	var reports []ErrorReport
+	reporter := ErrorReporterFunc(func(report ErrorReport) {
+		reports = append(reports, report)
+	})
+	log := NewZap().LogTo(io.Discard).WithErrorReporter(reporter).Build()
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:  trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+	})
+	log.WithValues(SpanRefKey, SpanRef{Name: "foo", SpanContext: sc}).Error(nil, "unexpected", "bar", true)
+
+	require.Len(t, reports, 1)
+	assert.Equal(t, "foo", reports[0].SpanName)
+	assert.Equal(t, sc, reports[0].SpanContext)
+	// The SpanRef isn't registered as a field
+	assert.Equal(t, map[string]interface{}{"bar": true}, reports[0].Fields)
+}
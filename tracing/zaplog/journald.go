@@ -0,0 +1,166 @@
+//go:build linux
+// +build linux
+
+package zaplog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// JournaldSocket is the path of the socket systemd-journald listens on for
+// log entries using its native protocol.
+const JournaldSocket = "/run/systemd/journal/socket"
+
+// LogToJournald makes the logger write logs to systemd-journald using its native
+// protocol. The encoded entry is set as the MESSAGE field, the identifier as the
+// SYSLOG_IDENTIFIER field, and the PRIORITY field is mapped from the level of the
+// entry like for LogToSyslog.
+//
+// As journald timestamps the messages, NoTimestamps() might be desired.
+//
+// Entries too large for a datagram on the socket are written to a sealed
+// memfd, or an unlinked temporary file in /dev/shm if memfds aren't supported,
+// whose file descriptor is passed to journald, as its protocol specifies.
+//
+// The client socket is opened upon the first write, and closed when the logger
+// is synced or closed (see BuildWithClose). It's reopened upon the next write.
+//
+// A call to this function overwrites any previous value, including the
+// destination set by LogTo.
+func (b *Builder) LogToJournald(identifier string) *Builder {
+	return b.logToJournald(JournaldSocket, identifier)
+}
+
+func (b *Builder) logToJournald(socket, identifier string) *Builder {
+	b.outW = nil
+	b.priorityOut = &journaldWriter{socket: socket, identifier: identifier}
+	return b
+}
+
+// journaldWriter is a priorityWriter writing to systemd-journald.
+type journaldWriter struct {
+	socket     string
+	identifier string
+
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+func (j *journaldWriter) writePriority(p priority, msg string) error {
+	var buf bytes.Buffer
+	writeJournaldField(&buf, "PRIORITY", strconv.Itoa(int(p)))
+	if len(j.identifier) != 0 {
+		writeJournaldField(&buf, "SYSLOG_IDENTIFIER", j.identifier)
+	}
+	writeJournaldField(&buf, "MESSAGE", msg)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.conn == nil {
+		// The socket isn't connected, as ancillary data can't be sent on
+		// connected datagram sockets
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+		if err != nil {
+			return err
+		}
+		j.conn = conn
+	}
+
+	addr := &net.UnixAddr{Name: j.socket, Net: "unixgram"}
+	_, err := j.conn.WriteToUnix(buf.Bytes(), addr)
+	if !errors.Is(err, syscall.EMSGSIZE) {
+		return err
+	}
+	// The entry is too large for a datagram, pass it in a file instead
+	return j.writeFile(buf.Bytes(), addr)
+}
+
+// writeFile writes data to a sealed memfd, or an unlinked temporary file, and
+// passes its file descriptor to journald.
+func (j *journaldWriter) writeFile(data []byte, addr *net.UnixAddr) error {
+	f, err := journaldFile(data)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, _, err = j.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), addr)
+	return err
+}
+
+// journaldFile returns a file containing data, which journald accepts a file
+// descriptor of. journald requires memfds to be sealed, and other files to be
+// unlinked.
+func journaldFile(data []byte) (*os.File, error) {
+	fd, err := unix.MemfdCreate("journald", unix.MFD_ALLOW_SEALING|unix.MFD_CLOEXEC)
+	if err != nil {
+		return journaldTempFile(data)
+	}
+	f := os.NewFile(uintptr(fd), "journald")
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, err
+	}
+	seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+	if _, err := unix.FcntlInt(f.Fd(), unix.F_ADD_SEALS, seals); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// journaldTempFile returns an unlinked temporary file in /dev/shm containing
+// data. It's used on kernels not supporting memfds.
+func journaldTempFile(data []byte) (*os.File, error) {
+	f, err := os.CreateTemp("/dev/shm", "journald-")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Remove(f.Name()); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// close closes the connection to the socket, if any.
+func (j *journaldWriter) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.conn == nil {
+		return nil
+	}
+	err := j.conn.Close()
+	j.conn = nil
+	return err
+}
+
+// writeJournaldField writes a field in the journald native protocol format.
+// Values containing newlines are written with an explicit length prefix.
+func writeJournaldField(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	if !strings.Contains(value, "\n") {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,123 @@
+//go:build linux
+// +build linux
+
+package zaplog
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-logr/zapr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder_LogToJournald(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenPacket("unixgram", socket)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	log := NewZap().Example().Console().logToJournald(socket, "test").Build()
+	log.Info("hello", "foo", true)
+	log.WithName("multi").Info("line 1\nline 2")
+
+	for _, want := range []string{
+		"PRIORITY=6\nSYSLOG_IDENTIFIER=test\nMESSAGE=INFO\thello\t{\"v\": 0, \"foo\": true}\n",
+		"PRIORITY=6\nSYSLOG_IDENTIFIER=test\nMESSAGE\n\x21\x00\x00\x00\x00\x00\x00\x00INFO\tmulti\tline 1\nline 2\t{\"v\": 0}\n",
+	} {
+		require.Nil(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		buf := make([]byte, 1024)
+		n, _, err := conn.ReadFrom(buf)
+		require.Nil(t, err)
+		assert.Equal(t, want, string(buf[:n]))
+	}
+}
+
+func TestBuilder_LogToJournald_Large(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.Nil(t, err)
+	defer conn.Close()
+
+	// The message is larger than the maximum datagram size, hence the
+	// entry is passed in a file
+	msg := strings.Repeat("a", 1<<20)
+	log, closeFn := NewZap().Example().Console().NoTimestamps().logToJournald(socket, "test").BuildWithClose()
+	log.Info(msg)
+
+	require.Nil(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 1024)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	require.Nil(t, err)
+	assert.Zero(t, n)
+
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	require.Nil(t, err)
+	require.Len(t, msgs, 1)
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	require.Nil(t, err)
+	require.Len(t, fds, 1)
+	f := os.NewFile(uintptr(fds[0]), "journald")
+	defer f.Close()
+
+	// The file offset is shared with the writer, read from the start
+	data, err := io.ReadAll(io.NewSectionReader(f, 0, 2<<20))
+	require.Nil(t, err)
+	assert.Equal(t, "PRIORITY=6\nSYSLOG_IDENTIFIER=test\nMESSAGE="+"INFO\t"+msg+"\t{\"v\": 0}\n", string(data))
+
+	require.Nil(t, closeFn())
+}
+
+func TestBuilder_LogToJournald_Close(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenPacket("unixgram", socket)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	b := NewZap().Example().Console().logToJournald(socket, "test")
+	log, closeFn := b.BuildWithClose()
+	w := b.priorityOut.(*journaldWriter)
+
+	log.Info("hello")
+	assert.NotNil(t, w.conn)
+
+	// Syncing closes the connection, it's reconnected upon the next write
+	require.Nil(t, log.GetSink().(zapr.Underlier).GetUnderlying().Sync())
+	assert.Nil(t, w.conn)
+	log.Info("hello again")
+	assert.NotNil(t, w.conn)
+
+	require.Nil(t, closeFn())
+	assert.Nil(t, w.conn)
+
+	for _, want := range []string{"hello", "hello again"} {
+		require.Nil(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		buf := make([]byte, 1024)
+		n, _, err := conn.ReadFrom(buf)
+		require.Nil(t, err)
+		assert.Equal(t, "PRIORITY=6\nSYSLOG_IDENTIFIER=test\nMESSAGE=INFO\t"+want+"\t{\"v\": 0}\n", string(buf[:n]))
+	}
+}
+
+func TestJournaldTempFile(t *testing.T) {
+	f, err := journaldTempFile([]byte("MESSAGE=hello\n"))
+	require.Nil(t, err)
+	defer f.Close()
+
+	// journald only accepts unlinked files
+	fi, err := f.Stat()
+	require.Nil(t, err)
+	assert.Zero(t, fi.Sys().(*syscall.Stat_t).Nlink)
+
+	data, err := io.ReadAll(io.NewSectionReader(f, 0, 1024))
+	require.Nil(t, err)
+	assert.Equal(t, "MESSAGE=hello\n", string(data))
+}
@@ -0,0 +1,164 @@
+package zaplog
+
+import (
+	"path"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// WithNameLevels overrides the logr level of the loggers whose name matches
+// a pattern, e.g. for making a specific component log at a higher verbosity
+// than the global level given to LogUpto. As the names of spans started by
+// the tracing package become logger names, this also applies to spans.
+//
+// The patterns are matched against the full, dot-separated logger name using
+// path.Match, e.g. "*Decoder" matches all logger names ending with "Decoder".
+// A pattern without wildcards also matches the loggers named by WithName on
+// a matching logger, e.g. "foo" matches both "foo" and "foo.bar". If several
+// patterns match, the most verbose level is used.
+//
+// The name levels apply to the destinations of this Builder, and not to the
+// destinations of the Builders given to Tee. Negative levels and malformed
+// patterns are ignored by Build, and make BuildE return an error.
+//
+// A call to this function adds to the previous values; the level of an
+// already registered pattern is overwritten.
+func (b *Builder) WithNameLevels(levels map[string]int8) *Builder {
+	for pattern, level := range levels {
+		if level < 0 {
+			b.errs = append(b.errs, ErrNegativeLevel)
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			b.errs = append(b.errs, err)
+			continue
+		}
+		if b.nameLevels == nil {
+			b.nameLevels = make(map[string]int8, len(levels))
+		}
+		b.nameLevels[pattern] = level
+	}
+	return b
+}
+
+// newNameLevelsEnabler returns a zapcore.LevelEnabler that enables the levels
+// of level, and all levels up to the most verbose level of nameLevels.
+func newNameLevelsEnabler(level zapcore.LevelEnabler, nameLevels map[string]int8) zapcore.LevelEnabler {
+	mostVerbose := int8(0)
+	for _, lvl := range nameLevels {
+		if lvl > mostVerbose {
+			mostVerbose = lvl
+		}
+	}
+	nameLevel := toZapLevel(mostVerbose)
+	return zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+		return lvl >= nameLevel || level.Enabled(lvl)
+	})
+}
+
+// matchNameLevel returns the most verbose level of the patterns in nameLevels
+// that match name, and whether any pattern matched.
+func matchNameLevel(nameLevels map[string]int8, name string) (int8, bool) {
+	level, found := int8(0), false
+	for pattern, lvl := range nameLevels {
+		if !matchName(pattern, name) {
+			continue
+		}
+		if !found || lvl > level {
+			level, found = lvl, true
+		}
+	}
+	return level, found
+}
+
+// matchName returns whether the logger name matches pattern, or whether the
+// name is a child of pattern, if pattern doesn't contain wildcards.
+func matchName(pattern, name string) bool {
+	if ok, _ := path.Match(pattern, name); ok {
+		return true
+	}
+	return !strings.ContainsAny(pattern, `*?[\`) && strings.HasPrefix(name, pattern+".")
+}
+
+func newNameLevelsSink(sink logr.LogSink, level zapcore.LevelEnabler, nameLevels map[string]int8) logr.LogSink {
+	return &nameLevelsSink{
+		// Info and Error are promoted from the embedded LogSink, and the
+		// generated wrapper methods don't show up as stack frames, hence
+		// no extra call depth is needed.
+		LogSink:    sink,
+		level:      level,
+		nameLevels: nameLevels,
+	}
+}
+
+// nameLevelsSink is a composite logr.LogSink that decides whether a log level is
+// enabled based on the logger name, as zapcore.Core doesn't know the logger name
+// in Enabled.
+type nameLevelsSink struct {
+	// embedding is important; this automatically exposes all inherited functionality from the
+	// underlying resource.
+	logr.LogSink
+
+	level      zapcore.LevelEnabler
+	nameLevels map[string]int8
+	name       string
+	// nameLevel is the level of the name, if hasNameLevel is true.
+	nameLevel    int8
+	hasNameLevel bool
+}
+
+// Assert that nameLevelsSink supports call depths and exposes the zap.Logger.
+var (
+	_ logr.CallDepthLogSink = &nameLevelsSink{}
+	_ zapr.Underlier        = &nameLevelsSink{}
+)
+
+// Init is a no-op, as the underlying LogSink has already been initialized.
+func (s *nameLevelsSink) Init(logr.RuntimeInfo) {}
+
+func (s *nameLevelsSink) Enabled(level int) bool {
+	if s.hasNameLevel {
+		return level <= int(s.nameLevel)
+	}
+	return s.level.Enabled(zapcore.Level(-level))
+}
+
+func (s *nameLevelsSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	newSink := *s
+	newSink.LogSink = s.LogSink.WithValues(keysAndValues...)
+	return &newSink
+}
+
+func (s *nameLevelsSink) WithName(name string) logr.LogSink {
+	newSink := *s
+	newSink.LogSink = s.LogSink.WithName(name)
+	if len(s.name) != 0 {
+		name = s.name + "." + name
+	}
+	newSink.name = name
+	newSink.nameLevel, newSink.hasNameLevel = matchNameLevel(s.nameLevels, name)
+	return &newSink
+}
+
+func (s *nameLevelsSink) WithCallDepth(depth int) logr.LogSink {
+	newSink := *s
+	newSink.LogSink = withCallDepth(s.LogSink, depth)
+	return &newSink
+}
+
+// GetUnderlying returns the underlying zap.Logger.
+func (s *nameLevelsSink) GetUnderlying() *zap.Logger {
+	return s.LogSink.(zapr.Underlier).GetUnderlying()
+}
+
+// withCallDepth returns sink with the given call depth, if sink supports it.
+func withCallDepth(sink logr.LogSink, depth int) logr.LogSink {
+	if depthSink, ok := sink.(logr.CallDepthLogSink); ok {
+		return depthSink.WithCallDepth(depth)
+	}
+	return sink
+}
@@ -0,0 +1,52 @@
+package zaplog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder_WithNameLevels(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewZap().Example().LogTo(&buf).LogUpto(1).
+		WithNameLevels(map[string]int8{"*Decoder": 3, "foo": 2, "quiet": 0}).
+		Build()
+
+	enabled := func(name string, level int) bool {
+		l := log
+		if len(name) != 0 {
+			l = l.WithName(name)
+		}
+		return l.V(level).Enabled()
+	}
+	assert.True(t, enabled("", 1))
+	assert.False(t, enabled("", 2))
+	assert.True(t, enabled("yamlDecoder", 3))
+	assert.False(t, enabled("yamlDecoder", 4))
+	assert.True(t, enabled("foo", 2))
+	assert.False(t, enabled("foo", 3))
+	assert.False(t, enabled("foobar", 2))
+	assert.False(t, enabled("quiet", 1))
+
+	// Children of a pattern without wildcards, and of a logger with a name level
+	log.WithName("foo").WithName("bar").V(2).Info("child of foo")
+	log.WithName("foo").WithName("bar").V(3).Info("too verbose, ignored")
+	log.WithName("foo").WithValues("a", 1).WithName("jsonDecoder").V(3).Info("most verbose level of all matching")
+	log.WithName("other").V(2).Info("too verbose, ignored")
+	log.WithName("quiet").V(1).Info("too verbose, ignored")
+	log.WithName("quiet").Error(nil, "errors are always logged")
+
+	assert.Equal(t, `{"level":"debug","logger":"foo.bar","msg":"child of foo","v":2}
+{"level":"debug","logger":"foo.jsonDecoder","msg":"most verbose level of all matching","a":1,"v":3}
+{"level":"error","logger":"quiet","msg":"errors are always logged"}
+`, buf.String())
+}
+
+func TestBuilder_WithNameLevels_invalid(t *testing.T) {
+	_, err := NewZap().WithNameLevels(map[string]int8{"foo": -1}).BuildE()
+	assert.ErrorIs(t, err, ErrNegativeLevel)
+
+	_, err = NewZap().WithNameLevels(map[string]int8{"[": 1}).BuildE()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,440 @@
+package zaplog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogsServiceClient is a symbolic link to the gRPC client of the OpenTelemetry
+// logs collector service. It can be created from a gRPC connection using
+// go.opentelemetry.io/proto/otlp/collector/logs/v1.NewLogsServiceClient.
+type LogsServiceClient = collogspb.LogsServiceClient
+
+const (
+	// OTLPInstrumentationName is the name of the instrumentation library that is
+	// registered for log records exported using the OpenTelemetry logs protocol.
+	OTLPInstrumentationName = "github.com/luxas/deklarative/tracing/zaplog"
+
+	// otlpMaxBatchSize is the amount of log records that are buffered before
+	// the batch is queued for export.
+	otlpMaxBatchSize = 512
+	// otlpMaxQueueSize is the amount of batches that may be queued for export.
+	// Further batches are dropped until the queue has room again.
+	otlpMaxQueueSize = 8
+	// otlpFlushInterval is how often the buffered log records are exported.
+	otlpFlushInterval = 5 * time.Second
+	// otlpExportTimeout is the maximum amount of time one export may take.
+	otlpExportTimeout = 10 * time.Second
+)
+
+// ErrOTLPQueueFull is returned by the core created by NewOTLPCore when a batch
+// of log records is dropped, as the export queue is full.
+var ErrOTLPQueueFull = errors.New("zaplog: the OTLP export queue is full")
+
+// ExportOTLP makes the logger also ship every enabled log entry to an
+// OpenTelemetry collector using the OpenTelemetry logs protocol (OTLP), such
+// that logs and traces land in the same pipeline. The log records are exported
+// using client, and are registered to belong to res, which may be nil.
+//
+// See NewOTLPCore for how log records are created, correlated with spans, and
+// when they are exported.
+//
+// A call to this function appends to the list of previous values.
+func (b *Builder) ExportOTLP(client LogsServiceClient, res *resource.Resource) *Builder {
+	b.otlpExporters = append(b.otlpExporters, newOTLPExporter(client, res))
+	return b
+}
+
+// NewOTLPCore returns a zapcore.Core that ships the log entries enabled by level
+// to an OpenTelemetry collector using client. The log records are registered to
+// belong to res, which may be nil.
+//
+// The fields of the log entry are registered as attributes of the log record,
+// except for fields holding a SpanRef, a trace.SpanContext or a trace.Span.
+// Such fields instead set the trace and span IDs of the log record, such that
+// the log record is correlated with the span. The tracing package attaches a
+// SpanRef to the loggers of its spans, other spans can be attached using e.g.
+// log.WithValues("span", span).
+//
+// Log records are buffered, and batches of them are exported in the background
+// when the buffer is full, when an entry at the error level or above is logged,
+// and periodically every five seconds. Logging never blocks on the export. At
+// most eight batches are queued for export; if the collector can't keep up,
+// further batches are dropped, and ErrOTLPQueueFull is returned from Write,
+// which zap reports to its error output. Errors from the background exports
+// are reported to the global OpenTelemetry error handler, see otel.Handle.
+//
+// Syncing the core exports all buffered and queued log records, and returns
+// the export errors. The background goroutine is started upon the first write;
+// it's stopped by the close function returned from Builder.BuildWithClose.
+func NewOTLPCore(client LogsServiceClient, level zapcore.LevelEnabler, res *resource.Resource) zapcore.Core {
+	return newOTLPCore(newOTLPExporter(client, res), level)
+}
+
+func newOTLPCore(exp *otlpExporter, level zapcore.LevelEnabler) zapcore.Core {
+	return &otlpCore{LevelEnabler: level, exp: exp}
+}
+
+// otlpCore is a zapcore.Core that converts log entries into OTLP log records.
+type otlpCore struct {
+	zapcore.LevelEnabler
+	exp    *otlpExporter
+	fields []zapcore.Field
+}
+
+func (c *otlpCore) With(fields []zapcore.Field) zapcore.Core {
+	newCore := *c
+	newCore.fields = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	newCore.fields = append(newCore.fields, c.fields...)
+	newCore.fields = append(newCore.fields, fields...)
+	return &newCore
+}
+
+func (c *otlpCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *otlpCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	record := &logspb.LogRecord{
+		TimeUnixNano:   uint64(ent.Time.UnixNano()),
+		SeverityNumber: toSeverityNumber(ent.Level),
+		SeverityText:   capitalLevelString(ent.Level),
+		Body:           toAnyValue(ent.Message),
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	if len(ent.LoggerName) != 0 {
+		enc.AddString(zap.NewProductionEncoderConfig().NameKey, ent.LoggerName)
+	}
+	for _, fieldList := range [][]zapcore.Field{c.fields, fields} {
+		for _, f := range fieldList {
+			if sc, ok := toSpanContext(f.Interface); ok {
+				traceID, spanID := sc.TraceID(), sc.SpanID()
+				record.TraceId, record.SpanId = traceID[:], spanID[:]
+				record.Flags = uint32(sc.TraceFlags())
+				continue
+			}
+			if isSpanRef(f) {
+				continue
+			}
+			f.AddTo(enc)
+		}
+	}
+	if len(ent.Stack) != 0 {
+		enc.AddString(StacktraceKey, ent.Stack)
+	}
+	record.Attributes = toKeyValues(enc.Fields)
+
+	return c.exp.add(record, ent.Level >= zap.ErrorLevel)
+}
+
+func (c *otlpCore) Sync() error { return c.exp.flush() }
+
+// toSpanContext returns the span context of obj, if obj is a SpanRef, a
+// trace.SpanContext or a trace.Span.
+func toSpanContext(obj interface{}) (trace.SpanContext, bool) {
+	var sc trace.SpanContext
+	switch o := obj.(type) {
+	case SpanRef:
+		sc = o.SpanContext
+	case trace.SpanContext:
+		sc = o
+	case trace.Span:
+		sc = o.SpanContext()
+	default:
+		return sc, false
+	}
+	return sc, sc.IsValid()
+}
+
+// otlpExporter buffers log records, and exports batches of them in the
+// background using the client.
+type otlpExporter struct {
+	client   LogsServiceClient
+	resource *resource.Resource
+
+	maxBatchSize  int
+	flushInterval time.Duration
+
+	queue   chan []*logspb.LogRecord
+	flushCh chan chan error
+	stopCh  chan struct{}
+	stopped chan struct{}
+	// stopErr holds the export errors when stopping, it's set before stopped
+	// is closed.
+	stopErr error
+
+	mu    sync.Mutex
+	batch []*logspb.LogRecord
+	// running is true when the goroutine exporting the batches is started.
+	running bool
+	// closed is true when the exporter has been stopped.
+	closed bool
+}
+
+func newOTLPExporter(client LogsServiceClient, res *resource.Resource) *otlpExporter {
+	return &otlpExporter{
+		client:        client,
+		resource:      res,
+		maxBatchSize:  otlpMaxBatchSize,
+		flushInterval: otlpFlushInterval,
+		queue:         make(chan []*logspb.LogRecord, otlpMaxQueueSize),
+		flushCh:       make(chan chan error),
+		stopCh:        make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+}
+
+// add buffers record, and queues the buffered records for export if the
+// buffer is full, or if flush is true. An error wrapping ErrOTLPQueueFull is
+// returned if the batch is dropped.
+func (e *otlpExporter) add(record *logspb.LogRecord, flush bool) error {
+	e.mu.Lock()
+	if !e.running && !e.closed {
+		e.running = true
+		go e.run()
+	}
+	e.batch = append(e.batch, record)
+	if !flush && len(e.batch) < e.maxBatchSize {
+		e.mu.Unlock()
+		return nil
+	}
+	batch := e.takeBatchLocked()
+	e.mu.Unlock()
+
+	select {
+	case e.queue <- batch:
+		return nil
+	default:
+		return fmt.Errorf("%w: dropped a batch of %d log records", ErrOTLPQueueFull, len(batch))
+	}
+}
+
+// run exports the queued batches, and the buffered records periodically,
+// until the exporter is stopped.
+func (e *otlpExporter) run() {
+	defer close(e.stopped)
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case batch := <-e.queue:
+			if err := e.export(batch); err != nil {
+				otel.Handle(err)
+			}
+		case <-ticker.C:
+			if err := e.export(e.takeBatch()); err != nil {
+				otel.Handle(err)
+			}
+		case errCh := <-e.flushCh:
+			errCh <- e.drain()
+		case <-e.stopCh:
+			e.stopErr = e.drain()
+			return
+		}
+	}
+}
+
+// flush exports all queued and buffered log records, and returns the export
+// errors. It's a no-op if nothing has been logged, or if the exporter has
+// been stopped.
+func (e *otlpExporter) flush() error {
+	e.mu.Lock()
+	running := e.running && !e.closed
+	e.mu.Unlock()
+	if !running {
+		return nil
+	}
+
+	errCh := make(chan error, 1)
+	select {
+	case e.flushCh <- errCh:
+		return <-errCh
+	case <-e.stopped:
+		return nil
+	}
+}
+
+// stop exports all queued and buffered log records, stops the goroutine
+// exporting them, and returns the export errors.
+func (e *otlpExporter) stop() error {
+	e.mu.Lock()
+	running, closed := e.running, e.closed
+	e.closed = true
+	e.mu.Unlock()
+	if !running || closed {
+		return nil
+	}
+
+	close(e.stopCh)
+	<-e.stopped
+	return e.stopErr
+}
+
+// drain exports the queued batches and the buffered records, and returns the
+// export errors.
+func (e *otlpExporter) drain() error {
+	var errs []error
+	for {
+		select {
+		case batch := <-e.queue:
+			errs = append(errs, e.export(batch))
+		default:
+			errs = append(errs, e.export(e.takeBatch()))
+			return multierr.Combine(errs...)
+		}
+	}
+}
+
+// takeBatch returns the buffered records, and resets the buffer.
+func (e *otlpExporter) takeBatch() []*logspb.LogRecord {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.takeBatchLocked()
+}
+
+// takeBatchLocked is like takeBatch, but requires e.mu to be held.
+func (e *otlpExporter) takeBatchLocked() []*logspb.LogRecord {
+	batch := e.batch
+	e.batch = nil
+	return batch
+}
+
+// export exports batch using the client.
+func (e *otlpExporter) export(batch []*logspb.LogRecord) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	res := &resourcepb.Resource{}
+	if e.resource != nil {
+		for _, kv := range e.resource.Attributes() {
+			res.Attributes = append(res.Attributes, &commonpb.KeyValue{
+				Key:   string(kv.Key),
+				Value: toAnyValue(kv.Value.AsInterface()),
+			})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), otlpExportTimeout)
+	defer cancel()
+
+	_, err := e.client.Export(ctx, &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: res,
+			InstrumentationLibraryLogs: []*logspb.InstrumentationLibraryLogs{{
+				InstrumentationLibrary: &commonpb.InstrumentationLibrary{
+					Name: OTLPInstrumentationName,
+				},
+				Logs: batch,
+			}},
+		}},
+	})
+	return err
+}
+
+// capitalLevelString returns the same string as CapitalLevelEncoder encodes
+// for level l.
+func capitalLevelString(l zapcore.Level) string {
+	if l < zap.DebugLevel {
+		l = zap.DebugLevel
+	}
+	return l.CapitalString()
+}
+
+// toSeverityNumber converts a zap level to an OTLP severity number. All levels
+// more verbose than the debug level are converted to the debug severity.
+func toSeverityNumber(l zapcore.Level) logspb.SeverityNumber {
+	switch {
+	case l <= zap.DebugLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG
+	case l == zap.InfoLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	case l == zap.WarnLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_WARN
+	case l == zap.ErrorLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	default:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL
+	}
+}
+
+// toKeyValues converts the fields of a zapcore.MapObjectEncoder to OTLP key
+// values, sorted by key for deterministic output.
+func toKeyValues(fields map[string]interface{}) []*commonpb.KeyValue {
+	kvs := make([]*commonpb.KeyValue, 0, len(fields))
+	for key, val := range fields {
+		kvs = append(kvs, &commonpb.KeyValue{Key: key, Value: toAnyValue(val)})
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs
+}
+
+// toAnyValue converts a value encoded by a zapcore.MapObjectEncoder to an OTLP
+// value. Values of unknown types are converted to strings.
+func toAnyValue(obj interface{}) *commonpb.AnyValue {
+	switch v := obj.(type) {
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case int:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case int8:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case int16:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case int32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case int64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v}}
+	case uint:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case uint8:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case uint16:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case uint32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case uint64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case float32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: float64(v)}}
+	case float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v}}
+	case []byte:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v}}
+	case []interface{}:
+		arr := &commonpb.ArrayValue{Values: make([]*commonpb.AnyValue, 0, len(v))}
+		for _, elem := range v {
+			arr.Values = append(arr.Values, toAnyValue(elem))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: arr}}
+	case map[string]interface{}:
+		kvList := &commonpb.KeyValueList{Values: toKeyValues(v)}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: kvList}}
+	case time.Time:
+		return toAnyValue(v.Format(time.RFC3339Nano))
+	default:
+		return toAnyValue(fmt.Sprint(v))
+	}
+}
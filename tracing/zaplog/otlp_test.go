@@ -0,0 +1,209 @@
+package zaplog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/zapr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+)
+
+type fakeLogsServiceClient struct {
+	// block makes Export block until it's closed, if set.
+	block chan struct{}
+
+	mu       sync.Mutex
+	requests []*collogspb.ExportLogsServiceRequest
+}
+
+func (c *fakeLogsServiceClient) Export(_ context.Context, in *collogspb.ExportLogsServiceRequest, _ ...grpc.CallOption) (*collogspb.ExportLogsServiceResponse, error) {
+	if c.block != nil {
+		<-c.block
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, in)
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func (c *fakeLogsServiceClient) records() []*logspb.LogRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var records []*logspb.LogRecord
+	for _, req := range c.requests {
+		for _, rl := range req.ResourceLogs {
+			for _, ill := range rl.InstrumentationLibraryLogs {
+				records = append(records, ill.Logs...)
+			}
+		}
+	}
+	return records
+}
+
+func attrs(kvs []*commonpb.KeyValue) map[string]interface{} {
+	m := make(map[string]interface{}, len(kvs))
+	for _, kv := range kvs {
+		switch v := kv.Value.Value.(type) {
+		case *commonpb.AnyValue_StringValue:
+			m[kv.Key] = v.StringValue
+		case *commonpb.AnyValue_IntValue:
+			m[kv.Key] = v.IntValue
+		case *commonpb.AnyValue_BoolValue:
+			m[kv.Key] = v.BoolValue
+		}
+	}
+	return m
+}
+
+func TestBuilder_ExportOTLP(t *testing.T) {
+	client := &fakeLogsServiceClient{}
+	res := resource.NewSchemaless(attribute.String("service.name", "foo"))
+	log := NewZap().LogTo(io.Discard).LogUpto(1).ExportOTLP(client, res).Build().WithName("bar")
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	log.Info("hello", "foo", true)
+	log.WithValues("span", sc).V(1).Info("debug", "count", 3)
+	log.V(2).Info("too verbose, ignored")
+	// Records are buffered until synced
+	assert.Empty(t, client.records())
+
+	// Errors are exported right away in the background
+	log.Error(errors.New("unexpected error"), "error message") //nolint:goerr113
+
+	assert.Eventually(t, func() bool { return len(client.records()) == 3 }, 5*time.Second, time.Millisecond)
+	records := client.records()
+	require.Len(t, records, 3)
+
+	assert.Equal(t, "hello", records[0].Body.GetStringValue())
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_INFO, records[0].SeverityNumber)
+	assert.Equal(t, "INFO", records[0].SeverityText)
+	assert.Equal(t, map[string]interface{}{"logger": "bar", "v": int64(0), "foo": true}, attrs(records[0].Attributes))
+	assert.Empty(t, records[0].TraceId)
+
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, records[1].SeverityNumber)
+	assert.Equal(t, "DEBUG", records[1].SeverityText)
+	assert.Equal(t, map[string]interface{}{"logger": "bar", "v": int64(1), "count": int64(3)}, attrs(records[1].Attributes))
+	traceID, spanID := sc.TraceID(), sc.SpanID()
+	assert.Equal(t, traceID[:], records[1].TraceId)
+	assert.Equal(t, spanID[:], records[1].SpanId)
+	assert.Equal(t, uint32(trace.FlagsSampled), records[1].Flags)
+
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, records[2].SeverityNumber)
+	assert.Equal(t, "unexpected error", attrs(records[2].Attributes)["error"])
+	assert.Contains(t, attrs(records[2].Attributes), StacktraceKey)
+
+	client.mu.Lock()
+	rl := client.requests[0].ResourceLogs[0]
+	client.mu.Unlock()
+	assert.Equal(t, "service.name", rl.Resource.Attributes[0].Key)
+	assert.Equal(t, "foo", rl.Resource.Attributes[0].Value.GetStringValue())
+	assert.Equal(t, OTLPInstrumentationName, rl.InstrumentationLibraryLogs[0].InstrumentationLibrary.Name)
+
+	// Syncing exports buffered records
+	log.Info("synced")
+	require.Len(t, client.records(), 3)
+	require.Nil(t, log.GetSink().(zapr.Underlier).GetUnderlying().Sync())
+	require.Len(t, client.records(), 4)
+}
+
+func TestOTLPExporter_FlushInterval(t *testing.T) {
+	client := &fakeLogsServiceClient{}
+	exp := newOTLPExporter(client, nil)
+	exp.flushInterval = 10 * time.Millisecond
+	core := newOTLPCore(exp, zap.InfoLevel)
+
+	require.Nil(t, core.Write(zapcore.Entry{Message: "hello"}, nil))
+	// The buffered record is exported periodically, without syncing
+	assert.Eventually(t, func() bool { return len(client.records()) == 1 }, 5*time.Second, time.Millisecond)
+
+	require.Nil(t, exp.stop())
+}
+
+func TestOTLPExporter_QueueFull(t *testing.T) {
+	client := &fakeLogsServiceClient{block: make(chan struct{})}
+	exp := newOTLPExporter(client, nil)
+	exp.maxBatchSize = 1
+	exp.queue = make(chan []*logspb.LogRecord, 1)
+	core := newOTLPCore(exp, zap.InfoLevel)
+
+	// The first batch is taken by the exporting goroutine, which blocks, and
+	// the second one fills the queue
+	require.Nil(t, core.Write(zapcore.Entry{Message: "first"}, nil))
+	assert.Eventually(t, func() bool { return len(exp.queue) == 0 }, 5*time.Second, time.Millisecond)
+	require.Nil(t, core.Write(zapcore.Entry{Message: "second"}, nil))
+
+	// Further batches are dropped, and reported as such
+	err := core.Write(zapcore.Entry{Message: "third"}, nil)
+	assert.ErrorIs(t, err, ErrOTLPQueueFull)
+	assert.EqualError(t, err, "zaplog: the OTLP export queue is full: dropped a batch of 1 log records")
+
+	// Stopping the exporter exports the queued batch
+	close(client.block)
+	require.Nil(t, exp.stop())
+	records := client.records()
+	require.Len(t, records, 2)
+	assert.Equal(t, "first", records[0].Body.GetStringValue())
+	assert.Equal(t, "second", records[1].Body.GetStringValue())
+
+	// Stopping and syncing again is a no-op
+	assert.Nil(t, exp.stop())
+	assert.Nil(t, core.Sync())
+}
+
+func TestBuilder_ExportOTLP_Close(t *testing.T) {
+	client := &fakeLogsServiceClient{}
+	log, closeFn := NewZap().LogTo(io.Discard).ExportOTLP(client, nil).BuildWithClose()
+
+	log.Info("hello")
+	assert.Empty(t, client.records())
+
+	// Closing the logger exports the buffered records
+	require.Nil(t, closeFn())
+	records := client.records()
+	require.Len(t, records, 1)
+	assert.Equal(t, "hello", records[0].Body.GetStringValue())
+}
+
+func TestBuilder_ExportOTLP_SpanRef(t *testing.T) {
+	client := &fakeLogsServiceClient{}
+	var buf bytes.Buffer
+	log := NewZap().Example().LogTo(&buf).ExportOTLP(client, nil).Build()
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:  trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+	})
+	log.WithValues(SpanRefKey, SpanRef{Name: "foo", SpanContext: sc}).Info("hello", "bar", true)
+
+	// The SpanRef isn't written to the destination
+	assert.Equal(t, `{"level":"info","msg":"hello","v":0,"bar":true}`+"\n", buf.String())
+
+	// But correlates the exported log record with the span
+	require.Nil(t, log.GetSink().(zapr.Underlier).GetUnderlying().Sync())
+	records := client.records()
+	require.Len(t, records, 1)
+	traceID, spanID := sc.TraceID(), sc.SpanID()
+	assert.Equal(t, traceID[:], records[0].TraceId)
+	assert.Equal(t, spanID[:], records[0].SpanId)
+	assert.Equal(t, map[string]interface{}{"v": int64(0), "bar": true}, attrs(records[0].Attributes))
+}
@@ -0,0 +1,103 @@
+package zaplog
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// priority is a syslog severity, as defined in RFC 5424. It's used by both
+// syslog and systemd-journald.
+type priority int
+
+const (
+	priorityEmerg priority = iota
+	priorityAlert
+	priorityCrit
+	priorityErr
+	priorityWarning
+	priorityNotice
+	priorityInfo
+	priorityDebug
+)
+
+// toPriority maps a zap level to a syslog priority. All levels more verbose than
+// the debug level are mapped to the debug priority.
+func toPriority(l zapcore.Level) priority {
+	switch {
+	case l <= zap.DebugLevel:
+		return priorityDebug
+	case l == zap.InfoLevel:
+		return priorityInfo
+	case l == zap.WarnLevel:
+		return priorityWarning
+	case l == zap.ErrorLevel:
+		return priorityErr
+	case l == zap.DPanicLevel:
+		return priorityCrit
+	case l == zap.PanicLevel:
+		return priorityAlert
+	default:
+		return priorityEmerg
+	}
+}
+
+// priorityWriter writes a log message with the given priority, e.g. to syslog
+// or systemd-journald.
+type priorityWriter interface {
+	writePriority(p priority, msg string) error
+}
+
+// priorityCloser is implemented by priorityWriters holding a connection, which
+// is closed when the priorityCore is synced or the logger is closed. The
+// connection is reopened upon the next write.
+type priorityCloser interface {
+	close() error
+}
+
+// priorityCore is a zapcore.Core that encodes log entries like the core created
+// by zapcore.NewCore, but writes the message to a priorityWriter along with the
+// priority mapped from the level of the entry.
+type priorityCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	out priorityWriter
+}
+
+func (c *priorityCore) With(fields []zapcore.Field) zapcore.Core {
+	newCore := *c
+	newCore.enc = c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(newCore.enc)
+	}
+	return &newCore
+}
+
+func (c *priorityCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *priorityCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	// Both syslog and journald delimit the messages themselves
+	msg := strings.TrimSuffix(buf.String(), "\n")
+	buf.Free()
+	return c.out.writePriority(toPriority(ent.Level), msg)
+}
+
+func (c *priorityCore) Sync() error { return c.close() }
+
+// close closes the connection of the priorityWriter, if it holds one.
+func (c *priorityCore) close() error {
+	if closer, ok := c.out.(priorityCloser); ok {
+		return closer.close()
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package zaplog
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// RedactedValue replaces the values of the fields redacted using Redact.
+	RedactedValue = "[REDACTED]"
+
+	// spanAttributePrefix is the prefix the tracing package uses for logging
+	// span attributes, i.e. tracing.SpanAttributePrefix. It can't be referenced
+	// directly, as the tracing package imports this package.
+	spanAttributePrefix = "span-attr-"
+)
+
+// Redact masks the values of the structured fields with the given keys using
+// RedactedValue, before they are written to any destination. This applies both
+// to keysAndValues given to the logr.Logger directly, and to span attributes
+// logged by the tracing package, e.g. span.SetAttributes(attribute.String("password", ...))
+// is logged as "span-attr-password":"[REDACTED]" when "password" is redacted.
+//
+// Only top-level fields are matched; the values of nested objects and maps
+// are not inspected.
+//
+// A call to this function appends to the list of previous values.
+func (b *Builder) Redact(keys ...string) *Builder {
+	if b.redactKeys == nil {
+		b.redactKeys = make(map[string]struct{}, len(keys))
+	}
+	for _, key := range keys {
+		b.redactKeys[key] = struct{}{}
+	}
+	return b
+}
+
+func newRedactCore(core zapcore.Core, keys map[string]struct{}) zapcore.Core {
+	return &redactCore{Core: core, keys: keys}
+}
+
+// redactCore is a composite zapcore.Core that masks the values of the fields
+// with the given keys, before passing the fields to the underlying core.
+type redactCore struct {
+	// embedding is important; this automatically exposes all inherited functionality from the
+	// underlying resource.
+	zapcore.Core
+
+	keys map[string]struct{}
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return newRedactCore(c.Core.With(c.redact(fields)), c.keys)
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.redact(fields))
+}
+
+// redact returns fields with the values of the redacted fields replaced. The
+// given slice is not mutated, as it might be reused by the caller.
+func (c *redactCore) redact(fields []zapcore.Field) []zapcore.Field {
+	var redacted []zapcore.Field
+	for i, f := range fields {
+		if !c.matches(f.Key) {
+			continue
+		}
+		if redacted == nil {
+			redacted = make([]zapcore.Field, len(fields))
+			copy(redacted, fields)
+		}
+		redacted[i] = zap.String(f.Key, RedactedValue)
+	}
+	if redacted == nil {
+		return fields
+	}
+	return redacted
+}
+
+func (c *redactCore) matches(key string) bool {
+	if _, ok := c.keys[key]; ok {
+		return true
+	}
+	if strings.HasPrefix(key, spanAttributePrefix) {
+		_, ok := c.keys[strings.TrimPrefix(key, spanAttributePrefix)]
+		return ok
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package zaplog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder_Redact(t *testing.T) {
+	var buf bytes.Buffer
+	var teeBuf bytes.Buffer
+	log := NewZap().Example().LogTo(&buf).
+		Tee(NewZap().Example().LogTo(&teeBuf)).
+		Redact("password").Redact("token", "secret").
+		Build()
+
+	log.WithValues("token", "abc").Info("hello", "user", "foo", "password", "hunter2")
+	log.Info("span attribute", "span-attr-secret", 42, "span-attr-other", true, "log-attr-secret", "not a span attribute")
+	log.Error(nil, "error", "password", []string{"a", "b"})
+
+	want := `{"level":"info","msg":"hello","token":"[REDACTED]","v":0,"user":"foo","password":"[REDACTED]"}
+{"level":"info","msg":"span attribute","v":0,"span-attr-secret":"[REDACTED]","span-attr-other":true,"log-attr-secret":"not a span attribute"}
+{"level":"error","msg":"error","password":"[REDACTED]"}
+`
+	assert.Equal(t, want, buf.String())
+	assert.Equal(t, want, teeBuf.String())
+}
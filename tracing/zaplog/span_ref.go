@@ -0,0 +1,75 @@
+package zaplog
+
+import (
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+)
+
+// SpanRefKey is the key of the field holding a SpanRef.
+const SpanRefKey = "span-ref"
+
+// SpanRef identifies the span that log entries are logged in. The tracing
+// package attaches a SpanRef to the loggers of its spans, using the SpanRefKey
+// key, if the logger is created by this package.
+//
+// SpanRef fields are not written to the destinations of the logger. Instead,
+// they correlate the log records exported using ExportOTLP, and the
+// ErrorReports given to ErrorReporters, with the span.
+type SpanRef struct {
+	// Name is the name of the span.
+	Name string
+	// SpanContext identifies the trace and span.
+	SpanContext trace.SpanContext
+}
+
+// isSpanRef returns true if f holds a SpanRef.
+func isSpanRef(f zapcore.Field) bool {
+	_, ok := f.Interface.(SpanRef)
+	return ok
+}
+
+func newSpanRefCore(core zapcore.Core) zapcore.Core {
+	return &spanRefCore{Core: core}
+}
+
+// spanRefCore is a composite zapcore.Core that removes the SpanRef fields,
+// before passing the fields to the underlying core.
+type spanRefCore struct {
+	// embedding is important; this automatically exposes all inherited functionality from the
+	// underlying resource.
+	zapcore.Core
+}
+
+func (c *spanRefCore) With(fields []zapcore.Field) zapcore.Core {
+	return newSpanRefCore(c.Core.With(withoutSpanRefs(fields)))
+}
+
+func (c *spanRefCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *spanRefCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, withoutSpanRefs(fields))
+}
+
+// withoutSpanRefs returns fields without the SpanRef fields. The given slice
+// is not mutated, as it might be reused by the caller.
+func withoutSpanRefs(fields []zapcore.Field) []zapcore.Field {
+	for i, f := range fields {
+		if !isSpanRef(f) {
+			continue
+		}
+		filtered := make([]zapcore.Field, 0, len(fields)-1)
+		filtered = append(filtered, fields[:i]...)
+		for _, f := range fields[i+1:] {
+			if !isSpanRef(f) {
+				filtered = append(filtered, f)
+			}
+		}
+		return filtered
+	}
+	return fields
+}
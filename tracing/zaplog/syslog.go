@@ -0,0 +1,60 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package zaplog
+
+import "log/syslog"
+
+// LogToSyslog makes the logger write logs to syslog using w. The syslog
+// priority of each entry is mapped from its level as follows:
+//
+//	Zap	Syslog
+//	Debug	LOG_DEBUG (all logr levels >= 1)
+//	Info	LOG_INFO
+//	Warn	LOG_WARNING
+//	Error	LOG_ERR
+//	DPanic	LOG_CRIT
+//	Panic	LOG_ALERT
+//	Fatal	LOG_EMERG
+//
+// The facility and tag are the ones w was created with, e.g. using
+// syslog.Dial. As syslog timestamps the messages, NoTimestamps() might be
+// desired.
+//
+// A call to this function overwrites any previous value, including the
+// destination set by LogTo.
+func (b *Builder) LogToSyslog(w *syslog.Writer) *Builder {
+	if w == nil {
+		b.errs = append(b.errs, ErrNoWriter)
+		return b
+	}
+	b.outW = nil
+	b.priorityOut = &syslogWriter{w}
+	return b
+}
+
+// syslogWriter is a priorityWriter writing to a *syslog.Writer.
+type syslogWriter struct {
+	w *syslog.Writer
+}
+
+func (s *syslogWriter) writePriority(p priority, msg string) error {
+	switch p {
+	case priorityEmerg:
+		return s.w.Emerg(msg)
+	case priorityAlert:
+		return s.w.Alert(msg)
+	case priorityCrit:
+		return s.w.Crit(msg)
+	case priorityErr:
+		return s.w.Err(msg)
+	case priorityWarning:
+		return s.w.Warning(msg)
+	case priorityNotice:
+		return s.w.Notice(msg)
+	case priorityInfo:
+		return s.w.Info(msg)
+	default:
+		return s.w.Debug(msg)
+	}
+}
@@ -0,0 +1,49 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package zaplog
+
+import (
+	"errors"
+	"fmt"
+	"log/syslog"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder_LogToSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer conn.Close()
+
+	w, err := syslog.Dial("udp", conn.LocalAddr().String(), syslog.LOG_USER, "test")
+	require.Nil(t, err)
+	defer w.Close()
+
+	log := NewZap().Example().NoStacktraceOnError().LogUpto(1).LogToSyslog(w).Build()
+	log.V(1).Info("debug")
+	log.Info("info")
+	log.Error(errors.New("unexpected error"), "error") //nolint:goerr113
+
+	// The priority value is facility*8 + severity, the user facility is 1
+	for _, want := range []struct{ prefix, suffix string }{
+		{"<15>", `test[%d]: {"level":"debug","msg":"debug","v":1}` + "\n"},
+		{"<14>", `test[%d]: {"level":"info","msg":"info","v":0}` + "\n"},
+		{"<11>", `test[%d]: {"level":"error","msg":"error","error":"unexpected error"}` + "\n"},
+	} {
+		require.Nil(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		buf := make([]byte, 1024)
+		n, _, err := conn.ReadFrom(buf)
+		require.Nil(t, err)
+
+		msg := string(buf[:n])
+		assert.True(t, strings.HasPrefix(msg, want.prefix), msg)
+		assert.True(t, strings.HasSuffix(msg, fmt.Sprintf(want.suffix, os.Getpid())), msg)
+	}
+}
@@ -5,15 +5,22 @@ package zaplog
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
+	"errors"
 	"io"
 	"os"
-	"strconv"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
 	"github.com/luxas/deklarative/tracing/filetest"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 type (
@@ -23,6 +30,8 @@ type (
 	EncoderConfig = zapcore.EncoderConfig
 	// LevelEncoder is a symbolic link to zapcore.LevelEncoder.
 	LevelEncoder = zapcore.LevelEncoder
+	// CallerEncoder is a symbolic link to zapcore.CallerEncoder.
+	CallerEncoder = zapcore.CallerEncoder
 
 	// EncoderConfigOption represents a function that applies an option to the EncoderConfig.
 	EncoderConfigOption func(*EncoderConfig)
@@ -42,39 +51,103 @@ func ProductionEncoderConfig() EncoderConfig { return zap.NewProductionEncoderCo
 // DevelopmentEncoderConfig is a symbolic link to zap.NewDevelopmentEncoderConfig().
 func DevelopmentEncoderConfig() EncoderConfig { return zap.NewDevelopmentEncoderConfig() }
 
+var (
+	// ErrNegativeLevel is returned when trying to set a negative logr level.
+	ErrNegativeLevel = errors.New("zaplog: the logr level must not be negative")
+	// ErrNoWriter is returned by BuildE when there is no writer to log to.
+	ErrNoWriter = errors.New("zaplog: no writer to log to")
+	// ErrNoEncoderCreator is returned by BuildE when no EncoderCreator is set.
+	ErrNoEncoderCreator = errors.New("zaplog: no EncoderCreator set")
+	// ErrNegativeBuffer is returned by BuildE when the buffer size or flush
+	// interval given to Buffered is negative.
+	ErrNegativeBuffer = errors.New("zaplog: the buffer size and flush interval must not be negative")
+)
+
+// LogrLevelKey is the key of the field that holds the logr level (verbosity)
+// of Info log entries.
+const LogrLevelKey = "v"
+
 // LowercaseLevelEncoder is the default LevelEncoder; it extends the zapcore.LowercaseLevelEncoder
-// by adding a "(v={V})" to all levels where {V} is the logr level.
-//
-// TODO: Once we can upgrade to logr v1.x, and https://github.com/go-logr/zapr/pull/37
-// has landed, we can make log levels more easily a field.
+// by encoding all levels more verbose than the debug level as "debug". The logr level
+// is registered with the LogrLevelKey field instead.
 func LowercaseLevelEncoder() LevelEncoder {
 	return func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
-		str := l.String()
 		if l < zap.DebugLevel {
-			str = "debug"
+			l = zap.DebugLevel
 		}
-		if l <= zap.InfoLevel {
-			str += "(v=" + strconv.Itoa(int(l*-1)) + ")"
-		}
-		enc.AppendString(str)
+		enc.AppendString(l.String())
 	}
 }
 
-// CapitalLevelEncoder extends the zapcore.CapitalLevelEncoder
-// by adding a "(v={V})" to all levels where {V} is the logr level.
+// CapitalLevelEncoder extends the zapcore.CapitalLevelEncoder by encoding all
+// levels more verbose than the debug level as "DEBUG". The logr level is registered
+// with the LogrLevelKey field instead.
 func CapitalLevelEncoder() LevelEncoder {
 	return func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
-		str := l.CapitalString()
 		if l < zap.DebugLevel {
-			str = "DEBUG"
+			l = zap.DebugLevel
+		}
+		enc.AppendString(l.CapitalString())
+	}
+}
+
+// CapitalColorLevelEncoder extends the zapcore.CapitalColorLevelEncoder by encoding
+// all levels more verbose than the debug level as a colored "DEBUG". The logr level
+// is registered with the LogrLevelKey field instead.
+func CapitalColorLevelEncoder() LevelEncoder {
+	return func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		if l < zap.DebugLevel {
+			l = zap.DebugLevel
+		}
+		zapcore.CapitalColorLevelEncoder(l, enc)
+	}
+}
+
+// LevelNamesEncoder returns a LevelEncoder that encodes the levels in names using
+// the given names. If the most verbose level in names is the debug level or more
+// verbose, all levels more verbose than it are encoded with its name; this way e.g.
+// all logr levels 2 and higher can be encoded as "TRACE" using {-2: "TRACE"}.
+// Other levels are encoded using fallback.
+func LevelNamesEncoder(names map[zapcore.Level]string, fallback LevelEncoder) LevelEncoder {
+	// Copy the map, such that the caller can't mutate it later
+	levelNames := make(map[zapcore.Level]string, len(names))
+	mostVerbose := zapcore.Level(0)
+	for l, name := range names {
+		levelNames[l] = name
+		if len(levelNames) == 1 || l < mostVerbose {
+			mostVerbose = l
 		}
-		if l <= zap.InfoLevel {
-			str += "(v=" + strconv.Itoa(int(l*-1)) + ")"
+	}
+
+	return func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		if name, ok := levelNames[l]; ok {
+			enc.AppendString(name)
+			return
+		}
+		if len(levelNames) != 0 && mostVerbose <= zap.DebugLevel && l < mostVerbose {
+			enc.AppendString(levelNames[mostVerbose])
+			return
 		}
-		enc.AppendString(str)
+		fallback(l, enc)
 	}
 }
 
+// StackdriverLevelEncoder encodes the levels as the severities of Google Cloud
+// Logging (formerly Stackdriver), e.g. "DEBUG", "INFO", "WARNING" and "ERROR".
+// All levels more verbose than the debug level are encoded as "DEBUG". The logr
+// level is registered with the LogrLevelKey field instead.
+func StackdriverLevelEncoder() LevelEncoder {
+	return LevelNamesEncoder(map[zapcore.Level]string{
+		zap.DebugLevel:  "DEBUG",
+		zap.InfoLevel:   "INFO",
+		zap.WarnLevel:   "WARNING",
+		zap.ErrorLevel:  "ERROR",
+		zap.DPanicLevel: "CRITICAL",
+		zap.PanicLevel:  "ALERT",
+		zap.FatalLevel:  "EMERGENCY",
+	}, CapitalLevelEncoder())
+}
+
 // NewZap returns a new *Builder using the default configuration.
 func NewZap() *Builder {
 	return (&Builder{
@@ -88,13 +161,28 @@ func NewZap() *Builder {
 // using go.uber.org/zap.
 //
 // The default configuration uses the production encoder configuration,
-// writes JSON, includes the V log levels in the level name, and logs to os.Stdout.
+// writes JSON, includes the logr level of Info log entries in the LogrLevelKey
+// field, and logs to os.Stdout.
 type Builder struct {
 	outW              io.Writer
+	priorityOut       priorityWriter
+	bufferSize        int
+	flushInterval     time.Duration
+	buffered          bool
 	encoderCfg        EncoderConfig
 	encoderCfgOptions []EncoderConfigOption
 	encoderCreator    EncoderCreator
 	level             zapcore.Level
+	atomicLevel       *AtomicLevel
+	tees              []*Builder
+	otlpExporters     []*otlpExporter
+	errorReporters    []ErrorReporter
+	errorChain        *bool
+	errorKey          string
+	redactKeys        map[string]struct{}
+	nameLevels        map[string]int8
+	errs              []error
+	fields            []interface{}
 	opts              []zap.Option
 }
 
@@ -110,6 +198,85 @@ type Builder struct {
 // A call to this function overwrites any previous value.
 func (b *Builder) LogTo(w io.Writer) *Builder {
 	b.outW = w
+	b.priorityOut = nil
+	return b
+}
+
+// LogToFile makes the logger write logs to the file at path, rotating it once
+// it grows larger than maxSizeMB megabytes. This removes the need for an external
+// logrotate setup for on-host deployments. The rotated files are named after path,
+// with a timestamp inserted before the file extension.
+//
+// At most maxBackups rotated files are retained, and rotated files older than
+// maxAgeDays days are removed. Zero values for maxBackups and maxAgeDays mean
+// that rotated files are retained regardless of count and age, respectively.
+// A zero maxSizeMB defaults to 100 megabytes.
+//
+// It corresponds to LogTo(&lumberjack.Logger{...}).
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) LogToFile(path string, maxSizeMB, maxBackups, maxAgeDays int) *Builder {
+	return b.LogTo(&lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    maxSizeMB,
+		MaxBackups: maxBackups,
+		MaxAge:     maxAgeDays,
+	})
+}
+
+// Buffered makes the logger buffer up to size bytes of log output in memory
+// before writing it to the destination configured using LogTo, which cuts
+// the syscall overhead for high-volume logging. The buffer is also flushed
+// every flushInterval, and when the logger is synced. tracing.ShutdownOnSignal
+// syncs the logger, such that no buffered logs are lost upon shutdown.
+//
+// A buffered logger must be built using BuildWithClose, and the returned close
+// function must be called once the logger isn't used anymore. It flushes the
+// buffer and stops the goroutine flushing it periodically, which otherwise
+// runs for the lifetime of the process.
+//
+// Internal errors of the logger are written directly, without buffering. Zero
+// values of size and flushInterval default to 256 kB and 30 seconds, respectively.
+//
+// It corresponds to wrapping the destination using zapcore.BufferedWriteSyncer.
+//
+// By default log output is not buffered.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) Buffered(size int, flushInterval time.Duration) *Builder {
+	b.buffered = true
+	b.bufferSize = size
+	b.flushInterval = flushInterval
+	return b
+}
+
+// Tee makes the logger write every log entry also to the destinations of the
+// given Builders, each with their own encoder and level. For example, console
+// output at level 0 and a JSON file at level 1 can be produced from one logger
+// as follows:
+//
+//	log := zaplog.NewZap().Console().
+//		Tee(zaplog.NewZap().LogToFile("debug.log", 100, 3, 7).LogUpto(1)).
+//		Build()
+//
+// Only the writer, encoder and level related options of the given Builders
+// are used; the zap.Options (e.g. from WithOptions, WithSampling and
+// NoStacktraceOnError) of this Builder apply to all destinations.
+//
+// A call to this function appends to the list of previous values.
+func (b *Builder) Tee(builders ...*Builder) *Builder {
+	b.tees = append(b.tees, builders...)
+	return b
+}
+
+// WithFields attaches constant key/value pairs, e.g. the service name, environment
+// or region, to every log entry of the built logger. It corresponds to calling
+// WithValues(keysAndValues...) on the built logr.Logger, but spares every consumer
+// from doing that.
+//
+// A call to this function appends to the list of previous values.
+func (b *Builder) WithFields(keysAndValues ...interface{}) *Builder {
+	b.fields = append(b.fields, keysAndValues...)
 	return b
 }
 
@@ -160,16 +327,40 @@ func (b *Builder) WithEncoderCreator(encoderCreator EncoderCreator) *Builder {
 // be output, unless logr.Logger.V() is used to raise the level.
 //
 // According to logr.Logger, "it's illegal to pass a log
-// level less than zero.", hence, negative logrLevel values are disallowed.
+// level less than zero.", hence, negative logrLevel values are disallowed;
+// they are ignored by Build, and make BuildE return ErrNegativeLevel.
 //
 // A call to this function overwrites any previous value.
 func (b *Builder) LogUpto(logrLevel int8) *Builder {
-	if b.level >= 0 {
-		b.level = zapcore.Level(-1 * logrLevel)
+	if logrLevel < 0 {
+		b.errs = append(b.errs, ErrNegativeLevel)
+		return b
+	}
+	b.level = toZapLevel(logrLevel)
+	if b.atomicLevel != nil {
+		b.atomicLevel.SetLogUpto(logrLevel)
 	}
 	return b
 }
 
+// AtomicLevel makes the log level of the built logger changeable at runtime,
+// and returns the handle for doing so. The AtomicLevel is initialized with the
+// level given to LogUpto, and later calls to LogUpto change it as well. The
+// AtomicLevel is also an http.Handler, which can be registered with e.g. an
+// admin HTTP server to allow changing the level without restarting:
+//
+//	b := zaplog.NewZap()
+//	http.Handle("/log/level", b.AtomicLevel())
+//	log := b.Build()
+//
+// Subsequent calls return the same AtomicLevel.
+func (b *Builder) AtomicLevel() AtomicLevel {
+	if b.atomicLevel == nil {
+		b.atomicLevel = &AtomicLevel{zap.NewAtomicLevelAt(b.level)}
+	}
+	return *b.atomicLevel
+}
+
 // WithOptions appends options for configuring zap.
 //
 // Options by default applied in Build() are:
@@ -198,6 +389,103 @@ func (b *Builder) Console() *Builder {
 		WithLevelEncoder(CapitalLevelEncoder())
 }
 
+// WithKeys renames the keys of the message, level, logger name and error fields,
+// e.g. for renaming "msg" to "message" as needed by some log aggregators. An empty
+// key leaves the respective key unchanged.
+//
+// It corresponds to setting EncoderConfig.MessageKey, EncoderConfig.LevelKey and
+// EncoderConfig.NameKey, and the zapr.ErrorKey option.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) WithKeys(messageKey, levelKey, nameKey, errorKey string) *Builder {
+	if len(errorKey) != 0 {
+		b.errorKey = errorKey
+	}
+	return b.WithEncoderConfigOption(func(ec *EncoderConfig) {
+		if len(messageKey) != 0 {
+			ec.MessageKey = messageKey
+		}
+		if len(levelKey) != 0 {
+			ec.LevelKey = levelKey
+		}
+		if len(nameKey) != 0 {
+			ec.NameKey = nameKey
+		}
+	})
+}
+
+// WithConsoleOrder writes the elements of console log lines in the given order,
+// e.g. for downstream parsing tools that expect a fixed column order. Elements
+// not in order are omitted. The default order is DefaultConsoleOrder().
+//
+// It corresponds to WithEncoderCreator(OrderedConsoleEncoderCreator(order...)),
+// and hence implies console output; it is meant to be used after Console().
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) WithConsoleOrder(order ...ConsoleElement) *Builder {
+	return b.WithEncoderCreator(OrderedConsoleEncoderCreator(order...))
+}
+
+// Stackdriver configures the logger to write JSON in the format Google Cloud
+// Logging (formerly Stackdriver) expects from e.g. GKE and Cloud Run workloads.
+// The time key is renamed to "time", and otherwise it is a shorthand for:
+//
+//	WithEncoderCreator(JSONEncoderCreator()).
+//	WithKeys("message", "severity", "", "").
+//	WithTimeLayout(time.RFC3339Nano, time.UTC).
+//	WithLevelEncoder(StackdriverLevelEncoder())
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) Stackdriver() *Builder {
+	return b.WithEncoderCreator(JSONEncoderCreator()).
+		WithKeys("message", "severity", "", "").
+		WithEncoderConfigOption(func(ec *EncoderConfig) {
+			ec.TimeKey = "time"
+		}).
+		WithTimeLayout(time.RFC3339Nano, time.UTC).
+		WithLevelEncoder(StackdriverLevelEncoder())
+}
+
+// LowAllocation configures the logger for high-volume logging, where the
+// allocations per log entry matter. With it, encoding a log entry doesn't
+// allocate, thanks to the pooled buffers of zap; only the conversion of the
+// logr keysAndValues to zap fields does. Console output, caller annotations and
+// human-friendly timestamps each add allocations, see BenchmarkBuilder. It is a
+// shorthand for:
+//
+//	WithEncoderCreator(JSONEncoderCreator()).
+//	WithLevelEncoder(LowercaseLevelEncoder()).
+//	WithCaller(false)
+//
+// where time.Time and time.Duration are both serialized as (floating-point)
+// seconds, the time since the Unix epoch, like by default. This overrides e.g.
+// HumanFriendlyTime.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) LowAllocation() *Builder {
+	return b.WithEncoderCreator(JSONEncoderCreator()).
+		WithLevelEncoder(LowercaseLevelEncoder()).
+		WithCaller(false).
+		WithEncoderConfigOption(func(ec *EncoderConfig) {
+			ec.EncodeTime = zapcore.EpochTimeEncoder
+			ec.EncodeDuration = zapcore.SecondsDurationEncoder
+		})
+}
+
+// Color colorizes the log level using ANSI escape codes, which is useful
+// for local development in a terminal. It is meant to be used together with
+// Console(), and is a shorthand for:
+//
+//	WithLevelEncoder(CapitalColorLevelEncoder())
+//
+// As with the other level encoders, the logr level is kept in the
+// LogrLevelKey field.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) Color() *Builder {
+	return b.WithLevelEncoder(CapitalColorLevelEncoder())
+}
+
 // Example is a shorthand for
 //
 //	HumanFriendlyTime().
@@ -216,11 +504,27 @@ func (b *Builder) Example() *Builder {
 // a file under testdata/ with the name of the test + the ".log" suffix.
 //
 // FilterStacktraceOrigins is applied before verifying the output such that
-// in console mode the stack trace is filtered.
+// the stack trace is filtered, both in console and JSON mode.
 func (b *Builder) Test(g *filetest.Tester) *Builder {
 	return b.LogTo(g.Add(g.T.Name() + ".log").Filter(FilterStacktraceOrigins).Writer())
 }
 
+// TestT makes the logger write every log entry to t using t.Logf, similarly to
+// zaptest.NewLogger. The log output hence shows up interleaved with the
+// other output of "go test -v", attributed to the right (sub)test, and is only
+// shown for failing tests when not in verbose mode. This complements Test,
+// which verifies the log output against a golden file.
+//
+// Internal errors of the logger are also written to t, and mark the test as
+// failed.
+//
+// A call to this function overwrites any previous value of LogTo, and appends
+// a zap.ErrorOutput option to the list of previous options.
+func (b *Builder) TestT(t zaptest.TestingT) *Builder {
+	return b.LogTo(testingWriter{t: t}).
+		WithOptions(zap.ErrorOutput(testingWriter{t: t, markFailed: true}))
+}
+
 // NoStacktraceOnError makes the logger not output a stack trace when
 // an error is logged. This is done by moving the stack trace level
 // to only be output for the DPanicLevel or higher (zap) levels.
@@ -230,6 +534,37 @@ func (b *Builder) NoStacktraceOnError() *Builder {
 	return b.WithOptions(zap.AddStacktrace(zap.DPanicLevel))
 }
 
+// WithSampling makes the logger sample log entries, in order to cap the CPU
+// and I/O load of logging in high-throughput services. For every second, the
+// first initial log entries with a given level and message are logged, and
+// after that, every thereafter-th entry is logged. The rest are dropped.
+//
+// It corresponds to wrapping the zapcore.Core using zapcore.NewSamplerWithOptions.
+//
+// By default log entries are not sampled.
+//
+// A call to this function appends to the list of previous values.
+func (b *Builder) WithSampling(initial, thereafter int) *Builder {
+	return b.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewSamplerWithOptions(core, time.Second, initial, thereafter)
+	})
+}
+
+// WrapCore registers a function that wraps the zapcore.Core of the logger. This
+// is a hook point for e.g. counting log entries per level for metrics, or
+// forwarding errors to an error tracking service, without abandoning the Builder.
+//
+// The given zapcore.Core writes to all destinations, including the ones added
+// using Tee. The wrapping functions are applied in the order they were
+// registered, along with the other zap.Options (e.g. from WithSampling).
+//
+// It corresponds to WithOptions(zap.WrapCore(fn)).
+//
+// A call to this function appends to the list of previous values.
+func (b *Builder) WrapCore(fn func(zapcore.Core) zapcore.Core) *Builder {
+	return b.WithOptions(zap.WrapCore(fn))
+}
+
 // WithLevelEncoder customizes how the log level is encoded.
 //
 // The default is LowercaseLevelEncoder.
@@ -241,6 +576,70 @@ func (b *Builder) WithLevelEncoder(levelEnc LevelEncoder) *Builder {
 	})
 }
 
+// WithLevelNames renames log levels without having to write a LevelEncoder from
+// scratch, e.g. for requiring "TRACE" or "VERBOSE" naming, or Stackdriver severities:
+//
+//	WithLevelNames(map[zapcore.Level]string{
+//		zapcore.DebugLevel: "VERBOSE", // logr level 1
+//		-2:                 "TRACE",   // logr levels 2 and higher
+//		zapcore.WarnLevel:  "WARNING",
+//	})
+//
+// See LevelNamesEncoder for how the names are applied. The levels not in names
+// are encoded using the LevelEncoder that was configured before this call, e.g.
+// using WithLevelEncoder. The logr level is still registered with the
+// LogrLevelKey field.
+//
+// A call to this function wraps any previous level encoder.
+func (b *Builder) WithLevelNames(names map[zapcore.Level]string) *Builder {
+	return b.WithEncoderConfigOption(func(ec *EncoderConfig) {
+		fallback := ec.EncodeLevel
+		if fallback == nil {
+			fallback = LowercaseLevelEncoder()
+		}
+		ec.EncodeLevel = LevelNamesEncoder(names, fallback)
+	})
+}
+
+// WithCaller controls whether the file and line of the caller is included in
+// the logs. The caller is the code calling the logr.Logger; frames of e.g. zapr
+// and the tracing package are skipped. By default, the caller is encoded in a
+// short package/file:line format, under the "caller" key.
+//
+// It corresponds to WithOptions(zap.WithCaller(enabled)).
+//
+// By default the caller is not included in the log output.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) WithCaller(enabled bool) *Builder {
+	return b.WithOptions(zap.WithCaller(enabled))
+}
+
+// WithCallerEncoder customizes how the caller is encoded, for example using
+// zapcore.FullCallerEncoder for the full file path. It is only used if
+// WithCaller(true) is set.
+//
+// The default is zapcore.ShortCallerEncoder.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) WithCallerEncoder(callerEnc CallerEncoder) *Builder {
+	return b.WithEncoderConfigOption(func(ec *EncoderConfig) {
+		ec.EncodeCaller = callerEnc
+	})
+}
+
+// WithCallerKey customizes the key of the caller field. It is only used if
+// WithCaller(true) is set.
+//
+// The default is "caller".
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) WithCallerKey(key string) *Builder {
+	return b.WithEncoderConfigOption(func(ec *EncoderConfig) {
+		ec.CallerKey = key
+	})
+}
+
 // NoTimestamps omits timestamps in the logs. It's useful for deterministic
 // output in examples and tests.
 //
@@ -273,21 +672,117 @@ func (b *Builder) HumanFriendlyTime() *Builder {
 	})
 }
 
-// Build builds the logger with the configured options.
+// WithTimeLayout serializes time.Time, including the timestamp of the log entry,
+// using time.Time.Format with the given layout, e.g. time.RFC3339Nano. If loc is
+// non-nil, the time is converted to that time zone (e.g. time.UTC) first.
+//
+// It corresponds to setting EncoderConfig.EncodeTime to zapcore.TimeEncoderOfLayout(layout),
+// with the time zone conversion applied before.
+//
+// A call to this function overwrites any previous value.
+func (b *Builder) WithTimeLayout(layout string, loc *time.Location) *Builder {
+	return b.WithEncoderConfigOption(func(ec *EncoderConfig) {
+		encodeTime := zapcore.TimeEncoderOfLayout(layout)
+		ec.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+			if loc != nil {
+				t = t.In(loc)
+			}
+			encodeTime(t, enc)
+		}
+	})
+}
+
+// BuildE validates the configured options, and builds the logger like Build if
+// they are valid. Otherwise an error is returned along with a logger that discards
+// all logs, instead of silently producing a misconfigured logger. The error
+// wraps e.g. ErrNegativeLevel, ErrNoWriter, ErrNoEncoderCreator or
+// ErrNegativeBuffer. The Builders given to Tee are validated as well.
+func (b *Builder) BuildE() (logr.Logger, error) {
+	if err := b.validate(); err != nil {
+		return logr.Discard(), err
+	}
+	return b.Build(), nil
+}
+
+// validate returns the errors of the configured options, combined.
+func (b *Builder) validate() error {
+	errs := append([]error{}, b.errs...)
+	if b.outW == nil && b.priorityOut == nil {
+		errs = append(errs, ErrNoWriter)
+	}
+	if b.encoderCreator == nil {
+		errs = append(errs, ErrNoEncoderCreator)
+	}
+	if b.bufferSize < 0 || b.flushInterval < 0 {
+		errs = append(errs, ErrNegativeBuffer)
+	}
+	for _, tee := range b.tees {
+		errs = append(errs, tee.validate())
+	}
+	return multierr.Combine(errs...)
+}
+
+// Build builds the logger with the configured options. Build doesn't
+// validate the options, use BuildE for that.
 //
 // By default the logger name is an empty string, and the log level is 0.
+//
+// Loggers that hold resources, e.g. due to Buffered, must be built using
+// BuildWithClose instead.
 func (b *Builder) Build() logr.Logger {
-	// Convert the io.Writer to a zapcore.WriteSyncer, if a zapcore.WriteSyncer wasn't already
-	// provided, and lock the resulting zapcore.WriteSyncer to make it thread-safe. Locking is
-	// needed, e.g. for *os.Files.
-	sink := zapcore.Lock(zapcore.AddSync(b.outW))
+	log, _ := b.BuildWithClose()
+	return log
+}
 
-	// Create the encoder
-	encCfg := b.encoderCfg
-	for _, mutFn := range b.encoderCfgOptions {
-		mutFn(&encCfg)
+// BuildWithClose builds the logger like Build, and returns a function
+// releasing the resources held by the logger as well. The close function
+// flushes and stops the buffers created by Buffered, also for the Builders
+// given to Tee, closes the connection to journald created by LogToJournald,
+// and exports the buffered log records of ExportOTLP, and stops its exporter.
+// The logger must not be used after it has been closed.
+func (b *Builder) BuildWithClose() (logr.Logger, func() error) {
+	// The destinations of this Builder need to be enabled for the most
+	// verbose level given to WithNameLevels, the nameLevelsSink below filters
+	// the log entries by logger name.
+	level := b.levelEnabler()
+	if len(b.nameLevels) != 0 {
+		level = newNameLevelsEnabler(level, b.nameLevels)
+	}
+
+	// SpanRefs are only consumed by the OTLP and error reporter cores, not
+	// written to the destinations.
+	core, sink, closeFn := b.core(level)
+	cores := make([]zapcore.Core, 0, 1+len(b.tees)+len(b.otlpExporters)+len(b.errorReporters))
+	cores = append(cores, newSpanRefCore(core))
+	closeFns := []func() error{closeFn}
+	for _, tee := range b.tees {
+		teeCore, _, teeCloseFn := tee.core(tee.levelEnabler())
+		cores = append(cores, newSpanRefCore(teeCore))
+		closeFns = append(closeFns, teeCloseFn)
+	}
+	for _, exp := range b.otlpExporters {
+		cores = append(cores, newOTLPCore(exp, level))
+		closeFns = append(closeFns, exp.stop)
+	}
+	for _, reporter := range b.errorReporters {
+		cores = append(cores, newErrorReporterCore(reporter))
+	}
+	// The error chain and redaction are applied to every destination core
+	// separately, as the core created by zapcore.NewTee doesn't check the
+	// levels of its cores in Write.
+	for i := range cores {
+		if b.errorChain != nil {
+			cores[i] = newErrorChainCore(cores[i], *b.errorChain)
+		}
+		if len(b.redactKeys) != 0 {
+			cores[i] = newRedactCore(cores[i], b.redactKeys)
+		}
+	}
+	if len(cores) == 1 {
+		core = cores[0]
+	} else {
+		core = zapcore.NewTee(cores...)
 	}
-	encoder := b.encoderCreator(encCfg)
 
 	// Pre-populate the options with opinionated defaults, such that internal errors are written to
 	// the same sink as configured above, and that stack traces are output for all errors by default.
@@ -298,18 +793,102 @@ func (b *Builder) Build() logr.Logger {
 	}
 	opts = append(opts, b.opts...)
 
-	// We know that the zapr Logger implements logr.CallDepthLogger, so this cast is safe.
-	return zapr.NewLogger(
-		zap.New(zapcore.NewCore(encoder, sink, b.level), opts...),
-	)
+	zaprOpts := []zapr.Option{zapr.LogInfoLevel(LogrLevelKey)}
+	if len(b.errorKey) != 0 {
+		zaprOpts = append(zaprOpts, zapr.ErrorKey(b.errorKey))
+	}
+	log := zapr.NewLoggerWithOptions(zap.New(core, opts...), zaprOpts...)
+	if len(b.nameLevels) != 0 {
+		log = log.WithSink(newNameLevelsSink(log.GetSink(), b.levelEnabler(), b.nameLevels))
+	}
+	if len(b.fields) != 0 {
+		log = log.WithValues(b.fields...)
+	}
+	return log, closeAll(closeFns)
+}
+
+// closeAll returns a function calling all non-nil closeFns, and combining
+// their errors.
+func closeAll(closeFns []func() error) func() error {
+	return func() error {
+		var errs []error
+		for _, closeFn := range closeFns {
+			if closeFn != nil {
+				errs = append(errs, closeFn())
+			}
+		}
+		return multierr.Combine(errs...)
+	}
 }
 
+// core builds the zapcore.Core writing to the configured writer, using the
+// configured encoder and the given level. The locked, unbuffered sink is returned as well.
+// When logging to syslog or journald, the sink is os.Stderr. The returned close function
+// is nil if the core can't hold any resources.
+func (b *Builder) core(level zapcore.LevelEnabler) (zapcore.Core, zapcore.WriteSyncer, func() error) {
+	// Create the encoder
+	encCfg := b.encoderCfg
+	for _, mutFn := range b.encoderCfgOptions {
+		mutFn(&encCfg)
+	}
+	encoder := b.encoderCreator(encCfg)
+
+	if b.priorityOut != nil {
+		core := &priorityCore{LevelEnabler: level, enc: encoder, out: b.priorityOut}
+		return core, zapcore.Lock(os.Stderr), core.close
+	}
+
+	// Convert the io.Writer to a zapcore.WriteSyncer, if a zapcore.WriteSyncer wasn't already
+	// provided, and lock the resulting zapcore.WriteSyncer to make it thread-safe. Locking is
+	// needed, e.g. for *os.Files.
+	sink := zapcore.Lock(zapcore.AddSync(b.outW))
+	if !b.buffered {
+		return zapcore.NewCore(encoder, sink, level), sink, nil
+	}
+	bufferedSink := &zapcore.BufferedWriteSyncer{
+		WS:            sink,
+		Size:          b.bufferSize,
+		FlushInterval: b.flushInterval,
+	}
+	return zapcore.NewCore(encoder, bufferedSink, level), sink, bufferedSink.Stop
+}
+
+// levelEnabler returns the atomic level if it's in use, otherwise the
+// configured level.
+func (b *Builder) levelEnabler() zapcore.LevelEnabler {
+	if b.atomicLevel != nil {
+		return *b.atomicLevel
+	}
+	return b.level
+}
+
+// testingWriter is a zapcore.WriteSyncer that writes to a zaptest.TestingT,
+// like the writer zaptest.NewLogger uses.
+type testingWriter struct {
+	t zaptest.TestingT
+	// markFailed makes the test fail if anything is written.
+	markFailed bool
+}
+
+func (w testingWriter) Write(p []byte) (int, error) {
+	// Strip the trailing newline, as t.Logf always adds one
+	w.t.Logf("%s", bytes.TrimRight(p, "\n"))
+	if w.markFailed {
+		w.t.Fail()
+	}
+	return len(p), nil
+}
+
+func (w testingWriter) Sync() error { return nil }
+
 // FilterStacktraceOrigins removes every line in content that
 // starts with tab. It is meant to be used for filtering call
 // stack output from for example a logger when testing (as the exact
 // lines of caller origin might vary for instance across Go versions).
 //
-// TODO: Make this work with JSON output as well.
+// JSON log entries are supported as well; in lines that contain a JSON
+// string field with the StacktraceKey key, the lines of the stack trace
+// that start with tab are removed.
 func FilterStacktraceOrigins(content []byte) []byte {
 	s := bufio.NewScanner(bytes.NewReader(content))
 	out := make([]byte, 0, len(content))
@@ -318,9 +897,46 @@ func FilterStacktraceOrigins(content []byte) []byte {
 		if bytes.HasPrefix(line, []byte("\t")) {
 			continue
 		}
+		if bytes.HasPrefix(line, []byte("{")) {
+			line = jsonStacktraceRegexp.ReplaceAllFunc(line, filterJSONStacktrace)
+		}
 
 		out = append(out, line...)
 		out = append(out, '\n')
 	}
 	return out
 }
+
+// StacktraceKey is the key of the stack trace field in ProductionEncoderConfig
+// and DevelopmentEncoderConfig.
+const StacktraceKey = "stacktrace"
+
+// jsonStacktraceRegexp matches the StacktraceKey field with a JSON string value.
+var jsonStacktraceRegexp = regexp.MustCompile(`"` + StacktraceKey + `":"(?:[^"\\]|\\.)*"`) //nolint:gochecknoglobals
+
+// filterJSONStacktrace filters a field matched by jsonStacktraceRegexp like
+// FilterStacktraceOrigins does for non-JSON lines.
+func filterJSONStacktrace(field []byte) []byte {
+	quoted := field[len(StacktraceKey)+3:]
+	var stacktrace string
+	if err := json.Unmarshal(quoted, &stacktrace); err != nil {
+		return field
+	}
+
+	lines := strings.Split(stacktrace, "\n")
+	filtered := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "\t") {
+			filtered = append(filtered, line)
+		}
+	}
+
+	// Don't escape e.g. < and >, like zap's JSON encoder
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(strings.Join(filtered, "\n")); err != nil {
+		return field
+	}
+	return append([]byte(`"`+StacktraceKey+`":`), bytes.TrimSuffix(buf.Bytes(), []byte("\n"))...)
+}
@@ -2,14 +2,22 @@ package zaplog
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/go-logr/zapr"
 	"github.com/luxas/deklarative/tracing/filetest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -31,10 +39,54 @@ func ExampleBuilder_json() {
 	log.V(2).Info("am I enabled?", "enabled", log.V(2).Enabled())
 
 	// Output:
-	// {"level":"info(v=0)","logger":"bar","msg":"some message","foo":true}
-	// {"level":"debug(v=1)","logger":"bar","msg":"hello","bar":1}
+	// {"level":"info","logger":"bar","msg":"some message","v":0,"foo":true}
+	// {"level":"debug","logger":"bar","msg":"hello","bar":1,"v":1}
 	// {"level":"error","logger":"bar","msg":"I don't know what happened here","duration":"1m0s","error":"unexpected error"}
-	// {"level":"debug(v=1)","logger":"bar","msg":"am I enabled?","enabled":true}
+	// {"level":"debug","logger":"bar","msg":"am I enabled?","v":1,"enabled":true}
+}
+
+func ExampleBuilder_stackdriver() {
+	// Build an example logger called bar that logs levels <= 1.
+	log := NewZap().Stackdriver().Example().LogUpto(1).Build().WithName("bar")
+
+	// Sample info usage
+	log.Info("some message", "foo", true)
+	log.WithValues("bar", 1).V(1).Info("hello")
+
+	// Sample error usage
+	err := errors.New("unexpected error") //nolint:goerr113
+	log.Error(err, "I don't know what happened here", "duration", time.Minute)
+
+	// Output:
+	// {"severity":"INFO","logger":"bar","message":"some message","v":0,"foo":true}
+	// {"severity":"DEBUG","logger":"bar","message":"hello","bar":1,"v":1}
+	// {"severity":"ERROR","logger":"bar","message":"I don't know what happened here","duration":"1m0s","error":"unexpected error"}
+}
+
+func TestBuilder_WithKeys(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewZap().Example().LogTo(&buf).WithKeys("message", "", "component", "err").Build().WithName("bar")
+	log.Info("hello")
+	log.Error(errors.New("unexpected error"), "failed") //nolint:goerr113
+
+	assert.Equal(t, `{"level":"info","component":"bar","message":"hello","v":0}
+{"level":"error","component":"bar","message":"failed","err":"unexpected error"}
+`, buf.String())
+}
+
+func TestBuilder_Stackdriver(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewZap().Stackdriver().NoStacktraceOnError().LogTo(&buf).Build()
+	log.Info("hello")
+
+	entry := map[string]interface{}{}
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "INFO", entry["severity"])
+	assert.Equal(t, "hello", entry["message"])
+	ts, err := time.Parse(time.RFC3339Nano, entry["time"].(string))
+	require.Nil(t, err)
+	assert.Equal(t, time.UTC, ts.Location())
+	assert.WithinDuration(t, time.Now(), ts, time.Minute)
 }
 
 func ExampleBuilder_console() {
@@ -54,10 +106,10 @@ func ExampleBuilder_console() {
 	log.V(2).Info("am I enabled?", "enabled", log.V(2).Enabled())
 
 	// Output:
-	// INFO(v=0)	bar	some message	{"foo": true}
-	// DEBUG(v=1)	bar	hello	{"bar": 1}
+	// INFO	bar	some message	{"v": 0, "foo": true}
+	// DEBUG	bar	hello	{"bar": 1, "v": 1}
 	// ERROR	bar	I don't know what happened here	{"duration": "1m0s", "error": "unexpected error"}
-	// DEBUG(v=1)	bar	am I enabled?	{"enabled": true}
+	// DEBUG	bar	am I enabled?	{"v": 1, "enabled": true}
 }
 
 func ExampleBuilder_custom() {
@@ -85,10 +137,10 @@ func ExampleBuilder_custom() {
 
 	fmt.Println(buf.String())
 	// Output:
-	// {"L":"info(v=0)","N":"bar","M":"some message","foo":true}
-	// {"L":"debug(v=1)","N":"bar","M":"hello","bar":1}
+	// {"L":"info","N":"bar","M":"some message","v":0,"foo":true}
+	// {"L":"debug","N":"bar","M":"hello","bar":1,"v":1}
 	// {"L":"error","N":"bar","M":"I don't know what happened here","duration":"1m0s","error":"unexpected error"}
-	// {"L":"debug(v=1)","N":"bar","M":"am I enabled?","enabled":true}
+	// {"L":"debug","N":"bar","M":"am I enabled?","v":1,"enabled":true}
 }
 
 func ExampleBuilder_calldepth() {
@@ -121,16 +173,15 @@ func ExampleBuilder_calldepth() {
 	fmt.Println(string(FilterStacktraceOrigins(buf.Bytes())))
 
 	// Output:
-	// INFO(v=0)	bar	some message	{"foo": true}
+	// INFO	bar	some message	{"v": 0, "foo": true}
 	// DPANIC	bar	strongly-typed Zap Field passed to logr	{"bar": 1, "zap field": {"Key":"foo","Type":10,"Integer":1102682522,"String":"","Interface":null}}
-	// github.com/go-logr/zapr.(*zapLogger).Info
 	// github.com/luxas/deklarative/tracing/zaplog.ExampleBuilder_calldepth
 	// testing.runExample
 	// testing.runExamples
 	// testing.(*M).Run
 	// main.main
 	// runtime.main
-	// DEBUG(v=1)	bar	hello	{"bar": 1}
+	// DEBUG	bar	hello	{"bar": 1, "v": 1}
 	// ERROR	bar	I don't know what happened here	{"duration": "1m0s", "error": "unexpected error"}
 	// github.com/luxas/deklarative/tracing/zaplog.ExampleBuilder_calldepth
 	// testing.runExample
@@ -138,7 +189,7 @@ func ExampleBuilder_calldepth() {
 	// testing.(*M).Run
 	// main.main
 	// runtime.main
-	// DEBUG(v=1)	bar	am I enabled?	{"enabled": true}
+	// DEBUG	bar	am I enabled?	{"v": 1, "enabled": true}
 }
 
 func TestTestdata(t *testing.T) {
@@ -170,6 +221,366 @@ func TestTestdata(t *testing.T) {
 	log.V(2).Info("am I enabled?", "enabled", log.V(2).Enabled())
 }
 
+func TestTestdataJSON(t *testing.T) {
+	g := filetest.New(t)
+	defer g.Assert()
+
+	// Build an example logger called bar that logs levels <= 1 in JSON.
+	log := NewZap().
+		NoTimestamps().
+		Test(g).
+		LogUpto(1).
+		Build().
+		WithName("bar")
+
+	log.Info("some message", "foo", true)
+
+	// Sample error usage. See the filtered call stack in action.
+	err := errors.New("unexpected error") //nolint:goerr113
+	log.Error(err, "I don't know what happened here", "duration", time.Minute)
+}
+
+func TestFilterStacktraceOrigins(t *testing.T) {
+	in := "INFO\tmsg\n" +
+		"main.foo\n" +
+		"\t/src/main.go:12\n" +
+		`{"level":"error","msg":"<b>","stacktrace":"main.foo\n\t/src/main.go:12\nmain.\"bar\"\n\t/src/main.go:34","v":1}` + "\n" +
+		`{"level":"info","msg":"no stacktrace"}` + "\n"
+	want := "INFO\tmsg\n" +
+		"main.foo\n" +
+		`{"level":"error","msg":"<b>","stacktrace":"main.foo\nmain.\"bar\"","v":1}` + "\n" +
+		`{"level":"info","msg":"no stacktrace"}` + "\n"
+	assert.Equal(t, want, string(FilterStacktraceOrigins([]byte(in))))
+}
+
+func TestBuilder_WithCaller(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewZap().Example().LogTo(&buf).WithCaller(true).Build()
+	log.Info("short")
+
+	// Disable the caller again, and verify that no caller is included
+	NewZap().Example().LogTo(&buf).WithCaller(true).WithCaller(false).Build().Info("none")
+
+	log = NewZap().Example().LogTo(&buf).
+		WithCaller(true).
+		WithCallerKey("src").
+		WithCallerEncoder(zapcore.FullCallerEncoder).
+		Build()
+	log.WithCallDepth(0).Info("full")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Regexp(t, `^\{"level":"info","caller":"zaplog/zap_test.go:\d+","msg":"short","v":0\}$`, lines[0])
+	assert.Equal(t, `{"level":"info","msg":"none","v":0}`, lines[1])
+	assert.Regexp(t, `^\{"level":"info","src":"/.+/zaplog/zap_test.go:\d+","msg":"full","v":0\}$`, lines[2])
+}
+
+func TestBuilder_WithFields(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewZap().Example().LogTo(&buf).
+		WithFields("service", "foo", "env", "prod").
+		WithFields("region", "eu-north-1").
+		Build().
+		WithName("bar")
+	log.Info("hello", "i", 1)
+	log.WithValues("j", 2).Error(nil, "error")
+
+	assert.Equal(t, `{"level":"info","logger":"bar","msg":"hello","service":"foo","env":"prod","region":"eu-north-1","v":0,"i":1}
+{"level":"error","logger":"bar","msg":"error","service":"foo","env":"prod","region":"eu-north-1","j":2}
+`, buf.String())
+}
+
+type testingTSpy struct {
+	*testing.T
+	logs   []string
+	failed bool
+}
+
+func (t *testingTSpy) Logf(format string, args ...interface{}) {
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+
+func (t *testingTSpy) Fail() { t.failed = true }
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("write failed") } //nolint:goerr113
+
+func TestBuilder_TestT(t *testing.T) {
+	spy := &testingTSpy{T: t}
+	log := NewZap().Example().Console().TestT(spy).Build()
+
+	log.Info("hello", "foo", true)
+	assert.Equal(t, []string{"INFO\thello\t{\"v\": 0, \"foo\": true}"}, spy.logs)
+	assert.False(t, spy.failed)
+
+	// Internal errors are reported to the test, and make it fail
+	spy = &testingTSpy{T: t}
+	log = NewZap().Example().TestT(spy).Tee(NewZap().LogTo(failingWriter{})).Build()
+	log.Info("hello")
+	require.Len(t, spy.logs, 2)
+	assert.Contains(t, spy.logs[1], "write failed")
+	assert.True(t, spy.failed)
+
+	// Sub-tests log to their own testing.T
+	t.Run("subtest", func(t *testing.T) {
+		NewZap().Example().TestT(t).Build().Info("logged in subtest")
+	})
+}
+
+func TestBuilder_WithLevelNames(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewZap().Example().NoStacktraceOnError().LogTo(&buf).LogUpto(3).
+		WithLevelEncoder(CapitalLevelEncoder()).
+		WithLevelNames(map[zapcore.Level]string{
+			zapcore.DebugLevel: "VERBOSE",
+			-2:                 "TRACE",
+			zapcore.WarnLevel:  "WARNING",
+		}).Build()
+
+	log.Info("info")
+	log.V(1).Info("verbose")
+	log.V(2).Info("trace")
+	log.V(3).Info("trace too")
+	log.Error(nil, "error")
+
+	assert.Equal(t, `{"level":"INFO","msg":"info","v":0}
+{"level":"VERBOSE","msg":"verbose","v":1}
+{"level":"TRACE","msg":"trace","v":2}
+{"level":"TRACE","msg":"trace too","v":3}
+{"level":"ERROR","msg":"error"}
+`, buf.String())
+}
+
+func TestBuilder_Color(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewZap().Example().Console().Color().LogTo(&buf).LogUpto(1).Build()
+	log.V(1).Info("hello")
+
+	assert.Equal(t, "\x1b[35mDEBUG\x1b[0m\thello\t{\"v\": 1}\n", buf.String())
+}
+
+func TestBuilder_WithTimeLayout(t *testing.T) {
+	ts := time.Date(2021, 8, 10, 12, 30, 45, 123456789, time.FixedZone("CEST", 2*60*60))
+	tests := []struct {
+		name   string
+		layout string
+		loc    *time.Location
+		want   string
+	}{
+		{"RFC3339Nano in UTC", time.RFC3339Nano, time.UTC, "2021-08-10T10:30:45.123456789Z"},
+		{"RFC3339 in original zone", time.RFC3339, nil, "2021-08-10T12:30:45+02:00"},
+		{"custom layout", "2006-01-02 15:04:05.000", time.UTC, "2021-08-10 10:30:45.123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := NewZap().Example().LogTo(&buf).WithTimeLayout(tt.layout, tt.loc).Build()
+			log.Info("hello", "ts", ts)
+			assert.Equal(t, fmt.Sprintf(`{"level":"info","msg":"hello","v":0,"ts":%q}`+"\n", tt.want), buf.String())
+		})
+	}
+}
+
+func TestBuilder_WithSampling(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewZap().Example().LogTo(&buf).WithSampling(2, 3).Build()
+
+	for i := 1; i <= 10; i++ {
+		log.Info("repeated", "i", i)
+	}
+	log.Info("other message")
+
+	assert.Equal(t, `{"level":"info","msg":"repeated","v":0,"i":1}
+{"level":"info","msg":"repeated","v":0,"i":2}
+{"level":"info","msg":"repeated","v":0,"i":5}
+{"level":"info","msg":"repeated","v":0,"i":8}
+{"level":"info","msg":"other message","v":0}
+`, buf.String())
+}
+
+// countingCore counts the written log entries per level.
+type countingCore struct {
+	zapcore.Core
+	counts map[zapcore.Level]int
+}
+
+func (c *countingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &countingCore{Core: c.Core.With(fields), counts: c.counts}
+}
+
+func (c *countingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *countingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.counts[ent.Level]++
+	return c.Core.Write(ent, fields)
+}
+
+func TestBuilder_WrapCore(t *testing.T) {
+	var buf bytes.Buffer
+	counts := map[zapcore.Level]int{}
+	log := NewZap().Example().NoStacktraceOnError().LogTo(&buf).LogUpto(1).
+		WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &countingCore{Core: core, counts: counts}
+		}).Build()
+
+	log.Info("hello")
+	log.WithValues("foo", "bar").Info("hello")
+	log.V(1).Info("debug")
+	log.V(2).Info("too verbose, ignored")
+	log.Error(nil, "error")
+
+	assert.Equal(t, map[zapcore.Level]int{zapcore.InfoLevel: 2, zapcore.DebugLevel: 1, zapcore.ErrorLevel: 1}, counts)
+	assert.Equal(t, `{"level":"info","msg":"hello","v":0}
+{"level":"info","msg":"hello","foo":"bar","v":0}
+{"level":"debug","msg":"debug","v":1}
+{"level":"error","msg":"error"}
+`, buf.String())
+}
+
+func TestBuilder_BuildE(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       *Builder
+		wantErr []error
+	}{
+		{"valid", NewZap().Example().LogUpto(2), nil},
+		{"negative level", NewZap().LogUpto(-1), []error{ErrNegativeLevel}},
+		{"nil writer", NewZap().LogTo(nil), []error{ErrNoWriter}},
+		{"nil encoder creator", NewZap().WithEncoderCreator(nil), []error{ErrNoEncoderCreator}},
+		{"negative buffer size", NewZap().Buffered(-1, time.Second), []error{ErrNegativeBuffer}},
+		{"negative flush interval", NewZap().Buffered(1024, -time.Second), []error{ErrNegativeBuffer}},
+		{"invalid tee", NewZap().Tee(NewZap().LogTo(nil)), []error{ErrNoWriter}},
+		{"multiple errors", NewZap().LogUpto(-2).LogTo(nil), []error{ErrNegativeLevel, ErrNoWriter}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, err := tt.b.BuildE()
+			if tt.wantErr == nil {
+				assert.Nil(t, err)
+				assert.True(t, log.V(2).Enabled())
+				return
+			}
+			for _, wantErr := range tt.wantErr {
+				assert.ErrorIs(t, err, wantErr)
+			}
+			assert.False(t, log.Enabled())
+		})
+	}
+}
+
+func TestBuilder_LogUpto(t *testing.T) {
+	var buf bytes.Buffer
+	// Later calls overwrite previous values, negative levels are ignored
+	log := NewZap().Example().LogTo(&buf).LogUpto(1).LogUpto(2).LogUpto(-1).Build()
+	assert.True(t, log.V(2).Enabled())
+	assert.False(t, log.V(3).Enabled())
+}
+
+func TestBuilder_LogToFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	log := NewZap().Example().LogToFile(path, 1, 2, 0).Build()
+
+	// Write a bit more than 2 MB of logs; this shall cause two rotations
+	msg := strings.Repeat("a", 1024)
+	for i := 0; i < 2100; i++ {
+		log.Info(msg)
+	}
+
+	files, err := os.ReadDir(dir)
+	require.Nil(t, err)
+	assert.Len(t, files, 3)
+
+	content, err := os.ReadFile(path)
+	require.Nil(t, err)
+	assert.Less(t, len(content), 1024*1024)
+}
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestBuilder_Buffered(t *testing.T) {
+	var buf syncBuffer
+	log := NewZap().Example().LogTo(&buf).Buffered(1024, time.Hour).Build()
+
+	log.Info("hello")
+	assert.Empty(t, buf.String())
+
+	// Syncing the logger flushes the buffer
+	require.Nil(t, log.GetSink().(zapr.Underlier).GetUnderlying().Sync())
+	assert.Equal(t, `{"level":"info","msg":"hello","v":0}`+"\n", buf.String())
+
+	// The buffer is flushed periodically as well
+	var buf2 syncBuffer
+	log = NewZap().Example().LogTo(&buf2).Buffered(1024, 10*time.Millisecond).Build()
+	log.Info("hello")
+	assert.Eventually(t, func() bool {
+		return buf2.String() == `{"level":"info","msg":"hello","v":0}`+"\n"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestBuilder_BuildWithClose(t *testing.T) {
+	var buf, teeBuf syncBuffer
+	log, closeFn := NewZap().Example().LogTo(&buf).Buffered(1024, time.Hour).
+		Tee(NewZap().Example().LogTo(&teeBuf).Buffered(1024, time.Hour)).
+		BuildWithClose()
+
+	log.Info("hello")
+	assert.Empty(t, buf.String())
+	assert.Empty(t, teeBuf.String())
+
+	// Closing the logger flushes and stops the buffers of all destinations
+	require.Nil(t, closeFn())
+	assert.Equal(t, `{"level":"info","msg":"hello","v":0}`+"\n", buf.String())
+	assert.Equal(t, `{"level":"info","msg":"hello","v":0}`+"\n", teeBuf.String())
+
+	// Unbuffered loggers can be closed as well
+	_, closeFn = NewZap().Example().LogTo(&buf).BuildWithClose()
+	assert.Nil(t, closeFn())
+}
+
+func TestBuilder_Tee(t *testing.T) {
+	var consoleBuf, jsonBuf bytes.Buffer
+	log := NewZap().Example().Console().LogTo(&consoleBuf).
+		Tee(NewZap().Example().LogTo(&jsonBuf).LogUpto(1)).
+		Build().
+		WithName("bar")
+
+	log.Info("some message", "foo", true)
+	log.V(1).Info("debug message")
+	log.V(2).Info("too verbose for both")
+	log.Error(errors.New("unexpected error"), "error message") //nolint:goerr113
+
+	assert.Equal(t, `INFO	bar	some message	{"v": 0, "foo": true}
+ERROR	bar	error message	{"error": "unexpected error"}
+`, consoleBuf.String())
+	assert.Equal(t, `{"level":"info","logger":"bar","msg":"some message","v":0,"foo":true}
+{"level":"debug","logger":"bar","msg":"debug message","v":1}
+{"level":"error","logger":"bar","msg":"error message","error":"unexpected error"}
+`, jsonBuf.String())
+}
+
 func TestLevelEncoders(t *testing.T) {
 	tests := []struct {
 		enc   LevelEncoder
@@ -180,18 +591,31 @@ func TestLevelEncoders(t *testing.T) {
 		{CapitalLevelEncoder(), zapcore.FatalLevel, "FATAL"},
 		{CapitalLevelEncoder(), zapcore.ErrorLevel, "ERROR"},
 		{CapitalLevelEncoder(), zapcore.WarnLevel, "WARN"},
-		{CapitalLevelEncoder(), zapcore.InfoLevel, "INFO(v=0)"},
-		{CapitalLevelEncoder(), zapcore.DebugLevel, "DEBUG(v=1)"},
-		{CapitalLevelEncoder(), -2, "DEBUG(v=2)"},
-		{CapitalLevelEncoder(), -44, "DEBUG(v=44)"},
+		{CapitalLevelEncoder(), zapcore.InfoLevel, "INFO"},
+		{CapitalLevelEncoder(), zapcore.DebugLevel, "DEBUG"},
+		{CapitalLevelEncoder(), -2, "DEBUG"},
+		{CapitalLevelEncoder(), -44, "DEBUG"},
+		// Capital color
+		{CapitalColorLevelEncoder(), zapcore.ErrorLevel, "\x1b[31mERROR\x1b[0m"},
+		{CapitalColorLevelEncoder(), zapcore.WarnLevel, "\x1b[33mWARN\x1b[0m"},
+		{CapitalColorLevelEncoder(), zapcore.InfoLevel, "\x1b[34mINFO\x1b[0m"},
+		{CapitalColorLevelEncoder(), zapcore.DebugLevel, "\x1b[35mDEBUG\x1b[0m"},
+		{CapitalColorLevelEncoder(), -44, "\x1b[35mDEBUG\x1b[0m"},
+		// Level names
+		{LevelNamesEncoder(map[zapcore.Level]string{zapcore.WarnLevel: "WARNING"}, CapitalLevelEncoder()), zapcore.WarnLevel, "WARNING"},
+		{LevelNamesEncoder(map[zapcore.Level]string{zapcore.WarnLevel: "WARNING"}, CapitalLevelEncoder()), zapcore.ErrorLevel, "ERROR"},
+		{LevelNamesEncoder(map[zapcore.Level]string{zapcore.WarnLevel: "WARNING"}, CapitalLevelEncoder()), zapcore.InfoLevel, "INFO"},
+		{LevelNamesEncoder(map[zapcore.Level]string{-2: "TRACE"}, LowercaseLevelEncoder()), -1, "debug"},
+		{LevelNamesEncoder(map[zapcore.Level]string{-2: "TRACE"}, LowercaseLevelEncoder()), -44, "TRACE"},
+		{LevelNamesEncoder(nil, LowercaseLevelEncoder()), -44, "debug"},
 		// Lowercase
 		{LowercaseLevelEncoder(), zapcore.FatalLevel, "fatal"},
 		{LowercaseLevelEncoder(), zapcore.ErrorLevel, "error"},
 		{LowercaseLevelEncoder(), zapcore.WarnLevel, "warn"},
-		{LowercaseLevelEncoder(), zapcore.InfoLevel, "info(v=0)"},
-		{LowercaseLevelEncoder(), zapcore.DebugLevel, "debug(v=1)"},
-		{LowercaseLevelEncoder(), -2, "debug(v=2)"},
-		{LowercaseLevelEncoder(), -44, "debug(v=44)"},
+		{LowercaseLevelEncoder(), zapcore.InfoLevel, "info"},
+		{LowercaseLevelEncoder(), zapcore.DebugLevel, "debug"},
+		{LowercaseLevelEncoder(), -2, "debug"},
+		{LowercaseLevelEncoder(), -44, "debug"},
 	}
 	for i, tt := range tests {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
@@ -222,3 +646,57 @@ func (*fakePrimitiveEncoder) AppendUint32(uint32)         {}
 func (*fakePrimitiveEncoder) AppendUint16(uint16)         {}
 func (*fakePrimitiveEncoder) AppendUint8(uint8)           {}
 func (*fakePrimitiveEncoder) AppendUintptr(uintptr)       {}
+
+func TestBuilder_LowAllocation(t *testing.T) {
+	// Durations are encoded in seconds, like by default
+	for _, b := range []*Builder{
+		NewZap(),
+		NewZap().HumanFriendlyTime().LowAllocation(),
+	} {
+		var buf bytes.Buffer
+		b.NoTimestamps().LogTo(&buf).Build().Info("hello", "duration", time.Minute)
+		assert.Equal(t, `{"level":"info","msg":"hello","v":0,"duration":60}`+"\n", buf.String())
+	}
+
+	if raceEnabled {
+		t.Skip("allocations can't be counted reliably with the race detector")
+	}
+	for _, b := range []*Builder{
+		NewZap(),
+		NewZap().Console().HumanFriendlyTime().WithCaller(true).LowAllocation(),
+	} {
+		log := b.LogTo(io.Discard).Build().WithName("foo").WithValues("a", 1)
+
+		// zapr allocates the fields, other than that, encoding is
+		// allocation-free thanks to the pooled buffers of zap.
+		allocs := testing.AllocsPerRun(100, func() {
+			log.Info("hello", "foo", true, "bar", 42, "duration", time.Second)
+		})
+		assert.LessOrEqual(t, allocs, float64(2))
+	}
+}
+
+func BenchmarkBuilder(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		b    *Builder
+	}{
+		{"default", NewZap()},
+		{"low allocation", NewZap().LowAllocation()},
+		{"console", NewZap().Console()},
+		{"console order", NewZap().Console().WithConsoleOrder(DefaultConsoleOrder()...)},
+		{"caller", NewZap().WithCaller(true)},
+		{"human friendly time", NewZap().HumanFriendlyTime()},
+		{"level names", NewZap().WithLevelNames(map[zapcore.Level]string{-2: "TRACE"})},
+		{"redact", NewZap().Redact("password")},
+	}
+	for _, bb := range benchmarks {
+		log := bb.b.LogTo(io.Discard).Build().WithName("foo").WithValues("a", 1)
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				log.Info("hello", "foo", true, "bar", 42)
+			}
+		})
+	}
+}